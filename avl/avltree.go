@@ -7,16 +7,19 @@ package avl
 
 import (
 	"fmt"
+	"iter"
 
 	"github.com/yu31/structs-go/container"
 	"github.com/yu31/structs-go/internal/tree"
 )
 
 var (
-	_ container.Container = (*Tree)(nil)
-	_ container.Element   = (*treeNode)(nil)
-	_ container.Tree      = (*Tree)(nil)
-	_ container.TreeNode  = (*treeNode)(nil)
+	_ container.Container      = (*Tree)(nil)
+	_ container.MultiContainer = (*Tree)(nil)
+	_ container.Element        = (*treeNode)(nil)
+	_ container.Tree           = (*Tree)(nil)
+	_ container.TreeNode       = (*treeNode)(nil)
+	_ container.MutableElement = (*treeNode)(nil)
 )
 
 // treeNode is used for avl tree.
@@ -26,6 +29,8 @@ type treeNode struct {
 	left   *treeNode
 	right  *treeNode
 	height int
+	// The seq is the insertion order of node, only used for distinguish the duplicate keys.
+	seq uint64
 }
 
 // Key returns the key.
@@ -38,6 +43,11 @@ func (n *treeNode) Value() container.Value {
 	return n.value
 }
 
+// SetValue sets the value in place.
+func (n *treeNode) SetValue(v container.Value) {
+	n.value = v
+}
+
 // Left returns the left child of the TreeNode.
 func (n *treeNode) Left() container.TreeNode {
 	if n.left == nil {
@@ -56,8 +66,10 @@ func (n *treeNode) Right() container.TreeNode {
 
 // Tree implements the AVL Tree.
 type Tree struct {
-	root *treeNode
-	len  int
+	root  *treeNode
+	len   int
+	multi bool
+	seq   uint64
 }
 
 // New creates an AVL Tree.
@@ -68,6 +80,25 @@ func New() *Tree {
 	}
 }
 
+// NewMulti creates an AVL Tree that allows duplicate keys, the elements
+// with the same key are kept in insertion order.
+func NewMulti() *Tree {
+	return &Tree{
+		root:  nil,
+		len:   0,
+		multi: true,
+	}
+}
+
+// NewFromSorted creates a Tree with n elements returned by next in ascending order by key.
+// It builds a balanced tree in O(n) without comparison, so the keys must be strictly ascending.
+func NewFromSorted(n int, next func() (container.Key, container.Value)) *Tree {
+	tr := New()
+	tr.root = tr.buildSorted(n, next)
+	tr.len = n
+	return tr
+}
+
 // Root returns the root node of the tree.
 func (tr *Tree) Root() container.TreeNode {
 	if tr.root == nil {
@@ -83,6 +114,8 @@ func (tr *Tree) Len() int {
 
 // Insert inserts a new element if the key doesn't exist, or returns the existing element for the key if present.
 // The bool result is true if an element was inserted, false if searched.
+//
+// If the tree allows duplicate keys, it always inserts a new element behind the elements with the same key.
 func (tr *Tree) Insert(k container.Key, v container.Value) (container.Element, bool) {
 	_, node, ok := tr.insertOrSearch(k, v)
 	return node, ok
@@ -112,6 +145,12 @@ func (tr *Tree) Update(k container.Key, v container.Value) container.Element {
 // Upsert inserts or updates an element by giving key and value.
 // The bool result is true if an element was inserted, false if an element was updated.
 func (tr *Tree) Upsert(k container.Key, v container.Value) (container.Element, bool) {
+	if tr.multi {
+		if node, parent := tr.searchNode(k); node != nil {
+			tr.replaceNode(node, parent, tr.createNode(k, v))
+			return node, false
+		}
+	}
 	parent, node, ok := tr.insertOrSearch(k, v)
 	if !ok {
 		tr.replaceNode(node, parent, tr.createNode(k, v))
@@ -119,6 +158,29 @@ func (tr *Tree) Upsert(k container.Key, v container.Value) (container.Element, b
 	return node, ok
 }
 
+// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place.
+// Returns the element of key and its old value, returns nil if the key not be found.
+func (tr *Tree) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	node, _ := tr.searchNode(k)
+	if node == nil {
+		return nil, nil
+	}
+	old := node.value
+	node.value = v
+	return node, old
+}
+
+// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (tr *Tree) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	var ok bool
+	ele := tr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		ok = !exists
+		return v, true
+	})
+	return ele, ok
+}
+
 // Search searches the element of a given key.
 // Returns nil if key not found.
 func (tr *Tree) Search(k container.Key) container.Element {
@@ -129,6 +191,121 @@ func (tr *Tree) Search(k container.Key) container.Element {
 	return node
 }
 
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f. All of these are done with a single search.
+//
+// Returns the element of key after computed, nil if no element for the key.
+func (tr *Tree) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) container.Element {
+	var node *treeNode
+	if tr.multi {
+		tr.root, node, _, _ = tr.computeMultiWithBalance(tr.root, k, false, f)
+	} else {
+		tr.root, node, _ = tr.computeWithBalance(tr.root, k, f)
+	}
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+func (tr *Tree) GetOrInsert(k container.Key, f func() container.Value) (container.Element, bool) {
+	var ok bool
+	ele := tr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		if exists {
+			return ele.Value(), true
+		}
+		ok = true
+		return f(), true
+	})
+	return ele, ok
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+//
+// The tree is split at start and boundary, and the two remaining parts are joined back together,
+// Thus the cost is O(log n) plus the number of removed elements.
+func (tr *Tree) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return 0
+	}
+
+	var left, middle, right *treeNode
+
+	middle = tr.root
+	if start != nil {
+		left, middle = tr.split(middle, start)
+	}
+	if boundary != nil {
+		middle, right = tr.split(middle, boundary)
+	}
+	tr.root = tr.join2(left, right)
+
+	n := tr.releaseNodes(middle, f)
+	tr.len -= n
+	return n
+}
+
+// Clear removes all elements in the tree.
+func (tr *Tree) Clear() {
+	tr.root = nil
+	tr.len = 0
+}
+
+// SearchAll searches all elements of a given key, and returns them in insertion order.
+// Returns nil if key not found.
+func (tr *Tree) SearchAll(k container.Key) []container.Element {
+	var result []container.Element
+	tr.rangeKey(k, func(node *treeNode) bool {
+		result = append(result, node)
+		return true
+	})
+	return result
+}
+
+// DeleteOne removes and returns the first element of a given key that f returns true.
+// Returns nil if no element matched.
+func (tr *Tree) DeleteOne(k container.Key, f func(ele container.Element) bool) container.Element {
+	var d *treeNode
+	tr.rangeKey(k, func(node *treeNode) bool {
+		if f(node) {
+			d = node
+			return false
+		}
+		return true
+	})
+	if d == nil {
+		return nil
+	}
+	return tr.deleteNode(d.key, d.seq)
+}
+
+// DeleteAll removes all elements of a given key, and returns the number of removed elements.
+func (tr *Tree) DeleteAll(k container.Key) int {
+	var seqs []uint64
+	tr.rangeKey(k, func(node *treeNode) bool {
+		seqs = append(seqs, node.seq)
+		return true
+	})
+	for _, seq := range seqs {
+		tr.deleteNode(k, seq)
+	}
+	return len(seqs)
+}
+
+// Count returns the number of elements of a given key.
+func (tr *Tree) Count(k container.Key) int {
+	n := 0
+	tr.rangeKey(k, func(node *treeNode) bool {
+		n++
+		return true
+	})
+	return n
+}
+
 // Iter return an Iterator, it's a wrap for tree.Iterator.
 func (tr *Tree) Iter(start container.Key, boundary container.Key) container.Iterator {
 	return tree.NewIterator(tr.root, start, boundary)
@@ -139,6 +316,31 @@ func (tr *Tree) IterReverse(start container.Key, boundary container.Key) contain
 	return tree.NewIteratorReverse(tr.root, start, boundary)
 }
 
+// All returns an iterator over all elements in ascending order.
+func (tr *Tree) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(tr)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (tr *Tree) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(tr)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (tr *Tree) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(tr, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (tr *Tree) Keys() iter.Seq[container.Key] {
+	return container.Keys(tr)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (tr *Tree) Values() iter.Seq[container.Value] {
+	return container.Values(tr)
+}
+
 // Range calls f sequentially each TreeNode present in the Tree.
 // If f returns false, range stops the iteration.
 func (tr *Tree) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
@@ -189,8 +391,22 @@ func (tr *Tree) insertOrSearch(k container.Key, v container.Value) (parent *tree
 
 // Searches and deletes a node of a given key.
 func (tr *Tree) deleteAndSearch(k container.Key) *treeNode {
+	var seq uint64
+	if tr.multi {
+		// Deletes the first one of duplicate keys.
+		node, _ := tr.searchNode(k)
+		if node == nil {
+			return nil
+		}
+		seq = node.seq
+	}
+	return tr.deleteNode(k, seq)
+}
+
+// Deletes the node of a given key and seq.
+func (tr *Tree) deleteNode(k container.Key, seq uint64) *treeNode {
 	var d *treeNode
-	tr.root, d = tr.deleteWithBalance(tr.root, k)
+	tr.root, d = tr.deleteWithBalance(tr.root, k, seq)
 	if d == nil {
 		return nil
 	}
@@ -204,6 +420,20 @@ func (tr *Tree) deleteAndSearch(k container.Key) *treeNode {
 	return d
 }
 
+// Builds a balanced subtree with n elements returned by next.
+// The size of left subtree is equal to or one greater than the right, so the heights differ by at most 1.
+func (tr *Tree) buildSorted(n int, next func() (container.Key, container.Value)) *treeNode {
+	if n <= 0 {
+		return nil
+	}
+	left := tr.buildSorted(n/2, next)
+	node := tr.createNode(next())
+	node.left = left
+	node.right = tr.buildSorted(n-n/2-1, next)
+	node.height = tr.nodeHeight(node.left) + 1
+	return node
+}
+
 // Creates a new node with the giving key and value.
 func (tr *Tree) createNode(k container.Key, v container.Value) *treeNode {
 	return &treeNode{
@@ -220,6 +450,7 @@ func (tr *Tree) replaceNode(old, parent, n0 *treeNode) {
 	n0.left = old.left
 	n0.right = old.right
 	n0.height = old.height
+	n0.seq = old.seq
 
 	if parent == nil {
 		tr.root = n0
@@ -236,26 +467,58 @@ func (tr *Tree) replaceNode(old, parent, n0 *treeNode) {
 }
 
 // Searches the node and its parent node of a given key.
+// Returns the first one in insertion order if the tree allows duplicate keys.
 func (tr *Tree) searchNode(k container.Key) (node *treeNode, parent *treeNode) {
-	node = tr.root
-	for node != nil {
-		cmp := k.Compare(node.key)
+	var pp *treeNode
+	p := tr.root
+	for p != nil {
+		cmp := k.Compare(p.key)
 		if cmp == 0 {
 			// Found the node of key.
-			return
+			node, parent = p, pp
+			if !tr.multi {
+				return
+			}
+			// Continue to search the first one of duplicate keys.
 		}
 
-		parent = node // The parent node of n.
+		pp = p // The parent node of p.
 
-		if cmp == -1 {
-			node = node.left
+		if cmp == 1 {
+			p = p.right
 		} else {
-			node = node.right
+			p = p.left
 		}
 	}
 	return
 }
 
+// Calls f sequentially each node of a given key in insertion order.
+// If f returns false, stops the iteration.
+func (tr *Tree) rangeKey(k container.Key, f func(node *treeNode) bool) {
+	tree.Range(tr.root, k, nil, func(node container.TreeNode) bool {
+		if node.Key().Compare(k) != 0 {
+			return false
+		}
+		return f(node.(*treeNode))
+	})
+}
+
+// Compares the key and seq with the node.
+// The seq is used to distinguish the duplicate keys if the tree allows duplicate keys.
+func (tr *Tree) compare(k container.Key, seq uint64, node *treeNode) int {
+	cmp := k.Compare(node.key)
+	if cmp == 0 && tr.multi {
+		if seq < node.seq {
+			return -1
+		}
+		if seq > node.seq {
+			return 1
+		}
+	}
+	return cmp
+}
+
 // Inserts a node and re-balance during insertion.
 // Returns root node, new node, parent node of new node if key not exists.
 // And returns root node, node of key, parent node of key if key already exists.
@@ -263,6 +526,10 @@ func (tr *Tree) searchNode(k container.Key) (node *treeNode, parent *treeNode) {
 func (tr *Tree) insertWithBalance(r0 *treeNode, k container.Key, v container.Value) (root *treeNode, node *treeNode, parent *treeNode, ok bool) {
 	if r0 == nil {
 		node = tr.createNode(k, v)
+		if tr.multi {
+			tr.seq++
+			node.seq = tr.seq
+		}
 		root = node
 		parent = nil
 		ok = true
@@ -272,6 +539,10 @@ func (tr *Tree) insertWithBalance(r0 *treeNode, k container.Key, v container.Val
 	root = r0
 
 	cmp := k.Compare(root.key)
+	if cmp == 0 && tr.multi {
+		// Inserts the duplicate key behind the exists.
+		cmp = 1
+	}
 	if cmp == 0 {
 		node = root
 		return
@@ -279,12 +550,12 @@ func (tr *Tree) insertWithBalance(r0 *treeNode, k container.Key, v container.Val
 
 	// search the parent node
 	parent = root
-	if parent.left != nil && k.Compare(parent.left.key) == 0 {
+	if !tr.multi && parent.left != nil && k.Compare(parent.left.key) == 0 {
 		// Found the key
 		node = parent.left
 		return
 	}
-	if parent.right != nil && k.Compare(parent.right.key) == 0 {
+	if !tr.multi && parent.right != nil && k.Compare(parent.right.key) == 0 {
 		// Found the key
 		node = parent.right
 		return
@@ -304,21 +575,134 @@ func (tr *Tree) insertWithBalance(r0 *treeNode, k container.Key, v container.Val
 	return
 }
 
-// Deletes a node of key and re-balance during deletion, returns root node and deleted node.
-func (tr *Tree) deleteWithBalance(r0 *treeNode, k container.Key) (root *treeNode, d *treeNode) {
+// Searches the node of key and calls f with it, then inserts, updates or deletes the node
+// and re-balance during the search depending on the result of f.
+// Returns root node and the node of key after computed.
+// Thus, changed is true means the structure of tree has been changed.
+func (tr *Tree) computeWithBalance(r0 *treeNode, k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) (root *treeNode, node *treeNode, changed bool) {
+	if r0 == nil {
+		v, keep := f(nil, false)
+		if !keep {
+			return
+		}
+		node = tr.createNode(k, v)
+		root = node
+		changed = true
+		tr.len++
+		return
+	}
+
+	root = r0
+
+	cmp := k.Compare(root.key)
+	if cmp == -1 {
+		root.left, node, changed = tr.computeWithBalance(root.left, k, f)
+	} else if cmp == 1 {
+		root.right, node, changed = tr.computeWithBalance(root.right, k, f)
+	} else {
+		v, keep := f(root, true)
+		if keep {
+			root.value = v
+			node = root
+			return
+		}
+
+		var d *treeNode
+		root, d = tr.deleteWithBalance(root, k, root.seq)
+
+		// reset the unused field.
+		d.left = nil
+		d.right = nil
+		d.height = -1
+
+		tr.len--
+		changed = true
+		return
+	}
+
+	if changed {
+		root = tr.reBalance(root)
+	}
+	return
+}
+
+// The computeMultiWithBalance is similar to the computeWithBalance but used for the tree that allows duplicate keys.
+// It calls f with the first node of key in insertion order, so when a node of key is found, it continues to
+// search the left subtree for an earlier one. The dup is true if an ancestor of r0 is a node of key.
+// Thus, done is false means no earlier node of key in r0, and f has not been called.
+func (tr *Tree) computeMultiWithBalance(r0 *treeNode, k container.Key, dup bool, f func(ele container.Element, exists bool) (container.Value, bool)) (root *treeNode, node *treeNode, done bool, changed bool) {
+	if r0 == nil {
+		if dup {
+			return
+		}
+		done = true
+		v, keep := f(nil, false)
+		if !keep {
+			return
+		}
+		node = tr.createNode(k, v)
+		tr.seq++
+		node.seq = tr.seq
+		root = node
+		changed = true
+		tr.len++
+		return
+	}
+
+	root = r0
+
+	cmp := k.Compare(root.key)
+	if cmp == -1 {
+		root.left, node, done, changed = tr.computeMultiWithBalance(root.left, k, dup, f)
+	} else if cmp == 1 {
+		root.right, node, done, changed = tr.computeMultiWithBalance(root.right, k, dup, f)
+	} else {
+		root.left, node, done, changed = tr.computeMultiWithBalance(root.left, k, true, f)
+		if !done {
+			// The root is the first node of key.
+			done = true
+			v, keep := f(root, true)
+			if keep {
+				root.value = v
+				node = root
+				return
+			}
+
+			var d *treeNode
+			root, d = tr.deleteWithBalance(root, k, root.seq)
+
+			// reset the unused field.
+			d.left = nil
+			d.right = nil
+			d.height = -1
+
+			tr.len--
+			changed = true
+			return
+		}
+	}
+
+	if changed {
+		root = tr.reBalance(root)
+	}
+	return
+}
+
+// Deletes a node of key and seq, and re-balance during deletion, returns root node and deleted node.
+func (tr *Tree) deleteWithBalance(r0 *treeNode, k container.Key, seq uint64) (root *treeNode, d *treeNode) {
 	root = r0
 	if root == nil {
 		// The key not exists.
 		return
 	}
 
-	cmp := k.Compare(root.key)
+	cmp := tr.compare(k, seq, root)
 	if cmp == -1 {
 		// delete from the left subtree.
-		root.left, d = tr.deleteWithBalance(root.left, k)
+		root.left, d = tr.deleteWithBalance(root.left, k, seq)
 	} else if cmp == 1 {
 		// delete from the right subtree.
-		root.right, d = tr.deleteWithBalance(root.right, k)
+		root.right, d = tr.deleteWithBalance(root.right, k, seq)
 	} else {
 		d = root
 		if root.left != nil && root.right != nil {
@@ -329,7 +713,7 @@ func (tr *Tree) deleteWithBalance(r0 *treeNode, k container.Key) (root *treeNode
 				for x.right != nil {
 					x = x.right
 				}
-				x.left, _ = tr.deleteWithBalance(root.left, x.key)
+				x.left, _ = tr.deleteWithBalance(root.left, x.key, x.seq)
 				x.right = root.right
 			} else {
 				// Replace the location of the deleted node with its successor
@@ -337,7 +721,7 @@ func (tr *Tree) deleteWithBalance(r0 *treeNode, k container.Key) (root *treeNode
 				for x.left != nil {
 					x = x.left
 				}
-				x.right, _ = tr.deleteWithBalance(root.right, x.key)
+				x.right, _ = tr.deleteWithBalance(root.right, x.key, x.seq)
 				x.left = root.left
 			}
 			x.height = tr.calculateHeight(x)
@@ -355,6 +739,89 @@ func (tr *Tree) deleteWithBalance(r0 *treeNode, k container.Key) (root *treeNode
 	return
 }
 
+// Splits the tree r0 into two trees, one with the keys that less than k,
+// and another with the keys that greater than or equal to k.
+func (tr *Tree) split(r0 *treeNode, k container.Key) (left *treeNode, right *treeNode) {
+	if r0 == nil {
+		return
+	}
+	if r0.key.Compare(k) == -1 {
+		left, right = tr.split(r0.right, k)
+		left = tr.join(r0.left, r0, left)
+	} else {
+		left, right = tr.split(r0.left, k)
+		right = tr.join(right, r0, r0.right)
+	}
+	return
+}
+
+// Joins the tree left, the node and the tree right into a balanced tree, returns the root node.
+// All keys in left must less than node's key, and all keys in right must greater than it.
+func (tr *Tree) join(left *treeNode, node *treeNode, right *treeNode) *treeNode {
+	lh := tr.nodeHeight(left)
+	rh := tr.nodeHeight(right)
+
+	if lh > rh+1 {
+		left.right = tr.join(left.right, node, right)
+		return tr.reBalance(left)
+	}
+	if rh > lh+1 {
+		right.left = tr.join(left, node, right.left)
+		return tr.reBalance(right)
+	}
+
+	node.left = left
+	node.right = right
+	node.height = tr.calculateHeight(node)
+	return node
+}
+
+// Joins the tree left and the tree right into a balanced tree, returns the root node.
+// All keys in left must less than the keys in right.
+func (tr *Tree) join2(left *treeNode, right *treeNode) *treeNode {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	var x *treeNode
+	right, x = tr.deleteMin(right)
+	return tr.join(left, x, right)
+}
+
+// Deletes the node with minimum key in the tree r0, returns the root node and deleted node.
+func (tr *Tree) deleteMin(r0 *treeNode) (root *treeNode, d *treeNode) {
+	if r0.left == nil {
+		return r0.right, r0
+	}
+	r0.left, d = tr.deleteMin(r0.left)
+	root = tr.reBalance(r0)
+	return
+}
+
+// Resets all nodes of the detached tree r0 by in-order traversal, returns the number of nodes.
+// f will be called with each node if it is not nil.
+func (tr *Tree) releaseNodes(r0 *treeNode, f func(ele container.Element)) int {
+	if r0 == nil {
+		return 0
+	}
+	n := 0
+	tree.LDR(r0, func(node container.TreeNode) bool {
+		d := node.(*treeNode)
+		// reset the unused field. LDR has already read both children before calls f.
+		d.left = nil
+		d.right = nil
+		d.height = -1
+		if f != nil {
+			f(d)
+		}
+		n++
+		return true
+	})
+	return n
+}
+
 func (tr *Tree) reBalance(node *treeNode) *treeNode {
 	if node == nil {
 		return nil
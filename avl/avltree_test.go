@@ -34,10 +34,10 @@ func checkBalance(t *testing.T, tr *Tree, n *treeNode) {
 	require.Equal(t, tr.nodeHeight(n), recurseCalculateNodeHeight(n))
 
 	if n.left != nil {
-		require.Equal(t, n.key.Compare(n.left.key), 1)
+		require.Equal(t, tr.compare(n.key, n.seq, n.left), 1)
 	}
 	if n.right != nil {
-		require.Equal(t, n.key.Compare(n.right.key), -1)
+		require.Equal(t, tr.compare(n.key, n.seq, n.right), -1)
 	}
 
 	// The height difference cannot exceed 1 in AVL Tree.
@@ -130,3 +130,156 @@ func TestTree(t *testing.T) {
 		require.Equal(t, tr.Len(), 0)
 	}
 }
+
+func TestTree_DeleteRange(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for x := 0; x < 64; x++ {
+		tr := New()
+		length := r.Intn(512) + 1
+		for i := 0; i < length; i++ {
+			tr.Insert(container.Int(i), i)
+		}
+
+		start := r.Intn(length)
+		boundary := start + r.Intn(length-start+1)
+
+		n := tr.DeleteRange(container.Int(start), container.Int(boundary), nil)
+		require.Equal(t, n, boundary-start)
+		require.Equal(t, tr.Len(), length-n)
+		checkBalance(t, tr, tr.root)
+
+		for i := 0; i < length; i++ {
+			if i >= start && i < boundary {
+				require.Nil(t, tr.Search(container.Int(i)))
+			} else {
+				require.NotNil(t, tr.Search(container.Int(i)))
+			}
+		}
+	}
+}
+
+func TestTree_Compute(t *testing.T) {
+	tr := New()
+
+	length := 257
+	for i := 0; i < length; i++ {
+		tr.Compute(container.Int(i), func(ele container.Element, exists bool) (container.Value, bool) {
+			return i, true
+		})
+		checkBalance(t, tr, tr.root)
+		require.Equal(t, tr.Len(), i+1)
+	}
+	for i := 0; i < length; i++ {
+		tr.Compute(container.Int(i), func(ele container.Element, exists bool) (container.Value, bool) {
+			return nil, false
+		})
+		checkBalance(t, tr, tr.root)
+		require.Equal(t, tr.Len(), length-i-1)
+	}
+	require.Nil(t, tr.root)
+}
+
+func TestTree_Multi(t *testing.T) {
+	tr := NewMulti()
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	length := 1024
+	for i := 0; i < length; i++ {
+		_, ok := tr.Insert(container.Int(r.Intn(32)), i)
+		require.True(t, ok)
+		checkBalance(t, tr, tr.root)
+		require.Equal(t, tr.Len(), i+1)
+	}
+
+	for tr.Len() > 0 {
+		k := container.Int(r.Intn(32))
+		switch r.Intn(3) {
+		case 0:
+			tr.Delete(k)
+		case 1:
+			tr.DeleteOne(k, func(ele container.Element) bool {
+				return ele.Value().(int)%2 == 0
+			})
+		case 2:
+			tr.DeleteAll(k)
+		}
+		checkBalance(t, tr, tr.root)
+	}
+	require.Nil(t, tr.root)
+}
+
+func TestTree_ComputeMulti(t *testing.T) {
+	tr := NewMulti()
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// The values of each key in insertion order.
+	model := make(map[int][]int)
+	for i := 0; i < 4096; i++ {
+		k := r.Intn(32)
+		calls := 0
+		tr.Compute(container.Int(k), func(ele container.Element, exists bool) (container.Value, bool) {
+			calls++
+			require.Equal(t, exists, len(model[k]) != 0)
+			if !exists {
+				model[k] = append(model[k], i)
+				return i, true
+			}
+			// Operates on the first one of duplicate keys.
+			require.Equal(t, ele.Value(), model[k][0])
+			if r.Intn(2) == 0 {
+				model[k] = model[k][1:]
+				return nil, false
+			}
+			model[k][0] = i
+			return i, true
+		})
+		require.Equal(t, calls, 1)
+		if r.Intn(2) == 0 {
+			tr.Insert(container.Int(k), -i)
+			model[k] = append(model[k], -i)
+		}
+		checkBalance(t, tr, tr.root)
+
+		n := 0
+		for _, vs := range model {
+			n += len(vs)
+		}
+		require.Equal(t, tr.Len(), n)
+		var values []interface{}
+		for _, ele := range tr.SearchAll(container.Int(k)) {
+			values = append(values, ele.Value())
+		}
+		var expected []interface{}
+		for _, v := range model[k] {
+			expected = append(expected, v)
+		}
+		require.Equal(t, values, expected)
+	}
+}
+
+func TestNewFromSorted(t *testing.T) {
+	for n := 0; n < 300; n++ {
+		i := 0
+		tr := NewFromSorted(n, func() (container.Key, container.Value) {
+			i++
+			return container.Int(i), i
+		})
+		require.Equal(t, tr.Len(), n)
+		checkBalance(t, tr, tr.root)
+
+		j := 0
+		tr.Range(nil, nil, func(ele container.Element) bool {
+			j++
+			require.Equal(t, ele.Key(), container.Int(j))
+			return true
+		})
+		require.Equal(t, j, n)
+
+		// The tree is usable after built.
+		_, ok := tr.Insert(container.Int(n+1), n+1)
+		require.True(t, ok)
+		require.NotNil(t, tr.Delete(container.Int(n/2+1)))
+		checkBalance(t, tr, tr.root)
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package bptree implements a B+tree stored in a file of fixed-size pages.
+//
+// The leaves are doubly linked for fast scans in both directions, and the pages are read through a
+// memory-mapped file if the platform supports it. The decoded pages are kept in a LRU page cache.
+//
+// The pages refer to each other by logical page ids, and a page table maps the logical ids to the
+// physical pages in the file. The changes are kept in memory until committed. A commit writes each
+// changed page and the changed parts of page table to free physical pages, and then writes the meta
+// page that points to the new page table. There are two meta pages used alternately, so a crash
+// during commit never corrupts the last committed tree. Because of the page table, a changed leaf
+// does not require to copy its neighbors to update the links.
+//
+// The file layout:
+//
+//	[meta 0] [meta 1] [page] [page] ...
+//
+// A node page starts with a header of the checksum, the type, the number of keys and the links of leaf.
+// A leaf holds the keys and values, and an internal node holds the keys and the logical ids of children,
+// each key or value is encoded by the codec.Registry. A page of page table holds an array of page ids.
+package bptree
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+
+	"github.com/yu31/structs-go/codec"
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	// ErrCorrupted is returned when the file is malformed or the checksum mismatched.
+	ErrCorrupted = errors.New("bptree: corrupted file")
+	// ErrTooLarge is returned when putting an element that too large for a page.
+	ErrTooLarge = errors.New("bptree: element too large")
+	// ErrClosed is returned when using a closed Tree.
+	ErrClosed = errors.New("bptree: tree is closed")
+)
+
+const (
+	defaultPageSize  = 4096
+	minPageSize      = 512
+	defaultCacheSize = 1024
+)
+
+// Options is the options of Tree.
+type Options struct {
+	// PageSize is the size of pages of a new file, default 4 KiB and at least 512 bytes.
+	// The page size of an existing file is read from the file.
+	PageSize int
+	// CacheSize is the number of decoded pages kept in memory, default 1024.
+	CacheSize int
+	// Registry is used to encode and decode the keys and values, default codec.Default.
+	// The decoded values must not retain the input data, since it may refer to the memory-mapped file.
+	Registry *codec.Registry
+	// NoSync disables to sync the file on commit, a machine crash may lose or corrupt the commits.
+	NoSync bool
+}
+
+// Returns a copy of opts with defaults filled.
+func copyOptions(opts *Options) *Options {
+	o := &Options{}
+	if opts != nil {
+		*o = *opts
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = defaultPageSize
+	}
+	if o.PageSize < minPageSize {
+		o.PageSize = minPageSize
+	}
+	if o.CacheSize <= 0 {
+		o.CacheSize = defaultCacheSize
+	}
+	if o.Registry == nil {
+		o.Registry = codec.Default
+	}
+	return o
+}
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+const (
+	magic    = 0x6270747265656f31 // "bptreeo1"
+	metaSize = 92
+
+	// The header of node: 4 bytes checksum, 1 byte type, 2 bytes number of keys, and 8 bytes each for
+	// the prev and next links of leaf.
+	headerSize = 23
+	// The header of table page: 4 bytes checksum and 4 bytes padding.
+	tableHeaderSize = 8
+
+	leafType     byte = 1
+	internalType byte = 2
+)
+
+// meta is the root of a committed tree.
+type meta struct {
+	pageSize   int
+	height     int    // The number of levels of nodes, 0 if the tree is empty.
+	txid       uint64 // Increased on each commit, the meta with larger txid is the latest.
+	tableRoot  uint64 // The physical page of the root of page table.
+	tableDepth int
+	logical    uint64 // The number of logical ids, including the unused 0.
+	physical   uint64 // The number of physical pages in the file.
+	root       uint64 // The logical ids of root, first leaf and last leaf, 0 if the tree is empty.
+	first      uint64
+	last       uint64
+	count      uint64 // The number of elements.
+}
+
+func (m *meta) encode(page []byte) {
+	binary.LittleEndian.PutUint64(page[0:], magic)
+	binary.LittleEndian.PutUint32(page[8:], uint32(m.pageSize))
+	binary.LittleEndian.PutUint32(page[12:], uint32(m.height))
+	binary.LittleEndian.PutUint64(page[16:], m.txid)
+	binary.LittleEndian.PutUint64(page[24:], m.tableRoot)
+	binary.LittleEndian.PutUint64(page[32:], uint64(m.tableDepth))
+	binary.LittleEndian.PutUint64(page[40:], m.logical)
+	binary.LittleEndian.PutUint64(page[48:], m.physical)
+	binary.LittleEndian.PutUint64(page[56:], m.root)
+	binary.LittleEndian.PutUint64(page[64:], m.first)
+	binary.LittleEndian.PutUint64(page[72:], m.last)
+	binary.LittleEndian.PutUint64(page[80:], m.count)
+	binary.LittleEndian.PutUint32(page[88:], crc32.Checksum(page[:88], crcTable))
+}
+
+// Decodes the meta, returns false if it is invalid.
+func (m *meta) decode(page []byte) bool {
+	if len(page) < metaSize || binary.LittleEndian.Uint64(page) != magic {
+		return false
+	}
+	if crc32.Checksum(page[:88], crcTable) != binary.LittleEndian.Uint32(page[88:]) {
+		return false
+	}
+	m.pageSize = int(binary.LittleEndian.Uint32(page[8:]))
+	m.height = int(binary.LittleEndian.Uint32(page[12:]))
+	m.txid = binary.LittleEndian.Uint64(page[16:])
+	m.tableRoot = binary.LittleEndian.Uint64(page[24:])
+	m.tableDepth = int(binary.LittleEndian.Uint64(page[32:]))
+	m.logical = binary.LittleEndian.Uint64(page[40:])
+	m.physical = binary.LittleEndian.Uint64(page[48:])
+	m.root = binary.LittleEndian.Uint64(page[56:])
+	m.first = binary.LittleEndian.Uint64(page[64:])
+	m.last = binary.LittleEndian.Uint64(page[72:])
+	m.count = binary.LittleEndian.Uint64(page[80:])
+	return m.pageSize >= minPageSize && m.tableDepth > 0 && m.logical > 0
+}
+
+// node is a decoded page of leaf or internal node.
+type node struct {
+	leaf     bool
+	keys     []container.Key
+	values   []container.Value // The values of leaf.
+	children []uint64          // The children of internal node, children[i+1] holds the keys >= keys[i].
+	prev     uint64            // The links of leaf.
+	next     uint64
+	size     int // The encoded size.
+}
+
+// Returns a copy of n that can be changed.
+func (n *node) clone() *node {
+	c := *n
+	c.keys = append([]container.Key(nil), n.keys...)
+	if n.leaf {
+		c.values = append([]container.Value(nil), n.values...)
+	} else {
+		c.children = append([]uint64(nil), n.children...)
+	}
+	return &c
+}
+
+// Encodes the node into page, the page must be zeroed.
+func (n *node) encode(page []byte, registry *codec.Registry) error {
+	typ := leafType
+	if !n.leaf {
+		typ = internalType
+	}
+	page[4] = typ
+	binary.LittleEndian.PutUint16(page[5:], uint16(len(n.keys)))
+	binary.LittleEndian.PutUint64(page[7:], n.prev)
+	binary.LittleEndian.PutUint64(page[15:], n.next)
+
+	buf := page[headerSize:headerSize]
+	var err error
+	if !n.leaf {
+		buf = appendUint64(buf, n.children[0])
+	}
+	for i, k := range n.keys {
+		if buf, err = registry.AppendValue(buf, k); err != nil {
+			return err
+		}
+		if n.leaf {
+			buf, err = registry.AppendValue(buf, n.values[i])
+		} else {
+			buf = appendUint64(buf, n.children[i+1])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if headerSize+len(buf) > len(page) {
+		// The node must be split before encoding.
+		panic("bptree: node overflows the page")
+	}
+	binary.LittleEndian.PutUint32(page, crc32.Checksum(page[4:], crcTable))
+	return nil
+}
+
+// Decodes a node from page.
+func decodeNode(page []byte, registry *codec.Registry) (*node, error) {
+	if crc32.Checksum(page[4:], crcTable) != binary.LittleEndian.Uint32(page) {
+		return nil, ErrCorrupted
+	}
+	n := &node{
+		leaf: page[4] == leafType,
+		prev: binary.LittleEndian.Uint64(page[7:]),
+		next: binary.LittleEndian.Uint64(page[15:]),
+	}
+	if !n.leaf && page[4] != internalType {
+		return nil, ErrCorrupted
+	}
+
+	count := int(binary.LittleEndian.Uint16(page[5:]))
+	data := page[headerSize:]
+	n.keys = make([]container.Key, count)
+	if n.leaf {
+		n.values = make([]container.Value, count)
+	} else {
+		if len(data) < 8 {
+			return nil, ErrCorrupted
+		}
+		n.children = make([]uint64, count+1)
+		n.children[0] = binary.LittleEndian.Uint64(data)
+		data = data[8:]
+	}
+	for i := 0; i < count; i++ {
+		v, m, err := registry.ReadValue(data)
+		if err != nil {
+			return nil, err
+		}
+		k, ok := v.(container.Key)
+		if !ok || k == nil {
+			return nil, ErrCorrupted
+		}
+		n.keys[i] = k
+		data = data[m:]
+
+		if n.leaf {
+			if n.values[i], m, err = registry.ReadValue(data); err != nil {
+				return nil, err
+			}
+			data = data[m:]
+		} else {
+			if len(data) < 8 {
+				return nil, ErrCorrupted
+			}
+			n.children[i+1] = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		}
+	}
+	n.size = len(page) - len(data)
+	return n, nil
+}
+
+// Encodes the page ids into a page of page table, the page must be zeroed.
+func encodeTable(page []byte, ids []uint64) {
+	for i, id := range ids {
+		binary.LittleEndian.PutUint64(page[tableHeaderSize+i*8:], id)
+	}
+	binary.LittleEndian.PutUint32(page, crc32.Checksum(page[4:], crcTable))
+}
+
+// Decodes the first n page ids from a page of page table.
+func decodeTable(page []byte, n int) ([]uint64, error) {
+	if crc32.Checksum(page[4:], crcTable) != binary.LittleEndian.Uint32(page) {
+		return nil, ErrCorrupted
+	}
+	ids := make([]uint64, n)
+	for i := range ids {
+		ids[i] = binary.LittleEndian.Uint64(page[tableHeaderSize+i*8:])
+	}
+	return ids, nil
+}
+
+func appendUint64(buf []byte, x uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	return append(buf, b[:]...)
+}
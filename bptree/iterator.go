@@ -0,0 +1,193 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package bptree
+
+import (
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	_ container.Iterator = (*Iterator)(nil)
+	_ container.Iterator = (*ReverseIterator)(nil)
+)
+
+// cursor is a position in the leaves, n is nil if out of the tree.
+type cursor struct {
+	t *Tree
+	n *node
+	i int
+}
+
+// Moves to the next leaf while the index is out of the leaf.
+func (c *cursor) forward() {
+	for c.n != nil && c.i >= len(c.n.keys) {
+		if c.n.next == 0 {
+			c.n = nil
+			return
+		}
+		c.n = c.t.node(c.n.next)
+		c.i = 0
+	}
+}
+
+// Moves to the previous leaf while the index is out of the leaf.
+func (c *cursor) backward() {
+	for c.n != nil && c.i < 0 {
+		if c.n.prev == 0 {
+			c.n = nil
+			return
+		}
+		if c.n = c.t.node(c.n.prev); c.n != nil {
+			c.i = len(c.n.keys) - 1
+		}
+	}
+}
+
+// Returns the element at the position, nil if out of the tree.
+func (c *cursor) element() container.Element {
+	if c.n == nil {
+		return nil
+	}
+	return &element{key: c.n.keys[c.i], value: c.n.values[c.i]}
+}
+
+// Returns the position of first key >= k, or the first element if k is nil.
+func (t *Tree) seekGE(k container.Key) *cursor {
+	c := &cursor{t: t}
+	if k == nil {
+		if t.meta.first != 0 {
+			c.n = t.node(t.meta.first)
+		}
+	} else if c.n = t.leafOf(k); c.n != nil {
+		c.i = searchGE(c.n, k)
+	}
+	c.forward()
+	return c
+}
+
+// Returns the position of first key > k.
+func (t *Tree) seekGT(k container.Key) *cursor {
+	c := &cursor{t: t}
+	if c.n = t.leafOf(k); c.n != nil {
+		c.i = searchGT(c.n, k)
+	}
+	c.forward()
+	return c
+}
+
+// Returns the position of last key < k, or the last element if k is nil.
+func (t *Tree) seekLT(k container.Key) *cursor {
+	c := &cursor{t: t}
+	if k == nil {
+		if t.meta.last != 0 {
+			if c.n = t.node(t.meta.last); c.n != nil {
+				c.i = len(c.n.keys) - 1
+			}
+		}
+	} else if c.n = t.leafOf(k); c.n != nil {
+		c.i = searchGE(c.n, k) - 1
+	}
+	c.backward()
+	return c
+}
+
+// Returns the position of last key <= k.
+func (t *Tree) seekLE(k container.Key) *cursor {
+	c := &cursor{t: t}
+	if c.n = t.leafOf(k); c.n != nil {
+		c.i = searchGT(c.n, k) - 1
+	}
+	c.backward()
+	return c
+}
+
+// Iterator implements container.Iterator, it follows the links of leaves.
+type Iterator struct {
+	c        *cursor
+	boundary container.Key
+	node     container.Element
+}
+
+func newIterator(t *Tree, start container.Key, boundary container.Key) *Iterator {
+	it := &Iterator{
+		c:        t.seekGE(start),
+		boundary: boundary,
+	}
+	it.advance()
+	return it
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (it *Iterator) Valid() bool {
+	return it.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (it *Iterator) Next() container.Element {
+	if it.node == nil {
+		return nil
+	}
+	n := it.node
+	it.advance()
+	return n
+}
+
+// Moves the node to the element at cursor, and moves the cursor forward.
+func (it *Iterator) advance() {
+	it.node = nil
+	ele := it.c.element()
+	if ele == nil || (it.boundary != nil && ele.Key().Compare(it.boundary) != -1) {
+		return
+	}
+	it.node = ele
+	it.c.i++
+	it.c.forward()
+}
+
+// ReverseIterator implements container.Iterator, it follows the links of leaves in reverse.
+type ReverseIterator struct {
+	c     *cursor
+	start container.Key
+	node  container.Element
+}
+
+func newReverseIterator(t *Tree, start container.Key, boundary container.Key) *ReverseIterator {
+	it := &ReverseIterator{
+		c:     t.seekLT(boundary),
+		start: start,
+	}
+	it.advance()
+	return it
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (it *ReverseIterator) Valid() bool {
+	return it.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (it *ReverseIterator) Next() container.Element {
+	if it.node == nil {
+		return nil
+	}
+	n := it.node
+	it.advance()
+	return n
+}
+
+// Moves the node to the element at cursor, and moves the cursor backward.
+func (it *ReverseIterator) advance() {
+	it.node = nil
+	ele := it.c.element()
+	if ele == nil || (it.start != nil && ele.Key().Compare(it.start) == -1) {
+		return
+	}
+	it.node = ele
+	it.c.i--
+	it.c.backward()
+}
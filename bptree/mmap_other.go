@@ -0,0 +1,22 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package bptree
+
+import (
+	"errors"
+	"os"
+)
+
+// The file is read by ReadAt on the platforms without mmap.
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, errors.New("bptree: mmap not supported")
+}
+
+func munmap(data []byte) error {
+	return nil
+}
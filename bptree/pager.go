@@ -0,0 +1,348 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package bptree
+
+import (
+	"os"
+	"sort"
+)
+
+// pager manages the physical pages and the page table of file.
+//
+// The page table is a radix tree of table pages, each level holds the physical pages of the previous
+// level, and the top level is a single page. The whole page table is kept in memory, it is a small
+// fraction of the file.
+type pager struct {
+	file     *os.File
+	pageSize int
+	fanout   int    // The number of page ids in a table page.
+	data     []byte // The memory-mapped file, nil if not mapped.
+	noSync   bool
+
+	meta   meta       // The last committed meta.
+	table  []uint64   // The physical page of each logical id, 0 if the logical id is free.
+	levels [][]uint64 // levels[i] holds the physical pages of the table pages of level i.
+	free   []uint64   // The free physical pages in descending order.
+}
+
+// Opens the file, a new file is initialized with an empty tree.
+func openPager(path string, opts *Options) (*pager, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	p := &pager{file: f, noSync: opts.NoSync}
+	if fi.Size() == 0 {
+		err = p.init(opts.PageSize)
+	} else {
+		err = p.load()
+	}
+	if err != nil {
+		p.close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Initializes a new file with an empty tree.
+func (p *pager) init(pageSize int) error {
+	p.setPageSize(pageSize)
+	p.meta = meta{pageSize: pageSize, logical: 1, physical: 2}
+	p.table = []uint64{0}
+	p.levels = nil
+	p.free = nil
+	return p.commit(nil, p.meta)
+}
+
+func (p *pager) setPageSize(pageSize int) {
+	p.pageSize = pageSize
+	p.fanout = (pageSize - tableHeaderSize) / 8
+}
+
+// Loads the latest valid meta and the page table.
+func (p *pager) load() error {
+	var m0, m1 meta
+	ok0 := p.readMeta(0, &m0)
+	ok1 := false
+	if ok0 {
+		ok1 = p.readMeta(m0.pageSize, &m1) && m1.pageSize == m0.pageSize
+	} else {
+		// The page size is unknown if meta 0 is corrupted, so meta 1 is searched by the possible page sizes.
+		for size := minPageSize; size <= 1<<20 && !ok1; size *= 2 {
+			ok1 = p.readMeta(size, &m1) && m1.pageSize == size
+		}
+	}
+
+	switch {
+	case ok0 && (!ok1 || m0.txid > m1.txid):
+		p.meta = m0
+	case ok1:
+		p.meta = m1
+	default:
+		return ErrCorrupted
+	}
+	p.setPageSize(p.meta.pageSize)
+
+	fi, err := p.file.Stat()
+	if err != nil {
+		return err
+	}
+	if uint64(fi.Size()) < p.meta.physical*uint64(p.pageSize) || p.meta.tableRoot < 2 || p.meta.tableRoot >= p.meta.physical {
+		return ErrCorrupted
+	}
+	if err := p.loadTable(); err != nil {
+		return err
+	}
+	p.remap()
+	return nil
+}
+
+// Reads and decodes the meta at offset, returns false if it is invalid.
+func (p *pager) readMeta(off int, m *meta) bool {
+	buf := make([]byte, metaSize)
+	if _, err := p.file.ReadAt(buf, int64(off)); err != nil {
+		return false
+	}
+	return m.decode(buf)
+}
+
+// Returns the number of entries of each level of page table, counts[0] is the number of logical ids.
+func (p *pager) tableCounts(logical uint64) []int {
+	counts := []int{int(logical)}
+	for counts[len(counts)-1] > p.fanout {
+		n := counts[len(counts)-1]
+		counts = append(counts, (n+p.fanout-1)/p.fanout)
+	}
+	return counts
+}
+
+// Loads the page table of the committed meta, and collects the free pages.
+func (p *pager) loadTable() error {
+	counts := p.tableCounts(p.meta.logical)
+	if len(counts) != p.meta.tableDepth {
+		return ErrCorrupted
+	}
+
+	levels := make([][]uint64, len(counts))
+	levels[len(levels)-1] = []uint64{p.meta.tableRoot}
+	var table []uint64
+	for i := len(counts) - 1; i >= 0; i-- {
+		entries := make([]uint64, 0, counts[i])
+		for _, phys := range levels[i] {
+			n := counts[i] - len(entries)
+			if n > p.fanout {
+				n = p.fanout
+			}
+			page, err := p.read(phys)
+			if err != nil {
+				return err
+			}
+			ids, err := decodeTable(page, n)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, ids...)
+		}
+		for _, phys := range entries {
+			if phys != 0 && (phys < 2 || phys >= p.meta.physical) {
+				return ErrCorrupted
+			}
+		}
+		if i > 0 {
+			levels[i-1] = entries
+		} else {
+			table = entries
+		}
+	}
+	p.table = table
+	p.levels = levels
+
+	// The pages not used by the committed tree are free.
+	used := make([]bool, p.meta.physical)
+	used[0], used[1] = true, true
+	for _, level := range levels {
+		for _, phys := range level {
+			used[phys] = true
+		}
+	}
+	for _, phys := range table {
+		used[phys] = true
+	}
+	p.free = p.free[:0]
+	for i := len(used) - 1; i >= 2; i-- {
+		if !used[i] {
+			p.free = append(p.free, uint64(i))
+		}
+	}
+	return nil
+}
+
+// Reads a physical page, the result refers to the memory-mapped file if mapped.
+func (p *pager) read(phys uint64) ([]byte, error) {
+	off := int(phys) * p.pageSize
+	if off+p.pageSize <= len(p.data) {
+		return p.data[off : off+p.pageSize], nil
+	}
+	page := make([]byte, p.pageSize)
+	if _, err := p.file.ReadAt(page, int64(off)); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Maps the file into memory, the file is read by ReadAt if failed.
+func (p *pager) remap() {
+	size := int(p.meta.physical) * p.pageSize
+	if size <= len(p.data) {
+		return
+	}
+	if p.data != nil {
+		munmap(p.data)
+		p.data = nil
+	}
+	if data, err := mmap(p.file, size); err == nil {
+		p.data = data
+	}
+}
+
+// Allocates a physical page for the commit.
+func (p *pager) alloc(m *meta) uint64 {
+	if n := len(p.free); n != 0 {
+		phys := p.free[n-1]
+		p.free = p.free[:n-1]
+		return phys
+	}
+	phys := m.physical
+	m.physical++
+	return phys
+}
+
+// Writes the encoded pages of logical ids, updates the page table and commits the meta m.
+// A nil page frees the logical id. The changes of pager are discarded if failed.
+func (p *pager) commit(pages map[uint64][]byte, m meta) (err error) {
+	defer func() {
+		if err != nil {
+			// Restores the committed state from the file.
+			if e := p.loadTable(); e != nil {
+				err = e
+			}
+		}
+	}()
+
+	var pending []uint64 // The pages replaced by this commit, they are free after committed.
+	ids := make([]uint64, 0, len(pages))
+	for id := range pages {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	table := p.table
+	for uint64(len(table)) < m.logical {
+		table = append(table, 0)
+	}
+	dirty := make(map[int]bool)
+	for _, id := range ids {
+		if old := table[id]; old != 0 {
+			pending = append(pending, old)
+		}
+		table[id] = 0
+		if page := pages[id]; page != nil {
+			phys := p.alloc(&m)
+			if _, err := p.file.WriteAt(page, int64(phys)*int64(p.pageSize)); err != nil {
+				return err
+			}
+			table[id] = phys
+		}
+		dirty[int(id)/p.fanout] = true
+	}
+
+	// Writes the changed table pages level by level.
+	counts := p.tableCounts(m.logical)
+	levels := make([][]uint64, len(counts))
+	page := make([]byte, p.pageSize)
+	entries := table
+	for i := range counts {
+		n := (counts[i] + p.fanout - 1) / p.fanout
+		levels[i] = make([]uint64, n)
+		if i < len(p.levels) {
+			copy(levels[i], p.levels[i])
+		}
+		next := make(map[int]bool)
+		for j := 0; j < n; j++ {
+			if levels[i][j] != 0 && !dirty[j] {
+				continue
+			}
+			if old := levels[i][j]; old != 0 {
+				pending = append(pending, old)
+			}
+			end := (j + 1) * p.fanout
+			if end > len(entries) {
+				end = len(entries)
+			}
+			for k := range page {
+				page[k] = 0
+			}
+			encodeTable(page, entries[j*p.fanout:end])
+			phys := p.alloc(&m)
+			if _, err := p.file.WriteAt(page, int64(phys)*int64(p.pageSize)); err != nil {
+				return err
+			}
+			levels[i][j] = phys
+			next[j/p.fanout] = true
+		}
+		entries = levels[i]
+		dirty = next
+	}
+	// The old top levels that no longer needed are freed.
+	for i := len(levels); i < len(p.levels); i++ {
+		pending = append(pending, p.levels[i]...)
+	}
+
+	if !p.noSync {
+		if err := p.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	m.pageSize = p.pageSize
+	m.txid = p.meta.txid + 1
+	m.tableRoot = levels[len(levels)-1][0]
+	m.tableDepth = len(levels)
+	for k := range page {
+		page[k] = 0
+	}
+	m.encode(page)
+	if _, err := p.file.WriteAt(page, int64(m.txid%2)*int64(p.pageSize)); err != nil {
+		return err
+	}
+	if !p.noSync {
+		if err := p.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	p.meta = m
+	p.table = table
+	p.levels = levels
+	p.free = append(p.free, pending...)
+	sort.Slice(p.free, func(i, j int) bool { return p.free[i] > p.free[j] })
+	p.remap()
+	return nil
+}
+
+func (p *pager) close() error {
+	if p.data != nil {
+		munmap(p.data)
+		p.data = nil
+	}
+	return p.file.Close()
+}
@@ -0,0 +1,706 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package bptree
+
+import (
+	"iter"
+	"sort"
+
+	"github.com/yu31/structs-go/cache"
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	_ container.Retriever = (*Tree)(nil)
+	_ container.Searcher  = (*Tree)(nil)
+	_ container.Element   = (*element)(nil)
+)
+
+// element is an element read from the tree.
+type element struct {
+	key   container.Key
+	value container.Value
+}
+
+// Key returns the key.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value.
+func (e *element) Value() container.Value {
+	return e.value
+}
+
+// The location of a child in an internal node.
+type pathEntry struct {
+	id    uint64
+	index int
+}
+
+// Tree is a B+tree stored in a file.
+//
+// The changes made by Put and Delete are visible to the Tree immediately, and they are written to
+// the file when Commit. The Rollback method discards the uncommitted changes. The Tree must not be
+// changed during iteration.
+//
+// The methods of Retriever and Searcher can not return errors, so an I/O error or a corrupted page is
+// treated as no more elements, and it's recorded to be returned by the Err method. After an error,
+// the uncommitted changes may be inconsistent, so the Put, Delete and Commit returns the error until Rollback.
+//
+// A Tree is not safe for concurrent use.
+type Tree struct {
+	p     *pager
+	opts  *Options
+	cache *cache.LRU // The decoded committed nodes by logical id.
+
+	meta    meta             // The uncommitted meta.
+	dirty   map[uint64]*node // The changed nodes since last commit, nil for the freed logical ids.
+	freeIDs []uint64         // The free logical ids, the smallest is at the end.
+	scratch []byte
+
+	err    error
+	closed bool
+}
+
+// Open opens or creates the file of a Tree, the default options is used if opts is nil.
+func Open(path string, opts *Options) (*Tree, error) {
+	opts = copyOptions(opts)
+	p, err := openPager(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tree{
+		p:     p,
+		opts:  opts,
+		cache: cache.NewLRU(opts.CacheSize, nil),
+	}
+	t.reset()
+	return t, nil
+}
+
+// Resets to the committed state.
+func (t *Tree) reset() {
+	t.meta = t.p.meta
+	t.dirty = make(map[uint64]*node)
+	t.freeIDs = t.freeIDs[:0]
+	for id := len(t.p.table) - 1; id >= 1; id-- {
+		if t.p.table[id] == 0 {
+			t.freeIDs = append(t.freeIDs, uint64(id))
+		}
+	}
+}
+
+// Len returns the number of elements.
+func (t *Tree) Len() int {
+	return int(t.meta.count)
+}
+
+// Err returns the first error encountered when reading pages.
+func (t *Tree) Err() error {
+	return t.err
+}
+
+// Get returns the value of key.
+// The bool result is false if the key not found.
+func (t *Tree) Get(k container.Key) (container.Value, bool) {
+	n := t.leafOf(k)
+	if n == nil {
+		return nil, false
+	}
+	i := searchGE(n, k)
+	if i == len(n.keys) || n.keys[i].Compare(k) != 0 {
+		return nil, false
+	}
+	return n.values[i], true
+}
+
+// Put inserts or updates the value of key.
+// Returns ErrTooLarge if the encoded size of the element exceeds a quarter of page.
+func (t *Tree) Put(k container.Key, v container.Value) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	ks, err := t.encodedSize(k)
+	if err != nil {
+		return err
+	}
+	vs, err := t.encodedSize(v)
+	if err != nil {
+		return err
+	}
+	if max := (t.p.pageSize - headerSize - 8) / 4; ks+vs > max || ks+8 > max {
+		return ErrTooLarge
+	}
+
+	if t.meta.root == 0 {
+		id := t.allocID()
+		t.dirty[id] = &node{
+			leaf:   true,
+			keys:   []container.Key{k},
+			values: []container.Value{v},
+			size:   headerSize + ks + vs,
+		}
+		t.meta.root, t.meta.first, t.meta.last = id, id, id
+		t.meta.height = 1
+		t.meta.count = 1
+		return nil
+	}
+
+	path, id := t.descend(k)
+	if id == 0 {
+		return t.err
+	}
+	n := t.mutable(id)
+	if n == nil {
+		return t.err
+	}
+	i := searchGE(n, k)
+	if i < len(n.keys) && n.keys[i].Compare(k) == 0 {
+		old, err := t.encodedSize(n.values[i])
+		if err != nil {
+			return err
+		}
+		n.values[i] = v
+		n.size += vs - old
+	} else {
+		n.keys = append(n.keys, nil)
+		copy(n.keys[i+1:], n.keys[i:])
+		n.keys[i] = k
+		n.values = append(n.values, nil)
+		copy(n.values[i+1:], n.values[i:])
+		n.values[i] = v
+		n.size += ks + vs
+		t.meta.count++
+	}
+	return t.split(path, id, n)
+}
+
+// Delete removes the key, the bool result is false if the key not found.
+func (t *Tree) Delete(k container.Key) (bool, error) {
+	if err := t.check(); err != nil {
+		return false, err
+	}
+	if t.meta.root == 0 {
+		return false, nil
+	}
+
+	path, id := t.descend(k)
+	if id == 0 {
+		return false, t.err
+	}
+	n := t.node(id)
+	if n == nil {
+		return false, t.err
+	}
+	i := searchGE(n, k)
+	if i == len(n.keys) || n.keys[i].Compare(k) != 0 {
+		return false, nil
+	}
+
+	n = t.mutable(id)
+	ks, err := t.encodedSize(n.keys[i])
+	if err != nil {
+		return false, err
+	}
+	vs, err := t.encodedSize(n.values[i])
+	if err != nil {
+		return false, err
+	}
+	n.keys = append(n.keys[:i], n.keys[i+1:]...)
+	n.values = append(n.values[:i], n.values[i+1:]...)
+	n.size -= ks + vs
+	t.meta.count--
+	return true, t.rebalance(path, id, n)
+}
+
+// Commit writes the changes to the file. The changes are discarded if failed.
+func (t *Tree) Commit() error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	if len(t.dirty) == 0 {
+		return nil
+	}
+
+	pages := make(map[uint64][]byte, len(t.dirty))
+	for id, n := range t.dirty {
+		if n == nil {
+			pages[id] = nil
+			continue
+		}
+		page := make([]byte, t.p.pageSize)
+		if err := n.encode(page, t.opts.Registry); err != nil {
+			t.reset()
+			return err
+		}
+		pages[id] = page
+	}
+	if err := t.p.commit(pages, t.meta); err != nil {
+		t.reset()
+		return err
+	}
+
+	// The committed nodes are immutable, they are copied on next change.
+	for id, n := range t.dirty {
+		if n == nil {
+			t.cache.Remove(container.Uint64(id))
+		} else {
+			t.cache.Put(container.Uint64(id), n)
+		}
+	}
+	t.reset()
+	return nil
+}
+
+// Rollback discards the uncommitted changes and the recorded error.
+func (t *Tree) Rollback() {
+	if t.closed {
+		return
+	}
+	t.reset()
+	t.err = nil
+}
+
+// Close discards the uncommitted changes and closes the file.
+func (t *Tree) Close() error {
+	if t.closed {
+		return ErrClosed
+	}
+	t.closed = true
+	return t.p.close()
+}
+
+// Returns the error that prevents changes.
+func (t *Tree) check() error {
+	if t.closed {
+		return ErrClosed
+	}
+	return t.err
+}
+
+// Records the first error.
+func (t *Tree) setErr(err error) {
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// Returns the size of encoded value.
+func (t *Tree) encodedSize(v interface{}) (int, error) {
+	var err error
+	t.scratch, err = t.opts.Registry.AppendValue(t.scratch[:0], v)
+	return len(t.scratch), err
+}
+
+// Returns the node of logical id, returns nil and records the error if failed.
+func (t *Tree) node(id uint64) *node {
+	if n, ok := t.dirty[id]; ok {
+		return n
+	}
+	if v, ok := t.cache.Get(container.Uint64(id)); ok {
+		return v.(*node)
+	}
+	if id >= uint64(len(t.p.table)) || t.p.table[id] == 0 {
+		t.setErr(ErrCorrupted)
+		return nil
+	}
+	page, err := t.p.read(t.p.table[id])
+	if err != nil {
+		t.setErr(err)
+		return nil
+	}
+	n, err := decodeNode(page, t.opts.Registry)
+	if err != nil {
+		t.setErr(err)
+		return nil
+	}
+	t.cache.Put(container.Uint64(id), n)
+	return n
+}
+
+// Returns the node of logical id that can be changed.
+func (t *Tree) mutable(id uint64) *node {
+	if n, ok := t.dirty[id]; ok {
+		return n
+	}
+	n := t.node(id)
+	if n == nil {
+		return nil
+	}
+	n = n.clone()
+	t.dirty[id] = n
+	return n
+}
+
+func (t *Tree) allocID() uint64 {
+	if n := len(t.freeIDs); n != 0 {
+		id := t.freeIDs[n-1]
+		t.freeIDs = t.freeIDs[:n-1]
+		return id
+	}
+	id := t.meta.logical
+	t.meta.logical++
+	return id
+}
+
+func (t *Tree) freeID(id uint64) {
+	t.dirty[id] = nil
+	t.freeIDs = append(t.freeIDs, id)
+}
+
+// Returns the path of internal nodes and the leaf that may contain the key.
+// The leaf is 0 if failed.
+func (t *Tree) descend(k container.Key) ([]pathEntry, uint64) {
+	var path []pathEntry
+	id := t.meta.root
+	for level := t.meta.height; level > 1; level-- {
+		n := t.node(id)
+		if n == nil {
+			return nil, 0
+		}
+		if n.leaf {
+			t.setErr(ErrCorrupted)
+			return nil, 0
+		}
+		i := sort.Search(len(n.keys), func(i int) bool {
+			return n.keys[i].Compare(k) == 1
+		})
+		path = append(path, pathEntry{id: id, index: i})
+		id = n.children[i]
+	}
+	return path, id
+}
+
+// Returns the leaf that may contain the key, nil if the tree is empty or failed.
+func (t *Tree) leafOf(k container.Key) *node {
+	if t.meta.root == 0 {
+		return nil
+	}
+	_, id := t.descend(k)
+	if id == 0 {
+		return nil
+	}
+	n := t.node(id)
+	if n != nil && !n.leaf {
+		t.setErr(ErrCorrupted)
+		return nil
+	}
+	return n
+}
+
+// Splits the node n of logical id until it fits in a page, the path is the internal nodes above n.
+func (t *Tree) split(path []pathEntry, id uint64, n *node) error {
+	for n.size > t.p.pageSize {
+		var sep container.Key
+		var right *node
+		var err error
+		if n.leaf {
+			sep, right, err = t.splitLeaf(n)
+		} else {
+			sep, right, err = t.splitInternal(n)
+		}
+		if err != nil {
+			return err
+		}
+		sepSize, err := t.encodedSize(sep)
+		if err != nil {
+			return err
+		}
+
+		rid := t.allocID()
+		t.dirty[rid] = right
+		if n.leaf {
+			if n.next != 0 {
+				next := t.mutable(n.next)
+				if next == nil {
+					return t.err
+				}
+				next.prev = rid
+			} else {
+				t.meta.last = rid
+			}
+			right.prev, right.next = id, n.next
+			n.next = rid
+		}
+
+		if len(path) == 0 {
+			// Grows a new root.
+			root := &node{
+				keys:     []container.Key{sep},
+				children: []uint64{id, rid},
+				size:     headerSize + 8 + sepSize + 8,
+			}
+			t.meta.root = t.allocID()
+			t.meta.height++
+			t.dirty[t.meta.root] = root
+			return nil
+		}
+
+		pe := path[len(path)-1]
+		path = path[:len(path)-1]
+		parent := t.mutable(pe.id)
+		if parent == nil {
+			return t.err
+		}
+		parent.keys = append(parent.keys, nil)
+		copy(parent.keys[pe.index+1:], parent.keys[pe.index:])
+		parent.keys[pe.index] = sep
+		parent.children = append(parent.children, 0)
+		copy(parent.children[pe.index+2:], parent.children[pe.index+1:])
+		parent.children[pe.index+1] = rid
+		parent.size += sepSize + 8
+		id, n = pe.id, parent
+	}
+	return nil
+}
+
+// Moves the second half of leaf n by size to a new node, returns the first key of new node.
+func (t *Tree) splitLeaf(n *node) (container.Key, *node, error) {
+	sizes := make([]int, len(n.keys))
+	for i := range n.keys {
+		ks, err := t.encodedSize(n.keys[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		vs, err := t.encodedSize(n.values[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		sizes[i] = ks + vs
+	}
+	total := n.size - headerSize
+	mid, acc := 1, sizes[0]
+	for mid < len(sizes)-1 && acc+sizes[mid] <= total/2 {
+		acc += sizes[mid]
+		mid++
+	}
+
+	right := &node{
+		leaf:   true,
+		keys:   append([]container.Key(nil), n.keys[mid:]...),
+		values: append([]container.Value(nil), n.values[mid:]...),
+		size:   headerSize + total - acc,
+	}
+	n.keys = n.keys[:mid:mid]
+	n.values = n.values[:mid:mid]
+	n.size = headerSize + acc
+	return right.keys[0], right, nil
+}
+
+// Moves the second half of internal node n by size to a new node, returns the middle key that
+// separates them.
+func (t *Tree) splitInternal(n *node) (container.Key, *node, error) {
+	sizes := make([]int, len(n.keys))
+	for i := range n.keys {
+		ks, err := t.encodedSize(n.keys[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		sizes[i] = ks + 8
+	}
+	total := n.size - headerSize - 8
+	mid, acc := 1, sizes[0]
+	for mid < len(sizes)-2 && acc+sizes[mid] <= total/2 {
+		acc += sizes[mid]
+		mid++
+	}
+
+	sep := n.keys[mid]
+	right := &node{
+		keys:     append([]container.Key(nil), n.keys[mid+1:]...),
+		children: append([]uint64(nil), n.children[mid+1:]...),
+		size:     headerSize + 8 + total - acc - sizes[mid],
+	}
+	n.keys = n.keys[:mid:mid]
+	n.children = n.children[: mid+1 : mid+1]
+	n.size = headerSize + 8 + acc
+	return sep, right, nil
+}
+
+// Merges the node n of logical id with a sibling if it's less than a quarter of page,
+// and repeats on the parent. The path is the internal nodes above n.
+func (t *Tree) rebalance(path []pathEntry, id uint64, n *node) error {
+	for len(path) != 0 {
+		if n.size-headerSize >= (t.p.pageSize-headerSize)/4 {
+			return nil
+		}
+		pe := path[len(path)-1]
+		path = path[:len(path)-1]
+		parent := t.mutable(pe.id)
+		if parent == nil {
+			return t.err
+		}
+		if len(parent.children) < 2 {
+			return nil
+		}
+
+		li := pe.index - 1
+		if li < 0 {
+			li = 0
+		}
+		leftID, rightID := parent.children[li], parent.children[li+1]
+		left := t.mutable(leftID)
+		right := t.node(rightID)
+		if left == nil || right == nil {
+			return t.err
+		}
+		sepSize, err := t.encodedSize(parent.keys[li])
+		if err != nil {
+			return err
+		}
+
+		if n.leaf {
+			size := left.size + right.size - headerSize
+			if size > t.p.pageSize {
+				return nil
+			}
+			if right.next != 0 {
+				next := t.mutable(right.next)
+				if next == nil {
+					return t.err
+				}
+				next.prev = leftID
+			} else {
+				t.meta.last = leftID
+			}
+			left.keys = append(left.keys, right.keys...)
+			left.values = append(left.values, right.values...)
+			left.next = right.next
+			left.size = size
+		} else {
+			// The separator is moved down to join the children.
+			size := left.size + sepSize + 8 + right.size - headerSize - 8
+			if size > t.p.pageSize {
+				return nil
+			}
+			left.keys = append(append(left.keys, parent.keys[li]), right.keys...)
+			left.children = append(left.children, right.children...)
+			left.size = size
+		}
+		t.freeID(rightID)
+
+		parent.keys = append(parent.keys[:li], parent.keys[li+1:]...)
+		parent.children = append(parent.children[:li+1], parent.children[li+2:]...)
+		parent.size -= sepSize + 8
+		id, n = pe.id, parent
+	}
+
+	// Shrinks the root.
+	for len(n.keys) == 0 {
+		t.freeID(id)
+		if n.leaf {
+			t.meta.root, t.meta.first, t.meta.last = 0, 0, 0
+			t.meta.height = 0
+			return nil
+		}
+		id = n.children[0]
+		t.meta.root = id
+		t.meta.height--
+		if n = t.node(id); n == nil {
+			return t.err
+		}
+	}
+	return nil
+}
+
+// Returns the index of first key >= k in leaf n.
+func searchGE(n *node, k container.Key) int {
+	return sort.Search(len(n.keys), func(i int) bool {
+		return n.keys[i].Compare(k) != -1
+	})
+}
+
+// Returns the index of first key > k in leaf n.
+func searchGT(n *node, k container.Key) int {
+	return sort.Search(len(n.keys), func(i int) bool {
+		return n.keys[i].Compare(k) == 1
+	})
+}
+
+// Iter return an Iterator include element of range start <= x < boundary.
+// The elements will return from the beginning if start is nil,
+// And return until the end if the boundary is nil.
+func (t *Tree) Iter(start container.Key, boundary container.Key) container.Iterator {
+	return newIterator(t, start, boundary)
+}
+
+// IterReverse return a reversed Iterator include element of range start <= x < boundary.
+// The elements will return from the end if boundary is nil,
+// And return until the beginning if start is nil.
+func (t *Tree) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	return newReverseIterator(t, start, boundary)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (t *Tree) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(t)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (t *Tree) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(t)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (t *Tree) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(t, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (t *Tree) Keys() iter.Seq[container.Key] {
+	return container.Keys(t)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (t *Tree) Values() iter.Seq[container.Value] {
+	return container.Values(t)
+}
+
+// Range calls f sequentially each element present in the Tree.
+// If f returns false, range stops the iteration.
+func (t *Tree) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	it := newIterator(t, start, boundary)
+	for it.Valid() {
+		if !f(it.Next()) {
+			return
+		}
+	}
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+func (t *Tree) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	it := newReverseIterator(t, start, boundary)
+	for it.Valid() {
+		if !f(it.Next()) {
+			return
+		}
+	}
+}
+
+// LastLT searches for the last element that less than the key.
+func (t *Tree) LastLT(k container.Key) container.Element {
+	c := t.seekLT(k)
+	return c.element()
+}
+
+// LastLE search for the last element that less than or equal to the key.
+func (t *Tree) LastLE(k container.Key) container.Element {
+	c := t.seekLE(k)
+	return c.element()
+}
+
+// FirstGT search for the first element that greater than to the key.
+func (t *Tree) FirstGT(k container.Key) container.Element {
+	c := t.seekGT(k)
+	return c.element()
+}
+
+// FirstGE search for the first element that greater than or equal to the key.
+func (t *Tree) FirstGE(k container.Key) container.Element {
+	c := t.seekGE(k)
+	return c.element()
+}
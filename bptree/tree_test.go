@@ -0,0 +1,301 @@
+package bptree
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/tests"
+)
+
+// The small pages to build a deep tree.
+var testOptions = &Options{PageSize: 512, CacheSize: 16, NoSync: true}
+
+// Checks the structure of tree and returns the leaves in order.
+func checkNode(t *testing.T, tr *Tree, id uint64, level int, lower container.Key, upper container.Key) []uint64 {
+	n := tr.node(id)
+	require.NotNil(t, n)
+	require.Equal(t, n.leaf, level == 1)
+	require.LessOrEqual(t, n.size, tr.p.pageSize)
+
+	page := make([]byte, tr.p.pageSize)
+	require.Nil(t, n.encode(page, tr.opts.Registry))
+	decoded, err := decodeNode(page, tr.opts.Registry)
+	require.Nil(t, err)
+	require.Equal(t, decoded.size, n.size)
+
+	for i, k := range n.keys {
+		if i > 0 {
+			require.Equal(t, n.keys[i-1].Compare(k), -1)
+		}
+		if lower != nil {
+			require.NotEqual(t, k.Compare(lower), -1)
+		}
+		if upper != nil {
+			require.Equal(t, k.Compare(upper), -1)
+		}
+	}
+	if n.leaf {
+		return []uint64{id}
+	}
+	require.Equal(t, len(n.children), len(n.keys)+1)
+	var leaves []uint64
+	for i, child := range n.children {
+		lo, hi := lower, upper
+		if i > 0 {
+			lo = n.keys[i-1]
+		}
+		if i < len(n.keys) {
+			hi = n.keys[i]
+		}
+		leaves = append(leaves, checkNode(t, tr, child, level-1, lo, hi)...)
+	}
+	return leaves
+}
+
+// Checks the tree has the same content as the model.
+func checkTree(t *testing.T, r *rand.Rand, tr *Tree, model *rb.Tree) {
+	require.Equal(t, tr.Len(), model.Len())
+	if tr.meta.root != 0 {
+		leaves := checkNode(t, tr, tr.meta.root, tr.meta.height, nil, nil)
+		require.Equal(t, tr.meta.first, leaves[0])
+		require.Equal(t, tr.meta.last, leaves[len(leaves)-1])
+		for i, id := range leaves {
+			n := tr.node(id)
+			if i > 0 {
+				require.Equal(t, n.prev, leaves[i-1])
+			} else {
+				require.Equal(t, n.prev, uint64(0))
+			}
+			if i < len(leaves)-1 {
+				require.Equal(t, n.next, leaves[i+1])
+			} else {
+				require.Equal(t, n.next, uint64(0))
+			}
+		}
+	}
+
+	require.Equal(t, tests.Pairs(tr, nil, nil, false), tests.Pairs(model, nil, nil, false))
+	require.Equal(t, tests.Pairs(tr, nil, nil, true), tests.Pairs(model, nil, nil, true))
+	for i := 0; i < 50; i++ {
+		k := container.Int(r.Intn(1200) - 100)
+		v, ok := tr.Get(k)
+		ele := model.Search(k)
+		require.Equal(t, ok, ele != nil)
+		if ok {
+			require.Equal(t, v, ele.Value())
+		}
+
+		require.Equal(t, tests.ElementPair(tr.LastLT(k)), tests.ElementPair(model.LastLT(k)))
+		require.Equal(t, tests.ElementPair(tr.LastLE(k)), tests.ElementPair(model.LastLE(k)))
+		require.Equal(t, tests.ElementPair(tr.FirstGT(k)), tests.ElementPair(model.FirstGT(k)))
+		require.Equal(t, tests.ElementPair(tr.FirstGE(k)), tests.ElementPair(model.FirstGE(k)))
+
+		boundary := k + container.Int(r.Intn(200))
+		require.Equal(t, tests.Pairs(tr, k, boundary, false), tests.Pairs(model, k, boundary, false))
+		require.Equal(t, tests.Pairs(tr, k, boundary, true), tests.Pairs(model, k, boundary, true))
+		require.Equal(t, tests.IterPairs(tr.Iter(k, boundary)), tests.IterPairs(model.Iter(k, boundary)))
+		require.Equal(t, tests.IterPairs(tr.IterReverse(k, nil)), tests.IterPairs(model.IterReverse(k, nil)))
+		require.Equal(t, tests.IterPairs(tr.IterReverse(nil, k)), tests.IterPairs(model.IterReverse(nil, k)))
+	}
+	require.Nil(t, tr.Err())
+}
+
+// Returns a copy of model.
+func cloneModel(model *rb.Tree) *rb.Tree {
+	c := rb.New()
+	model.Range(nil, nil, func(ele container.Element) bool {
+		c.Insert(ele.Key(), ele.Value())
+		return true
+	})
+	return c
+}
+
+func TestTree(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	path := filepath.Join(t.TempDir(), "tree")
+
+	tr, err := Open(path, testOptions)
+	require.Nil(t, err)
+	model := rb.New()
+	committed := rb.New()
+
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 500; i++ {
+			k := container.Int(r.Intn(1000))
+			if r.Intn(3) == 0 {
+				ok, err := tr.Delete(k)
+				require.Nil(t, err)
+				require.Equal(t, ok, model.Delete(k) != nil)
+			} else {
+				v := container.String(make([]byte, r.Intn(40)))
+				require.Nil(t, tr.Put(k, v))
+				model.Upsert(k, v)
+			}
+		}
+		checkTree(t, r, tr, model)
+
+		switch r.Intn(3) {
+		case 0:
+			tr.Rollback()
+			model = cloneModel(committed)
+		case 1:
+			require.Nil(t, tr.Commit())
+			committed = cloneModel(model)
+		default:
+			// Reopens and loses the uncommitted changes.
+			require.Nil(t, tr.Commit())
+			committed = cloneModel(model)
+			if r.Intn(2) == 0 {
+				require.Nil(t, tr.Put(container.Int(-1), nil))
+			}
+			require.Nil(t, tr.Close())
+			require.Equal(t, tr.Close(), ErrClosed)
+			require.Equal(t, tr.Put(container.Int(0), nil), ErrClosed)
+			tr, err = Open(path, &Options{CacheSize: 16, NoSync: true})
+			require.Nil(t, err)
+		}
+		checkTree(t, r, tr, model)
+	}
+
+	// Deletes all.
+	model.Range(nil, nil, func(ele container.Element) bool {
+		ok, err := tr.Delete(ele.Key())
+		require.Nil(t, err)
+		require.True(t, ok)
+		return true
+	})
+	require.Nil(t, tr.Commit())
+	checkTree(t, r, tr, rb.New())
+	require.Equal(t, tr.meta.height, 0)
+	require.Nil(t, tr.Close())
+}
+
+func TestTree_Reuse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree")
+	tr, err := Open(path, testOptions)
+	require.Nil(t, err)
+	defer tr.Close()
+
+	// The replaced pages are reused by the later commits.
+	var size int64
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 300; i++ {
+			require.Nil(t, tr.Put(container.Int(i), round))
+		}
+		require.Nil(t, tr.Commit())
+		fi, err := os.Stat(path)
+		require.Nil(t, err)
+		if round == 2 {
+			size = fi.Size()
+		}
+		if round > 2 {
+			require.Equal(t, fi.Size(), size)
+		}
+	}
+	for i := 0; i < 300; i++ {
+		v, ok := tr.Get(container.Int(i))
+		require.True(t, ok)
+		require.Equal(t, v, 19)
+	}
+}
+
+func TestTree_Crash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree")
+	tr, err := Open(path, testOptions)
+	require.Nil(t, err)
+	for i := 0; i < 200; i++ {
+		require.Nil(t, tr.Put(container.Int(i), i))
+	}
+	require.Nil(t, tr.Commit())
+	for i := 0; i < 200; i++ {
+		require.Nil(t, tr.Put(container.Int(i), -i))
+	}
+	for i := 200; i < 400; i++ {
+		require.Nil(t, tr.Put(container.Int(i), -i))
+	}
+	require.Nil(t, tr.Commit())
+	txid := tr.p.meta.txid
+	require.Nil(t, tr.Close())
+
+	// A torn meta page falls back to the previous commit.
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xff}, int64(txid%2)*int64(testOptions.PageSize)+20)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	tr, err = Open(path, testOptions)
+	require.Nil(t, err)
+	require.Equal(t, tr.Len(), 200)
+	for i := 0; i < 200; i++ {
+		v, ok := tr.Get(container.Int(i))
+		require.True(t, ok)
+		require.Equal(t, v, i)
+	}
+	require.Nil(t, tr.Err())
+
+	// The next commit overwrites the torn meta.
+	require.Nil(t, tr.Put(container.Int(1000), 1000))
+	require.Nil(t, tr.Commit())
+	require.Nil(t, tr.Close())
+	tr, err = Open(path, testOptions)
+	require.Nil(t, err)
+	require.Equal(t, tr.Len(), 201)
+	require.Nil(t, tr.Close())
+
+	// Both meta pages are corrupted.
+	f, err = os.OpenFile(path, os.O_RDWR, 0644)
+	require.Nil(t, err)
+	for i := int64(0); i < 2; i++ {
+		_, err = f.WriteAt([]byte{0xff}, i*int64(testOptions.PageSize)+20)
+		require.Nil(t, err)
+	}
+	require.Nil(t, f.Close())
+	_, err = Open(path, testOptions)
+	require.Equal(t, err, ErrCorrupted)
+}
+
+func TestTree_Errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree")
+	tr, err := Open(path, testOptions)
+	require.Nil(t, err)
+
+	require.Equal(t, tr.Put(container.Int(1), container.String(make([]byte, 200))), ErrTooLarge)
+	require.Equal(t, tr.Put(container.String(make([]byte, 200)), nil), ErrTooLarge)
+	require.NotNil(t, tr.Put(container.Int(1), struct{}{}))
+	require.Nil(t, tr.Err())
+	require.Nil(t, tr.LastLT(container.Int(1)))
+	require.Nil(t, tr.FirstGE(container.Int(1)))
+	require.False(t, tr.Iter(nil, nil).Valid())
+	require.False(t, tr.IterReverse(nil, nil).Valid())
+
+	for i := 0; i < 100; i++ {
+		require.Nil(t, tr.Put(container.Int(i), i))
+	}
+	require.Nil(t, tr.Commit())
+	phys := tr.p.table[tr.meta.first]
+	require.Nil(t, tr.Close())
+
+	// A corrupted page is recorded.
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xff}, int64(phys)*int64(testOptions.PageSize)+100)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+	tr, err = Open(path, testOptions)
+	require.Nil(t, err)
+	_, ok := tr.Get(container.Int(1))
+	require.False(t, ok)
+	require.Equal(t, tr.Err(), ErrCorrupted)
+	require.Equal(t, tr.Put(container.Int(1), 1), ErrCorrupted)
+	tr.Rollback()
+	require.Nil(t, tr.Err())
+	require.Nil(t, tr.Close())
+}
@@ -6,15 +6,18 @@
 package bs
 
 import (
+	"iter"
+
 	"github.com/yu31/structs-go/container"
 	"github.com/yu31/structs-go/internal/tree"
 )
 
 var (
-	_ container.Container = (*Tree)(nil)
-	_ container.Element   = (*treeNode)(nil)
-	_ container.Tree      = (*Tree)(nil)
-	_ container.TreeNode  = (*treeNode)(nil)
+	_ container.Container      = (*Tree)(nil)
+	_ container.Element        = (*treeNode)(nil)
+	_ container.Tree           = (*Tree)(nil)
+	_ container.TreeNode       = (*treeNode)(nil)
+	_ container.MutableElement = (*treeNode)(nil)
 )
 
 // treeNode is used for Binary Search Tree.
@@ -35,6 +38,11 @@ func (n *treeNode) Value() container.Value {
 	return n.value
 }
 
+// SetValue sets the value in place.
+func (n *treeNode) SetValue(v container.Value) {
+	n.value = v
+}
+
 // Left returns the left child of the TreeNode.
 func (n *treeNode) Left() container.TreeNode {
 	if n.left == nil {
@@ -65,6 +73,15 @@ func New() *Tree {
 	}
 }
 
+// NewFromSorted creates a Tree with n elements returned by next in ascending order by key.
+// It builds a balanced tree in O(n) without comparison, so the keys must be strictly ascending.
+func NewFromSorted(n int, next func() (container.Key, container.Value)) *Tree {
+	tr := New()
+	tr.root = tr.buildSorted(n, next)
+	tr.len = n
+	return tr
+}
+
 // Root returns the root node of the tree.
 func (tr *Tree) Root() container.TreeNode {
 	if tr.root == nil {
@@ -116,6 +133,29 @@ func (tr *Tree) Upsert(k container.Key, v container.Value) (container.Element, b
 	return node, ok
 }
 
+// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place.
+// Returns the element of key and its old value, returns nil if the key not be found.
+func (tr *Tree) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	node, _ := tr.searchNode(k)
+	if node == nil {
+		return nil, nil
+	}
+	old := node.value
+	node.value = v
+	return node, old
+}
+
+// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (tr *Tree) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	var ok bool
+	ele := tr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		ok = !exists
+		return v, true
+	})
+	return ele, ok
+}
+
 // Search searches the element of a given key.
 // Returns nil if key not found.
 func (tr *Tree) Search(k container.Key) container.Element {
@@ -126,6 +166,102 @@ func (tr *Tree) Search(k container.Key) container.Element {
 	return node
 }
 
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f. All of these are done with a single search.
+//
+// Returns the element of key after computed, nil if no element for the key.
+func (tr *Tree) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) container.Element {
+	var parent *treeNode
+	var cmp int
+
+	node := tr.root
+	for node != nil {
+		cmp = k.Compare(node.key)
+		if cmp == 0 {
+			break
+		}
+		parent = node
+		if cmp == -1 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	if node != nil {
+		v, keep := f(node, true)
+		if !keep {
+			tr.deleteNode(node, parent)
+			return nil
+		}
+		node.value = v
+		return node
+	}
+
+	v, keep := f(nil, false)
+	if !keep {
+		return nil
+	}
+
+	node = tr.createNode(k, v)
+	if parent == nil {
+		tr.root = node
+	} else if cmp == -1 {
+		parent.left = node
+	} else {
+		parent.right = node
+	}
+	tr.len++
+	return node
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+func (tr *Tree) GetOrInsert(k container.Key, f func() container.Value) (container.Element, bool) {
+	var ok bool
+	ele := tr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		if exists {
+			return ele.Value(), true
+		}
+		ok = true
+		return f(), true
+	})
+	return ele, ok
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+//
+// The tree is split at start and boundary, and the two remaining parts are joined back together,
+// Thus the cost is O(h) plus the number of removed elements, h is the height of tree.
+func (tr *Tree) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return 0
+	}
+
+	var left, middle, right *treeNode
+
+	middle = tr.root
+	if start != nil {
+		left, middle = tr.split(middle, start)
+	}
+	if boundary != nil {
+		middle, right = tr.split(middle, boundary)
+	}
+	tr.root = tr.join(left, right)
+
+	n := tr.releaseNodes(middle, f)
+	tr.len -= n
+	return n
+}
+
+// Clear removes all elements in the tree.
+func (tr *Tree) Clear() {
+	tr.root = nil
+	tr.len = 0
+}
+
 // Iter return an Iterator, it's a wrap for tree.Iterator.
 func (tr *Tree) Iter(start container.Key, boundary container.Key) container.Iterator {
 	return tree.NewIterator(tr.root, start, boundary)
@@ -136,6 +272,31 @@ func (tr *Tree) IterReverse(start container.Key, boundary container.Key) contain
 	return tree.NewIteratorReverse(tr.root, start, boundary)
 }
 
+// All returns an iterator over all elements in ascending order.
+func (tr *Tree) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(tr)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (tr *Tree) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(tr)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (tr *Tree) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(tr, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (tr *Tree) Keys() iter.Seq[container.Key] {
+	return container.Keys(tr)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (tr *Tree) Values() iter.Seq[container.Value] {
+	return container.Values(tr)
+}
+
 // Range calls f sequentially each TreeNode present in the Tree.
 // If f returns false, range stops the iteration.
 func (tr *Tree) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
@@ -223,6 +384,18 @@ func (tr *Tree) deleteAndSearch(k container.Key) *treeNode {
 	return node
 }
 
+// Builds a balanced subtree with n elements returned by next.
+func (tr *Tree) buildSorted(n int, next func() (container.Key, container.Value)) *treeNode {
+	if n <= 0 {
+		return nil
+	}
+	left := tr.buildSorted(n/2, next)
+	node := tr.createNode(next())
+	node.left = left
+	node.right = tr.buildSorted(n-n/2-1, next)
+	return node
+}
+
 // Creates a new node with the giving key and value.
 func (tr *Tree) createNode(k container.Key, v container.Value) *treeNode {
 	return &treeNode{
@@ -287,6 +460,70 @@ func (tr *Tree) replaceNode(old, parent, n0 *treeNode) {
 	old.right = nil
 }
 
+// Splits the tree r0 into two trees, one with the keys that less than k,
+// and another with the keys that greater than or equal to k.
+func (tr *Tree) split(r0 *treeNode, k container.Key) (left *treeNode, right *treeNode) {
+	if r0 == nil {
+		return
+	}
+	if r0.key.Compare(k) == -1 {
+		left, right = tr.split(r0.right, k)
+		r0.right = left
+		left = r0
+	} else {
+		left, right = tr.split(r0.left, k)
+		r0.left = right
+		right = r0
+	}
+	return
+}
+
+// Joins the tree left and the tree right, returns the root node.
+// All keys in left must less than the keys in right.
+func (tr *Tree) join(left *treeNode, right *treeNode) *treeNode {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	// Uses the minimum node of right as the new root.
+	var parent *treeNode
+	x := right
+	for x.left != nil {
+		parent = x
+		x = x.left
+	}
+	if parent != nil {
+		parent.left = x.right
+		x.right = right
+	}
+	x.left = left
+	return x
+}
+
+// Resets all nodes of the detached tree r0 by in-order traversal, returns the number of nodes.
+// f will be called with each node if it is not nil.
+func (tr *Tree) releaseNodes(r0 *treeNode, f func(ele container.Element)) int {
+	if r0 == nil {
+		return 0
+	}
+	n := 0
+	tree.LDR(r0, func(node container.TreeNode) bool {
+		d := node.(*treeNode)
+		// reset the unused field. LDR has already read both children before calls f.
+		d.left = nil
+		d.right = nil
+		if f != nil {
+			f(d)
+		}
+		n++
+		return true
+	})
+	return n
+}
+
 // Searches the node and its parent node of a given key.
 func (tr *Tree) searchNode(k container.Key) (node *treeNode, parent *treeNode) {
 	node = tr.root
@@ -116,3 +116,29 @@ func TestTree(t *testing.T) {
 		require.Equal(t, tr.Len(), 0)
 	}
 }
+
+func TestNewFromSorted(t *testing.T) {
+	for n := 0; n < 300; n++ {
+		i := 0
+		tr := NewFromSorted(n, func() (container.Key, container.Value) {
+			i++
+			return container.Int(i), i
+		})
+		require.Equal(t, tr.Len(), n)
+		checkCorrect(t, tr.root)
+
+		j := 0
+		tr.Range(nil, nil, func(ele container.Element) bool {
+			j++
+			require.Equal(t, ele.Key(), container.Int(j))
+			return true
+		})
+		require.Equal(t, j, n)
+
+		// The tree is usable after built.
+		_, ok := tr.Insert(container.Int(n+1), n+1)
+		require.True(t, ok)
+		require.NotNil(t, tr.Delete(container.Int(n/2+1)))
+		checkCorrect(t, tr.root)
+	}
+}
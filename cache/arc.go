@@ -0,0 +1,182 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package cache
+
+var _ Cache = (*ARC)(nil)
+
+// ARC implements a Cache with the Adaptive Replacement Cache policy.
+//
+// The resident elements are kept in two lists, t1 for the elements accessed once recently and t2 for the
+// elements accessed at least twice. The keys evicted from t1 and t2 are remembered in the ghost lists b1 and b2.
+// A hit in b1 or b2 adapts the target size p of t1 to favor recency or frequency.
+//
+// The sizes of lists are measured by cost, so the policy works with both count and cost capacity.
+type ARC struct {
+	capacity int
+	opts     *Options
+	entries  *index
+	p        int // The target cost of t1.
+
+	t1, t2 *list
+	b1, b2 *list
+}
+
+// NewARC creates an ARC with the given capacity, the default options is used if opts is nil.
+func NewARC(capacity int, opts *Options) *ARC {
+	return &ARC{
+		capacity: capacity,
+		opts:     copyOptions(opts),
+		entries:  newIndex(),
+		p:        0,
+		t1:       new(list).init(),
+		t2:       new(list).init(),
+		b1:       new(list).init(),
+		b2:       new(list).init(),
+	}
+}
+
+// Get returns the value of key and moves it to the frequently used list.
+// The bool result is false if key not found.
+func (c *ARC) Get(k Key) (Value, bool) {
+	e := c.resident(k)
+	if e == nil {
+		return nil, false
+	}
+	e.list.remove(e)
+	c.t2.pushFront(e)
+	return e.value, true
+}
+
+// Put inserts or updates the value of key.
+// A new key is put into the recently used list, and the existing or remembered key is put into the frequently used list.
+func (c *ARC) Put(k Key, v Value) {
+	cost := c.opts.cost(k, v)
+
+	e, ok := c.entries.get(k)
+	switch {
+	case !ok:
+		e = &entry{key: k, value: v, cost: cost}
+		c.entries.set(k, e)
+		c.makeRoom(cost, false)
+		c.t1.pushFront(e)
+	case e.list == c.t1 || e.list == c.t2:
+		e.list.remove(e)
+		e.value = v
+		e.cost = cost
+		c.makeRoom(cost, false)
+		c.t2.pushFront(e)
+	case e.list == c.b1:
+		// Favors recency.
+		c.p = min(c.capacity, c.p+ratio(c.b2.cost, c.b1.cost)*e.cost)
+		c.b1.remove(e)
+		e.value = v
+		e.cost = cost
+		c.makeRoom(cost, false)
+		c.t2.pushFront(e)
+	case e.list == c.b2:
+		// Favors frequency.
+		c.p = max(0, c.p-ratio(c.b1.cost, c.b2.cost)*e.cost)
+		c.b2.remove(e)
+		e.value = v
+		e.cost = cost
+		c.makeRoom(cost, true)
+		c.t2.pushFront(e)
+	}
+
+	// The element is evicted immediately if its cost exceeds the capacity.
+	c.makeRoom(0, false)
+	c.trimGhosts()
+}
+
+// Peek returns the value of key without changing its position.
+// The bool result is false if key not found.
+func (c *ARC) Peek(k Key) (Value, bool) {
+	e := c.resident(k)
+	if e == nil {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Remove removes the key from the cache, the eviction callback is not called.
+// Returns false if key not found.
+func (c *ARC) Remove(k Key) bool {
+	e, ok := c.entries.get(k)
+	if !ok {
+		return false
+	}
+	isResident := e.list == c.t1 || e.list == c.t2
+	e.list.remove(e)
+	c.entries.remove(k)
+	return isResident
+}
+
+// Len returns the number of elements in the cache.
+func (c *ARC) Len() int {
+	return c.t1.len + c.t2.len
+}
+
+// Cost returns the total cost of elements in the cache.
+func (c *ARC) Cost() int {
+	return c.t1.cost + c.t2.cost
+}
+
+// Returns the resident entry of key, nil if not found or it's a ghost.
+func (c *ARC) resident(k Key) *entry {
+	e, ok := c.entries.get(k)
+	if !ok || (e.list != c.t1 && e.list != c.t2) {
+		return nil
+	}
+	return e
+}
+
+// Evicts elements into the ghost lists until the total cost plus reserved does not exceed the capacity.
+// The inB2 represents whether the element to put is remembered in b2.
+func (c *ARC) makeRoom(reserved int, inB2 bool) {
+	for c.Len() > 0 && c.Cost()+reserved > c.capacity {
+		c.replace(inB2)
+	}
+}
+
+// Evicts the least recently used element from t1 into b1 if t1 exceeds its target, otherwise from t2 into b2.
+func (c *ARC) replace(inB2 bool) {
+	from, to := c.t2, c.b2
+	if c.t1.len > 0 && (c.t1.cost > c.p || (inB2 && c.t1.cost == c.p) || c.t2.len == 0) {
+		from, to = c.t1, c.b1
+	}
+	e := from.back()
+	from.remove(e)
+	v := e.value
+	e.value = nil // The ghost only remembers the key.
+	to.pushFront(e)
+	c.opts.evict(e.key, v)
+}
+
+// Forgets the least recently evicted keys to keep the cost of t1+b1 within the capacity,
+// and the cost of all lists within twice the capacity.
+func (c *ARC) trimGhosts() {
+	for c.b1.len > 0 && c.t1.cost+c.b1.cost > c.capacity {
+		c.forget(c.b1)
+	}
+	for c.b2.len > 0 && c.t1.cost+c.t2.cost+c.b1.cost+c.b2.cost > 2*c.capacity {
+		c.forget(c.b2)
+	}
+}
+
+// Removes the least recently evicted key from the ghost list.
+func (c *ARC) forget(l *list) {
+	e := l.back()
+	l.remove(e)
+	c.entries.remove(e.key)
+}
+
+// Returns max(a/b, 1), the b may be zero if the elements cost nothing.
+func ratio(a, b int) int {
+	if b == 0 || a/b < 1 {
+		return 1
+	}
+	return a / b
+}
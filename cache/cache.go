@@ -0,0 +1,219 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package cache
+
+import (
+	"time"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+)
+
+// Type aliases for simplifying use in this package.
+
+type Key = container.Key
+type Value = container.Value
+
+// Cache declares a bounded cache interface.
+// The implementations are not safe for concurrent use.
+type Cache interface {
+	// Get returns the value of key and marks the key as accessed.
+	// The bool result is false if key not found.
+	Get(k Key) (Value, bool)
+
+	// Put inserts or updates the value of key and marks the key as accessed.
+	// The elements are evicted if the total cost exceeds the capacity after put.
+	Put(k Key, v Value)
+
+	// Peek returns the value of key without marking the key as accessed.
+	// The bool result is false if key not found.
+	Peek(k Key) (Value, bool)
+
+	// Remove removes the key from the cache, the eviction callback is not called.
+	// Returns false if key not found.
+	Remove(k Key) bool
+
+	// Len returns the number of elements in the cache.
+	Len() int
+
+	// Cost returns the total cost of elements in the cache.
+	Cost() int
+}
+
+// Options is the options of Cache.
+type Options struct {
+	// Cost computes the cost of an element, the capacity of Cache is measured by the total cost.
+	// Each element costs 1 if it is nil, thus the capacity is measured by count.
+	Cost func(k Key, v Value) int
+
+	// OnEvict is called with the evicted element if it is not nil.
+	OnEvict func(k Key, v Value)
+}
+
+// Returns the cost of element.
+func (opts *Options) cost(k Key, v Value) int {
+	if opts.Cost == nil {
+		return 1
+	}
+	return opts.Cost(k, v)
+}
+
+// Calls the OnEvict callback.
+func (opts *Options) evict(k Key, v Value) {
+	if opts.OnEvict != nil {
+		opts.OnEvict(k, v)
+	}
+}
+
+// Returns a copy of the options, the default options is used if opts is nil.
+func copyOptions(opts *Options) *Options {
+	o := &Options{}
+	if opts != nil {
+		*o = *opts
+	}
+	return o
+}
+
+// bytesKey is used as the map key of container.Bytes, because the slice is not comparable.
+type bytesKey string
+
+// timeKey is used as the map key of container.Time, because the time.Time with the same instant
+// may have different location or monotonic clock reading.
+type timeKey struct {
+	sec  int64
+	nsec int
+}
+
+// Returns the key used in map, the keys are same in map if and only if they are equal by Compare.
+// The bool result is false if the key is not a built-in type of container,
+// because its map equality may differ from Compare, or even panic if it is not comparable.
+func mapKey(k Key) (interface{}, bool) {
+	switch k := k.(type) {
+	case container.Bytes:
+		return bytesKey(k), true
+	case container.Time:
+		t := time.Time(k)
+		return timeKey{sec: t.Unix(), nsec: t.Nanosecond()}, true
+	case container.String, container.Byte, container.Rune, container.Duration,
+		container.Int, container.Int8, container.Int16, container.Int32, container.Int64,
+		container.Uint, container.Uint8, container.Uint16, container.Uint32, container.Uint64:
+		return k, true
+	}
+	return nil, false
+}
+
+// index maps the keys to entries, the keys are equal if Compare returns 0.
+// The built-in types of container are indexed by a map, and others are indexed by a Red-Black Tree.
+type index struct {
+	m  map[interface{}]*entry
+	tr *rb.Tree
+}
+
+// Creates an empty index.
+func newIndex() *index {
+	return &index{
+		m:  make(map[interface{}]*entry),
+		tr: rb.New(),
+	}
+}
+
+// Returns the entry of key. The bool result is false if key not found.
+func (x *index) get(k Key) (*entry, bool) {
+	if mk, ok := mapKey(k); ok {
+		e, ok := x.m[mk]
+		return e, ok
+	}
+	ele := x.tr.Search(k)
+	if ele == nil {
+		return nil, false
+	}
+	return ele.Value().(*entry), true
+}
+
+// Sets the entry of key.
+func (x *index) set(k Key, e *entry) {
+	if mk, ok := mapKey(k); ok {
+		x.m[mk] = e
+		return
+	}
+	x.tr.Upsert(k, e)
+}
+
+// Removes the entry of key.
+func (x *index) remove(k Key) {
+	if mk, ok := mapKey(k); ok {
+		delete(x.m, mk)
+		return
+	}
+	x.tr.Delete(k)
+}
+
+// entry is an element of Cache.
+type entry struct {
+	key   Key
+	value Value
+	cost  int
+
+	prev, next *entry
+	list       *list   // The list that contains the entry.
+	bucket     *bucket // The frequency bucket that contains the entry, only used for LFU.
+}
+
+// list is a doubly linked list of entries with a sentinel.
+// The front is the most recently used, and the back is the least recently used.
+type list struct {
+	root entry
+	len  int
+	cost int
+}
+
+// Initializes or clears the list.
+func (l *list) init() *list {
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	l.len = 0
+	l.cost = 0
+	return l
+}
+
+// Returns the last entry of the list, nil if the list is empty.
+func (l *list) back() *entry {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// Inserts the entry at the front of list.
+func (l *list) pushFront(e *entry) {
+	e.prev = &l.root
+	e.next = l.root.next
+	e.prev.next = e
+	e.next.prev = e
+	e.list = l
+	l.len++
+	l.cost += e.cost
+}
+
+// Removes the entry from list.
+func (l *list) remove(e *entry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev = nil // Prevent memory leaks.
+	e.next = nil // Prevent memory leaks.
+	e.list = nil
+	l.len--
+	l.cost -= e.cost
+}
+
+// Moves the entry to the front of list.
+func (l *list) moveToFront(e *entry) {
+	if l.root.next == e {
+		return
+	}
+	l.remove(e)
+	l.pushFront(e)
+}
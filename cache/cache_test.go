@@ -0,0 +1,303 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+)
+
+var caches = map[string]func(capacity int, opts *Options) Cache{
+	"lru": func(capacity int, opts *Options) Cache {
+		return NewLRU(capacity, opts)
+	},
+	"lfu": func(capacity int, opts *Options) Cache {
+		return NewLFU(capacity, opts)
+	},
+	"arc": func(capacity int, opts *Options) Cache {
+		return NewARC(capacity, opts)
+	},
+}
+
+func TestCache(t *testing.T) {
+	for name, f := range caches {
+		t.Run(name, func(t *testing.T) {
+			evicted := make(map[container.Key]Value)
+			c := f(4, &Options{
+				OnEvict: func(k Key, v Value) {
+					evicted[k] = v
+				},
+			})
+
+			for i := 0; i < 4; i++ {
+				c.Put(container.Int(i), i)
+			}
+			require.Equal(t, c.Len(), 4)
+			require.Equal(t, c.Cost(), 4)
+			require.Equal(t, len(evicted), 0)
+
+			v, ok := c.Peek(container.Int(2))
+			require.True(t, ok)
+			require.Equal(t, v, 2)
+			v, ok = c.Get(container.Int(3))
+			require.True(t, ok)
+			require.Equal(t, v, 3)
+			_, ok = c.Get(container.Int(4))
+			require.False(t, ok)
+
+			c.Put(container.Int(3), 33)
+			v, _ = c.Peek(container.Int(3))
+			require.Equal(t, v, 33)
+			require.Equal(t, c.Len(), 4)
+
+			// Put a new key evicts an element.
+			c.Put(container.Int(4), 4)
+			require.Equal(t, c.Len(), 4)
+			require.Equal(t, len(evicted), 1)
+			for k, v := range evicted {
+				_, ok := c.Peek(k)
+				require.False(t, ok)
+				require.Equal(t, v, int(k.(container.Int)))
+			}
+
+			// The callback is not called by Remove.
+			require.True(t, c.Remove(container.Int(4)))
+			require.False(t, c.Remove(container.Int(4)))
+			require.Equal(t, c.Len(), 3)
+			require.Equal(t, len(evicted), 1)
+		})
+	}
+}
+
+func TestCache_Cost(t *testing.T) {
+	for name, f := range caches {
+		t.Run(name, func(t *testing.T) {
+			var evicted []Key
+			c := f(10, &Options{
+				Cost: func(k Key, v Value) int {
+					return len(v.(string))
+				},
+				OnEvict: func(k Key, v Value) {
+					evicted = append(evicted, k)
+				},
+			})
+
+			c.Put(container.String("a"), "aaaa")
+			c.Put(container.String("b"), "bbbb")
+			require.Equal(t, c.Cost(), 8)
+			c.Put(container.String("c"), "cc")
+			require.Equal(t, c.Cost(), 10)
+			require.Equal(t, len(evicted), 0)
+
+			// Updates change the cost.
+			c.Put(container.String("c"), "ccc")
+			require.LessOrEqual(t, c.Cost(), 10)
+			require.Equal(t, len(evicted), 1)
+
+			// An element larger than capacity is evicted immediately.
+			c.Put(container.String("d"), "ddddddddddd")
+			_, ok := c.Peek(container.String("d"))
+			require.False(t, ok)
+			require.Equal(t, evicted[len(evicted)-1], container.String("d"))
+			require.LessOrEqual(t, c.Cost(), 10)
+		})
+	}
+}
+
+func TestCache_BytesKey(t *testing.T) {
+	for name, f := range caches {
+		t.Run(name, func(t *testing.T) {
+			c := f(2, nil)
+			c.Put(container.Bytes("a"), 1)
+			v, ok := c.Get(container.Bytes("a"))
+			require.True(t, ok)
+			require.Equal(t, v, 1)
+			require.True(t, c.Remove(container.Bytes("a")))
+		})
+	}
+}
+
+func TestCache_TimeKey(t *testing.T) {
+	for name, f := range caches {
+		t.Run(name, func(t *testing.T) {
+			c := f(2, nil)
+			now := time.Now()
+			c.Put(container.Time(now), 1)
+
+			// The same instant with different location and without monotonic clock reading.
+			k := container.Time(now.Round(0).In(time.FixedZone("UTC+8", 8*3600)))
+			require.Equal(t, k.Compare(container.Time(now)), 0)
+			v, ok := c.Get(k)
+			require.True(t, ok)
+			require.Equal(t, v, 1)
+			c.Put(k, 2)
+			require.Equal(t, c.Len(), 1)
+			require.True(t, c.Remove(container.Time(now)))
+		})
+	}
+}
+
+// sliceKey is a Comparator that is not comparable by map.
+type sliceKey []int
+
+func (k1 sliceKey) Compare(target container.Comparator) int {
+	k2 := target.(sliceKey)
+	for i := 0; i < len(k1) && i < len(k2); i++ {
+		if k1[i] != k2[i] {
+			if k1[i] < k2[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	if len(k1) == len(k2) {
+		return 0
+	}
+	if len(k1) < len(k2) {
+		return -1
+	}
+	return 1
+}
+
+func TestCache_CustomKey(t *testing.T) {
+	for name, f := range caches {
+		t.Run(name, func(t *testing.T) {
+			c := f(2, nil)
+			c.Put(sliceKey{1, 2}, 1)
+			c.Put(sliceKey{1, 3}, 2)
+			v, ok := c.Get(sliceKey{1, 2})
+			require.True(t, ok)
+			require.Equal(t, v, 1)
+
+			c.Put(sliceKey{1, 2}, 3)
+			require.Equal(t, c.Len(), 2)
+			v, _ = c.Peek(sliceKey{1, 2})
+			require.Equal(t, v, 3)
+
+			c.Put(sliceKey{2}, 4)
+			require.Equal(t, c.Len(), 2)
+			require.True(t, c.Remove(sliceKey{2}))
+			require.False(t, c.Remove(sliceKey{2}))
+		})
+	}
+}
+
+func TestCache_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for name, f := range caches {
+		t.Run(name, func(t *testing.T) {
+			capacity := 32
+			// The reference of resident elements, maintained by callbacks and return values.
+			resident := make(map[Key]Value)
+			c := f(capacity, &Options{
+				OnEvict: func(k Key, v Value) {
+					require.Equal(t, resident[k], v)
+					delete(resident, k)
+				},
+			})
+
+			for i := 0; i < 8192; i++ {
+				k := container.Int(r.Intn(128))
+				switch r.Intn(4) {
+				case 0, 1:
+					c.Put(k, i)
+					resident[k] = i
+				case 2:
+					v, ok := c.Get(k)
+					require.Equal(t, ok, resident[k] != nil)
+					if ok {
+						require.Equal(t, v, resident[k])
+					}
+				case 3:
+					require.Equal(t, c.Remove(k), resident[k] != nil)
+					delete(resident, k)
+				}
+				require.Equal(t, c.Len(), len(resident))
+				require.LessOrEqual(t, c.Len(), capacity)
+			}
+		})
+	}
+}
+
+func TestLRU(t *testing.T) {
+	c := NewLRU(3, nil)
+	c.Put(container.Int(1), 1)
+	c.Put(container.Int(2), 2)
+	c.Put(container.Int(3), 3)
+
+	// The key 1 is most recently used, the key 2 is evicted.
+	c.Get(container.Int(1))
+	c.Put(container.Int(4), 4)
+	_, ok := c.Peek(container.Int(2))
+	require.False(t, ok)
+
+	// Peek does not change the order, the key 3 is evicted.
+	c.Peek(container.Int(3))
+	c.Put(container.Int(5), 5)
+	_, ok = c.Peek(container.Int(3))
+	require.False(t, ok)
+}
+
+func TestLFU(t *testing.T) {
+	c := NewLFU(3, nil)
+	c.Put(container.Int(1), 1)
+	c.Put(container.Int(2), 2)
+	c.Put(container.Int(3), 3)
+
+	c.Get(container.Int(1))
+	c.Get(container.Int(1))
+	c.Get(container.Int(2))
+
+	// The key 3 is least frequently used.
+	c.Put(container.Int(4), 4)
+	_, ok := c.Peek(container.Int(3))
+	require.False(t, ok)
+
+	// The key 4 has the least frequency, the new key is not the victim.
+	c.Put(container.Int(5), 5)
+	_, ok = c.Peek(container.Int(4))
+	require.False(t, ok)
+	_, ok = c.Peek(container.Int(5))
+	require.True(t, ok)
+
+	// Check the buckets are in ascending order by frequency.
+	n := 0
+	for b := c.buckets.next; b != &c.buckets; b = b.next {
+		require.Greater(t, b.entries.len, 0)
+		if b.next != &c.buckets {
+			require.Less(t, b.freq, b.next.freq)
+		}
+		n += b.entries.len
+	}
+	require.Equal(t, n, c.Len())
+}
+
+func TestARC(t *testing.T) {
+	c := NewARC(4, nil)
+
+	// The frequently used keys survive a scan.
+	for i := 0; i < 2; i++ {
+		c.Put(container.Int(i), i)
+		c.Get(container.Int(i))
+	}
+	for i := 100; i < 200; i++ {
+		c.Put(container.Int(i), i)
+	}
+	for i := 0; i < 2; i++ {
+		_, ok := c.Peek(container.Int(i))
+		require.True(t, ok)
+	}
+
+	// The ghost hit puts the key into t2.
+	require.Greater(t, c.b1.len, 0)
+	k := c.b1.root.next.key
+	c.Put(k, 0)
+	e, _ := c.entries.get(k)
+	require.True(t, e.list == c.t2)
+	require.LessOrEqual(t, c.t1.cost+c.b1.cost, 4)
+	require.LessOrEqual(t, c.t1.cost+c.t2.cost+c.b1.cost+c.b2.cost, 8)
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package cache
+
+var _ Cache = (*LFU)(nil)
+
+// bucket holds the entries with the same access frequency in LRU order.
+type bucket struct {
+	freq       int
+	entries    *list
+	prev, next *bucket
+}
+
+// LFU implements a Cache that evicts the least frequently used element.
+// The least recently used one is evicted among the elements with the same frequency.
+//
+// The entries are grouped in frequency buckets, so all operations are O(1).
+type LFU struct {
+	capacity int
+	opts     *Options
+	entries  *index
+	buckets  bucket // The sentinel of buckets list in ascending order by frequency.
+	len      int
+	cost     int
+}
+
+// NewLFU creates an LFU with the given capacity, the default options is used if opts is nil.
+func NewLFU(capacity int, opts *Options) *LFU {
+	c := &LFU{
+		capacity: capacity,
+		opts:     copyOptions(opts),
+		entries:  newIndex(),
+	}
+	c.buckets.next = &c.buckets
+	c.buckets.prev = &c.buckets
+	return c
+}
+
+// Get returns the value of key and increases its frequency.
+// The bool result is false if key not found.
+func (c *LFU) Get(k Key) (Value, bool) {
+	e, ok := c.entries.get(k)
+	if !ok {
+		return nil, false
+	}
+	c.touch(e)
+	return e.value, true
+}
+
+// Put inserts or updates the value of key and increases its frequency.
+// The least frequently used elements are evicted if the total cost exceeds the capacity.
+func (c *LFU) Put(k Key, v Value) {
+	cost := c.opts.cost(k, v)
+
+	if e, ok := c.entries.get(k); ok {
+		c.cost += cost - e.cost
+		e.bucket.entries.remove(e)
+		e.value = v
+		e.cost = cost
+		e.bucket.entries.pushFront(e)
+		c.touch(e)
+		c.evict(0)
+		return
+	}
+
+	// Evicts before insert, so that the new element is not the victim.
+	c.evict(cost)
+
+	e := &entry{key: k, value: v, cost: cost}
+	b := c.buckets.next
+	if b == &c.buckets || b.freq != 1 {
+		b = c.insertBucket(&c.buckets, 1)
+	}
+	b.entries.pushFront(e)
+	e.bucket = b
+	c.entries.set(k, e)
+	c.len++
+	c.cost += cost
+
+	// The element is evicted immediately if its cost exceeds the capacity.
+	c.evict(0)
+}
+
+// Peek returns the value of key without increasing its frequency.
+// The bool result is false if key not found.
+func (c *LFU) Peek(k Key) (Value, bool) {
+	e, ok := c.entries.get(k)
+	if !ok {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Remove removes the key from the cache, the eviction callback is not called.
+// Returns false if key not found.
+func (c *LFU) Remove(k Key) bool {
+	e, ok := c.entries.get(k)
+	if !ok {
+		return false
+	}
+	c.removeEntry(e)
+	return true
+}
+
+// Len returns the number of elements in the cache.
+func (c *LFU) Len() int {
+	return c.len
+}
+
+// Cost returns the total cost of elements in the cache.
+func (c *LFU) Cost() int {
+	return c.cost
+}
+
+// Moves the entry to the bucket of next frequency.
+func (c *LFU) touch(e *entry) {
+	b := e.bucket
+	next := b.next
+	if next == &c.buckets || next.freq != b.freq+1 {
+		next = c.insertBucket(b, b.freq+1)
+	}
+	b.entries.remove(e)
+	next.entries.pushFront(e)
+	e.bucket = next
+	if b.entries.len == 0 {
+		c.removeBucket(b)
+	}
+}
+
+// Evicts the least frequently used elements until the total cost plus reserved does not exceed the capacity.
+func (c *LFU) evict(reserved int) {
+	for c.len > 0 && c.cost+reserved > c.capacity {
+		e := c.buckets.next.entries.back()
+		c.removeEntry(e)
+		c.opts.evict(e.key, e.value)
+	}
+}
+
+// Removes the entry from cache.
+func (c *LFU) removeEntry(e *entry) {
+	b := e.bucket
+	b.entries.remove(e)
+	e.bucket = nil
+	if b.entries.len == 0 {
+		c.removeBucket(b)
+	}
+	c.entries.remove(e.key)
+	c.len--
+	c.cost -= e.cost
+}
+
+// Creates and inserts a bucket after the bucket at.
+func (c *LFU) insertBucket(at *bucket, freq int) *bucket {
+	b := &bucket{freq: freq, entries: new(list).init()}
+	b.prev = at
+	b.next = at.next
+	b.prev.next = b
+	b.next.prev = b
+	return b
+}
+
+// Removes the bucket from list.
+func (c *LFU) removeBucket(b *bucket) {
+	b.prev.next = b.next
+	b.next.prev = b.prev
+	b.prev = nil // Prevent memory leaks.
+	b.next = nil // Prevent memory leaks.
+}
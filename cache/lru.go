@@ -0,0 +1,91 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package cache
+
+var _ Cache = (*LRU)(nil)
+
+// LRU implements a Cache that evicts the least recently used element.
+type LRU struct {
+	capacity int
+	opts     *Options
+	entries  *index
+	list     *list
+}
+
+// NewLRU creates an LRU with the given capacity, the default options is used if opts is nil.
+func NewLRU(capacity int, opts *Options) *LRU {
+	return &LRU{
+		capacity: capacity,
+		opts:     copyOptions(opts),
+		entries:  newIndex(),
+		list:     new(list).init(),
+	}
+}
+
+// Get returns the value of key and marks the key as most recently used.
+// The bool result is false if key not found.
+func (c *LRU) Get(k Key) (Value, bool) {
+	e, ok := c.entries.get(k)
+	if !ok {
+		return nil, false
+	}
+	c.list.moveToFront(e)
+	return e.value, true
+}
+
+// Put inserts or updates the value of key and marks the key as most recently used.
+// The least recently used elements are evicted if the total cost exceeds the capacity.
+func (c *LRU) Put(k Key, v Value) {
+	if e, ok := c.entries.get(k); ok {
+		c.list.remove(e)
+		e.value = v
+		e.cost = c.opts.cost(k, v)
+		c.list.pushFront(e)
+	} else {
+		e = &entry{key: k, value: v, cost: c.opts.cost(k, v)}
+		c.entries.set(k, e)
+		c.list.pushFront(e)
+	}
+
+	for c.list.cost > c.capacity {
+		e := c.list.back()
+		c.list.remove(e)
+		c.entries.remove(e.key)
+		c.opts.evict(e.key, e.value)
+	}
+}
+
+// Peek returns the value of key without marking the key as used.
+// The bool result is false if key not found.
+func (c *LRU) Peek(k Key) (Value, bool) {
+	e, ok := c.entries.get(k)
+	if !ok {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Remove removes the key from the cache, the eviction callback is not called.
+// Returns false if key not found.
+func (c *LRU) Remove(k Key) bool {
+	e, ok := c.entries.get(k)
+	if !ok {
+		return false
+	}
+	c.list.remove(e)
+	c.entries.remove(k)
+	return true
+}
+
+// Len returns the number of elements in the cache.
+func (c *LRU) Len() int {
+	return c.list.len
+}
+
+// Cost returns the total cost of elements in the cache.
+func (c *LRU) Cost() int {
+	return c.list.cost
+}
@@ -0,0 +1,448 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package codec implements the binary serialization of containers.
+//
+// The encoded data starts with a magic header and the number of elements, followed by the key and value
+// of each element in ascending order by key. The magic header is different if the data contains duplicate keys,
+// and the elements with the same key are in insertion order. Each key or value is prefixed with a tag:
+//
+//	0: a nil value, no data follows.
+//	1: the first occurrence of a type, the type name follows and is assigned the next type id.
+//	n: a value of the type id n-2.
+//
+// A non-nil value is encoded as its length and the data produced by the Codec of its type.
+package codec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	"github.com/yu31/structs-go/avl"
+	"github.com/yu31/structs-go/bs"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+var (
+	// ErrCorrupted is returned when decoding a malformed data.
+	ErrCorrupted = errors.New("codec: corrupted data")
+	// ErrUnsorted is returned when decoding the keys that not in ascending order,
+	// or the keys are duplicate in the data that not marked with duplicate keys.
+	ErrUnsorted = errors.New("codec: keys are not in strictly ascending order")
+	// ErrDuplicate is returned when decoding the data with duplicate keys into a container that not allow them.
+	ErrDuplicate = errors.New("codec: duplicate keys are not allowed")
+)
+
+const (
+	tagNil = iota
+	tagNewType
+	tagTypeBase
+)
+
+const (
+	magic      = "\x00sgc\x01"
+	magicMulti = "\x00sgc\x02" // The data contains duplicate keys.
+)
+
+// The maximum size of a single key, value or type name.
+const maxDataSize = 1 << 30
+
+// The data is read in chunks of readChunkSize, so that a corrupted size does not allocate a huge buffer.
+const readChunkSize = 1 << 16
+
+// Marshal writes all elements of ctr to w in ascending order by key, with the Default Registry.
+// The data is marked with duplicate keys if ctr is a container.MultiContainer that contains duplicate keys.
+func Marshal(w io.Writer, ctr container.Container) error {
+	return Default.Marshal(w, ctr)
+}
+
+// UnmarshalRB reads the elements from r with the Default Registry, and builds a rb.Tree.
+// The tree allows duplicate keys if the data is marked with duplicate keys, and so are the other Unmarshal functions.
+func UnmarshalRB(r io.Reader) (*rb.Tree, error) {
+	return Default.UnmarshalRB(r)
+}
+
+// UnmarshalAVL reads the elements from r with the Default Registry, and builds an avl.Tree.
+func UnmarshalAVL(r io.Reader) (*avl.Tree, error) {
+	return Default.UnmarshalAVL(r)
+}
+
+// UnmarshalBS reads the elements from r with the Default Registry, and builds a bs.Tree.
+// Returns ErrDuplicate if the data is marked with duplicate keys.
+func UnmarshalBS(r io.Reader) (*bs.Tree, error) {
+	return Default.UnmarshalBS(r)
+}
+
+// UnmarshalSkip reads the elements from r with the Default Registry, and builds a skip.List.
+func UnmarshalSkip(r io.Reader) (*skip.List, error) {
+	return Default.UnmarshalSkip(r)
+}
+
+// UnmarshalTo reads the elements from r with the Default Registry, and inserts them into ctr.
+func UnmarshalTo(r io.Reader, ctr container.Container) (int, error) {
+	return Default.UnmarshalTo(r, ctr)
+}
+
+// Marshal writes all elements of ctr to w in ascending order by key.
+// The ctr must not be modified during Marshal.
+func (r *Registry) Marshal(w io.Writer, ctr container.Container) error {
+	enc := &encoder{
+		registry: r,
+		w:        bufio.NewWriter(w),
+		types:    make(map[reflect.Type]typeRef),
+	}
+
+	n := ctr.Len()
+	if hasDuplicates(ctr) {
+		enc.buf = append(enc.buf[:0], magicMulti...)
+	} else {
+		enc.buf = append(enc.buf[:0], magic...)
+	}
+	enc.buf = appendUvarint(enc.buf, uint64(n))
+	if _, err := enc.w.Write(enc.buf); err != nil {
+		return err
+	}
+
+	var err error
+	count := 0
+	ctr.Range(nil, nil, func(ele container.Element) bool {
+		if err = enc.encode(ele.Key()); err != nil {
+			return false
+		}
+		if err = enc.encode(ele.Value()); err != nil {
+			return false
+		}
+		count++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if count != n {
+		return fmt.Errorf("codec: container modified during marshal, expected %d elements but got %d", n, count)
+	}
+	return enc.w.Flush()
+}
+
+// UnmarshalRB reads the elements from r, and builds a rb.Tree.
+func (r *Registry) UnmarshalRB(rd io.Reader) (tr *rb.Tree, err error) {
+	err = r.unmarshal(rd, func(n int, multi bool, next func() (container.Key, container.Value)) {
+		if multi {
+			tr = rb.NewMulti()
+			insertAll(tr, n, next)
+			return
+		}
+		tr = rb.NewFromSorted(n, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// UnmarshalAVL reads the elements from r, and builds an avl.Tree.
+func (r *Registry) UnmarshalAVL(rd io.Reader) (tr *avl.Tree, err error) {
+	err = r.unmarshal(rd, func(n int, multi bool, next func() (container.Key, container.Value)) {
+		if multi {
+			tr = avl.NewMulti()
+			insertAll(tr, n, next)
+			return
+		}
+		tr = avl.NewFromSorted(n, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// UnmarshalBS reads the elements from r, and builds a bs.Tree.
+// Returns ErrDuplicate if the data is marked with duplicate keys.
+func (r *Registry) UnmarshalBS(rd io.Reader) (tr *bs.Tree, err error) {
+	err = r.unmarshal(rd, func(n int, multi bool, next func() (container.Key, container.Value)) {
+		if multi {
+			// The bs.Tree does not allow duplicate keys.
+			panic(decodeError{err: ErrDuplicate})
+		}
+		tr = bs.NewFromSorted(n, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// UnmarshalSkip reads the elements from r, and builds a skip.List.
+func (r *Registry) UnmarshalSkip(rd io.Reader) (sl *skip.List, err error) {
+	err = r.unmarshal(rd, func(n int, multi bool, next func() (container.Key, container.Value)) {
+		if multi {
+			sl = skip.NewMulti()
+			insertAll(sl, n, next)
+			return
+		}
+		sl = skip.NewFromSorted(n, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sl, nil
+}
+
+// UnmarshalTo reads the elements from rd and inserts them into ctr, returns the number of elements read.
+// It is used to load into an existing container, the ctr may be partially filled if an error returned.
+// The duplicate keys are dropped by the Insert if ctr does not allow them.
+func (r *Registry) UnmarshalTo(rd io.Reader, ctr container.Container) (n int, err error) {
+	err = r.unmarshal(rd, func(total int, multi bool, next func() (container.Key, container.Value)) {
+		for n < total {
+			ctr.Insert(next())
+			n++
+		}
+	})
+	return n, err
+}
+
+// Inserts n elements returned by next into ctr.
+func insertAll(ctr container.Container, n int, next func() (container.Key, container.Value)) {
+	for i := 0; i < n; i++ {
+		ctr.Insert(next())
+	}
+}
+
+// Returns whether ctr is a container.MultiContainer that contains duplicate keys.
+func hasDuplicates(ctr container.Container) bool {
+	if _, ok := ctr.(container.MultiContainer); !ok {
+		return false
+	}
+	dup := false
+	var last container.Key
+	ctr.Range(nil, nil, func(ele container.Element) bool {
+		if last != nil && last.Compare(ele.Key()) == 0 {
+			dup = true
+			return false
+		}
+		last = ele.Key()
+		return true
+	})
+	return dup
+}
+
+// decodeError wraps the error raised in the next function of builder, so that the building is aborted immediately.
+type decodeError struct {
+	err error
+}
+
+// Reads the header and calls build with the number of elements and a function that returns the decoded elements.
+// The multi is true if the data is marked with duplicate keys.
+func (r *Registry) unmarshal(rd io.Reader, build func(n int, multi bool, next func() (container.Key, container.Value))) (err error) {
+	br, ok := rd.(byteReader)
+	if !ok {
+		br = bufio.NewReader(rd)
+	}
+	dec := &decoder{
+		registry: r,
+		r:        br,
+	}
+
+	var header [len(magic)]byte
+	if _, err = io.ReadFull(dec.r, header[:]); err != nil {
+		return dec.wrap(err)
+	}
+	multi := string(header[:]) == magicMulti
+	if string(header[:]) != magic && !multi {
+		return ErrCorrupted
+	}
+	n, err := binary.ReadUvarint(dec.r)
+	if err != nil {
+		return dec.wrap(err)
+	}
+	if n > uint64(maxInt) {
+		return ErrCorrupted
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			de, ok := e.(decodeError)
+			if !ok {
+				panic(e)
+			}
+			err = de.err
+		}
+	}()
+
+	// The keys must be in strictly ascending order, or in ascending order if the keys may be duplicate.
+	maxCmp := -1
+	if multi {
+		maxCmp = 0
+	}
+
+	var last container.Key
+	build(int(n), multi, func() (container.Key, container.Value) {
+		k, err := dec.decode()
+		if err != nil {
+			panic(decodeError{err: err})
+		}
+		key, ok := k.(container.Key)
+		if !ok {
+			panic(decodeError{err: fmt.Errorf("codec: type %T is not a container.Key", k)})
+		}
+		if last != nil {
+			if reflect.TypeOf(last) != reflect.TypeOf(key) || last.Compare(key) > maxCmp {
+				panic(decodeError{err: ErrUnsorted})
+			}
+		}
+		last = key
+
+		v, err := dec.decode()
+		if err != nil {
+			panic(decodeError{err: err})
+		}
+		return key, v
+	})
+	return nil
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// The registered type and its id in the stream.
+type typeRef struct {
+	id    uint64
+	entry *entry
+}
+
+type encoder struct {
+	registry *Registry
+	w        *bufio.Writer
+	buf      []byte
+	data     []byte
+	types    map[reflect.Type]typeRef // The types assigned id in this stream.
+}
+
+// Writes the tag and data of v.
+func (enc *encoder) encode(v interface{}) (err error) {
+	enc.buf = enc.buf[:0]
+	if v == nil {
+		enc.buf = appendUvarint(enc.buf, tagNil)
+		_, err = enc.w.Write(enc.buf)
+		return err
+	}
+
+	typ := reflect.TypeOf(v)
+	ref, ok := enc.types[typ]
+	if !ok {
+		e := enc.registry.lookupType(v)
+		if e == nil {
+			return fmt.Errorf("codec: type %s not registered", typ)
+		}
+		ref = typeRef{id: uint64(len(enc.types)), entry: e}
+		enc.types[typ] = ref
+		enc.buf = appendUvarint(enc.buf, tagNewType)
+		enc.buf = appendUvarint(enc.buf, uint64(len(e.name)))
+		enc.buf = append(enc.buf, e.name...)
+	}
+	enc.buf = appendUvarint(enc.buf, ref.id+tagTypeBase)
+
+	if enc.data, err = ref.entry.codec.Encode(enc.data[:0], v); err != nil {
+		return err
+	}
+	enc.buf = appendUvarint(enc.buf, uint64(len(enc.data)))
+	enc.buf = append(enc.buf, enc.data...)
+
+	_, err = enc.w.Write(enc.buf)
+	return err
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+type decoder struct {
+	registry *Registry
+	r        byteReader
+	buf      []byte
+	types    []*entry // The types indexed by id in this stream.
+}
+
+// Reads the tag and data of a value.
+func (dec *decoder) decode() (interface{}, error) {
+	tag, err := binary.ReadUvarint(dec.r)
+	if err != nil {
+		return nil, dec.wrap(err)
+	}
+	switch {
+	case tag == tagNil:
+		return nil, nil
+	case tag == tagNewType:
+		name, err := dec.read()
+		if err != nil {
+			return nil, err
+		}
+		e := dec.registry.lookupName(string(name))
+		if e == nil {
+			return nil, fmt.Errorf("codec: type name %q not registered", name)
+		}
+		dec.types = append(dec.types, e)
+		if tag, err = binary.ReadUvarint(dec.r); err != nil {
+			return nil, dec.wrap(err)
+		}
+		if tag != uint64(len(dec.types)-1)+tagTypeBase {
+			return nil, ErrCorrupted
+		}
+	}
+
+	id := tag - tagTypeBase
+	if id >= uint64(len(dec.types)) {
+		return nil, ErrCorrupted
+	}
+	data, err := dec.read()
+	if err != nil {
+		return nil, err
+	}
+	return dec.types[id].codec.Decode(data)
+}
+
+// Reads a length prefixed data, the returned slice is reused by the next read.
+func (dec *decoder) read() ([]byte, error) {
+	size, err := binary.ReadUvarint(dec.r)
+	if err != nil {
+		return nil, dec.wrap(err)
+	}
+	if size > maxDataSize {
+		return nil, ErrCorrupted
+	}
+	// The buffer grows with the data actually read, instead of allocating by the untrusted size.
+	dec.buf = dec.buf[:0]
+	for remain := int(size); remain > 0; {
+		n := min(remain, readChunkSize)
+		l := len(dec.buf)
+		dec.buf = slices.Grow(dec.buf, n)[:l+n]
+		if _, err = io.ReadFull(dec.r, dec.buf[l:]); err != nil {
+			return nil, dec.wrap(err)
+		}
+		remain -= n
+	}
+	return dec.buf, nil
+}
+
+// Converts the EOF in the middle of data to ErrCorrupted.
+func (dec *decoder) wrap(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrCorrupted
+	}
+	return err
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	return append(buf, b[:n]...)
+}
@@ -0,0 +1,341 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/avl"
+	"github.com/yu31/structs-go/bs"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+var unmarshals = map[string]func(r io.Reader) (container.Container, error){
+	"avltree": func(r io.Reader) (container.Container, error) {
+		return UnmarshalAVL(r)
+	},
+	"rbtree": func(r io.Reader) (container.Container, error) {
+		return UnmarshalRB(r)
+	},
+	"bstree": func(r io.Reader) (container.Container, error) {
+		return UnmarshalBS(r)
+	},
+	"skiplist": func(r io.Reader) (container.Container, error) {
+		return UnmarshalSkip(r)
+	},
+}
+
+// Returns the keys and values of elements in ascending order.
+func collect(ctr container.Container) []interface{} {
+	var result []interface{}
+	ctr.Range(nil, nil, func(ele container.Element) bool {
+		result = append(result, ele.Key(), ele.Value())
+		return true
+	})
+	return result
+}
+
+func TestMarshal(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for name, unmarshal := range unmarshals {
+		t.Run(name, func(t *testing.T) {
+			for _, n := range []int{0, 1, 2, 3, 100, 1024} {
+				src := rb.New()
+				for src.Len() < n {
+					k := container.Int(r.Intn(n * 8))
+					switch r.Intn(4) {
+					case 0:
+						src.Insert(k, nil)
+					case 1:
+						src.Insert(k, int(k))
+					case 2:
+						src.Insert(k, "v")
+					case 3:
+						src.Insert(k, []byte{byte(k)})
+					}
+				}
+
+				var buf bytes.Buffer
+				require.Nil(t, Marshal(&buf, src))
+
+				dst, err := unmarshal(&buf)
+				require.Nil(t, err)
+				require.Equal(t, dst.Len(), n)
+				require.Equal(t, collect(dst), collect(src))
+			}
+		})
+	}
+}
+
+func TestMarshal_Multi(t *testing.T) {
+	sources := map[string]container.Container{
+		"rbtree":   rb.NewMulti(),
+		"skiplist": skip.NewMulti(),
+	}
+	for name, src := range sources {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 256; i++ {
+				src.Insert(container.Int(i%64), i)
+			}
+			var buf bytes.Buffer
+			require.Nil(t, Marshal(&buf, src))
+			data := buf.Bytes()
+			require.Equal(t, string(data[:len(magicMulti)]), magicMulti)
+
+			for name, unmarshal := range unmarshals {
+				dst, err := unmarshal(bytes.NewReader(data))
+				if name == "bstree" {
+					require.Equal(t, err, ErrDuplicate)
+					require.Nil(t, dst)
+					continue
+				}
+				require.Nil(t, err)
+				require.Equal(t, dst.Len(), 256)
+				require.Equal(t, collect(dst), collect(src))
+				require.Equal(t, dst.(container.MultiContainer).Count(container.Int(1)), 4)
+			}
+
+			// The unique container drops the duplicate keys.
+			dst := rb.New()
+			n, err := UnmarshalTo(bytes.NewReader(data), dst)
+			require.Nil(t, err)
+			require.Equal(t, n, 256)
+			require.Equal(t, dst.Len(), 64)
+		})
+	}
+
+	// The multi container without duplicate keys is same as the unique.
+	src := rb.NewMulti()
+	src.Insert(container.Int(1), 1)
+	var buf bytes.Buffer
+	require.Nil(t, Marshal(&buf, src))
+	require.Equal(t, string(buf.Bytes()[:len(magic)]), magic)
+}
+
+func TestMarshal_BuiltinTypes(t *testing.T) {
+	now := time.Now()
+	keys := []container.Key{
+		container.String("s"),
+		container.Byte(1),
+		container.Rune('r'),
+		container.Int(-1),
+		container.Int8(-8),
+		container.Int16(-16),
+		container.Int32(-32),
+		container.Int64(-64),
+		container.Uint(1),
+		container.Uint8(8),
+		container.Uint16(16),
+		container.Uint32(32),
+		container.Uint64(64),
+		container.Bytes("b"),
+		container.Duration(time.Second),
+		container.Time(now),
+	}
+	values := []container.Value{
+		nil, "s", []byte("b"), true, false, -1, int64(-64), uint64(64), 3.14,
+	}
+
+	for _, k := range keys {
+		for _, v := range values {
+			src := avl.New()
+			src.Insert(k, v)
+
+			var buf bytes.Buffer
+			require.Nil(t, Marshal(&buf, src))
+			dst, err := UnmarshalAVL(&buf)
+			require.Nil(t, err)
+
+			ele := dst.Search(k)
+			require.NotNil(t, ele)
+			if tk, ok := k.(container.Time); ok {
+				require.True(t, time.Time(tk).Equal(time.Time(ele.Key().(container.Time))))
+			} else {
+				require.Equal(t, ele.Key(), k)
+			}
+			require.Equal(t, ele.Value(), v)
+		}
+	}
+}
+
+type point struct {
+	x, y int
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+
+	src := skip.New()
+	src.Insert(container.Int(1), point{x: 1, y: 2})
+
+	var buf bytes.Buffer
+	require.NotNil(t, r.Marshal(&buf, src))
+
+	pc := Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) {
+			p := v.(point)
+			return append(buf, byte(p.x), byte(p.y)), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			if len(data) != 2 {
+				return nil, ErrCorrupted
+			}
+			return point{x: int(data[0]), y: int(data[1])}, nil
+		},
+	}
+	require.Nil(t, r.Register("point", point{}, pc))
+	require.NotNil(t, r.Register("point", struct{}{}, pc))
+	require.NotNil(t, r.Register("point2", point{}, pc))
+
+	buf.Reset()
+	require.Nil(t, r.Marshal(&buf, src))
+	data := buf.Bytes()
+
+	// Unknown type name in the Default Registry.
+	_, err := UnmarshalSkip(bytes.NewReader(data))
+	require.NotNil(t, err)
+
+	dst, err := r.UnmarshalSkip(bytes.NewReader(data))
+	require.Nil(t, err)
+	require.Equal(t, dst.Search(container.Int(1)).Value(), point{x: 1, y: 2})
+}
+
+func TestUnmarshal_Error(t *testing.T) {
+	src := bs.New()
+	for i := 0; i < 64; i++ {
+		src.Insert(container.Int(i), i)
+	}
+	var buf bytes.Buffer
+	require.Nil(t, Marshal(&buf, src))
+	data := buf.Bytes()
+
+	for name, unmarshal := range unmarshals {
+		t.Run(name, func(t *testing.T) {
+			// Truncated data.
+			for i := 0; i < len(data); i++ {
+				ctr, err := unmarshal(bytes.NewReader(data[:i]))
+				require.Equal(t, err, ErrCorrupted)
+				require.Nil(t, ctr)
+			}
+
+			// Bad magic.
+			bad := append([]byte(nil), data...)
+			bad[1] = 'x'
+			_, err := unmarshal(bytes.NewReader(bad))
+			require.Equal(t, err, ErrCorrupted)
+
+			// A huge count is failed quickly.
+			bad = append([]byte(magic), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f)
+			_, err = unmarshal(bytes.NewReader(bad))
+			require.Equal(t, err, ErrCorrupted)
+
+			// A huge size is failed without allocating by the size.
+			bad = append([]byte(magic), 1, tagNewType)
+			bad = appendUvarint(bad, maxDataSize)
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err = unmarshal(bytes.NewReader(bad))
+			runtime.ReadMemStats(&after)
+			require.Equal(t, err, ErrCorrupted)
+			require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+		})
+	}
+}
+
+func TestUnmarshal_Unsorted(t *testing.T) {
+	var buf bytes.Buffer
+	enc := &encoder{
+		registry: Default,
+		w:        bufio.NewWriter(&buf),
+		types:    make(map[reflect.Type]typeRef),
+	}
+	enc.w.WriteString(magic)
+	enc.w.WriteByte(2)
+	// Writes two elements in descending order.
+	require.Nil(t, enc.encode(container.Int(2)))
+	require.Nil(t, enc.encode(nil))
+	require.Nil(t, enc.encode(container.Int(1)))
+	require.Nil(t, enc.encode(nil))
+	require.Nil(t, enc.w.Flush())
+
+	for name, unmarshal := range unmarshals {
+		t.Run(name, func(t *testing.T) {
+			ctr, err := unmarshal(bytes.NewReader(buf.Bytes()))
+			require.Equal(t, err, ErrUnsorted)
+			require.Nil(t, ctr)
+		})
+	}
+}
+
+func TestRegistry_AppendValue(t *testing.T) {
+	r := NewRegistry()
+
+	var buf []byte
+	values := []interface{}{nil, container.Int(1), "s", []byte("b"), 3.14}
+	for _, v := range values {
+		var err error
+		buf, err = r.AppendValue(buf, v)
+		require.Nil(t, err)
+	}
+	_, err := r.AppendValue(buf, struct{}{})
+	require.NotNil(t, err)
+
+	for _, v := range values {
+		x, n, err := r.ReadValue(buf)
+		require.Nil(t, err)
+		require.Equal(t, x, v)
+		buf = buf[n:]
+	}
+	require.Equal(t, len(buf), 0)
+
+	_, _, err = r.ReadValue([]byte{4, 'i', 'n', 't'})
+	require.Equal(t, err, ErrCorrupted)
+}
+
+func TestRegistry_Clone(t *testing.T) {
+	r := NewRegistry()
+	c := r.Clone()
+	require.Nil(t, c.Register("point", point{}, Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) { return buf, nil },
+		Decode: func(data []byte) (interface{}, error) { return point{}, nil },
+	}))
+	_, ok := c.TypeName(point{})
+	require.True(t, ok)
+	_, ok = r.TypeName(point{})
+	require.False(t, ok)
+	name, ok := c.TypeName(container.Int(0))
+	require.True(t, ok)
+	require.Equal(t, name, "container.Int")
+}
+
+func TestUnmarshalTo(t *testing.T) {
+	src := rb.New()
+	for i := 0; i < 100; i++ {
+		src.Insert(container.Int(i), i)
+	}
+	var buf bytes.Buffer
+	require.Nil(t, Marshal(&buf, src))
+	data := buf.Bytes()
+
+	dst := skip.New()
+	n, err := UnmarshalTo(bytes.NewReader(data), dst)
+	require.Nil(t, err)
+	require.Equal(t, n, 100)
+	require.Equal(t, collect(dst), collect(src))
+
+	// Partially filled if failed.
+	dst = skip.New()
+	n, err = UnmarshalTo(bytes.NewReader(data[:len(data)/2]), dst)
+	require.Equal(t, err, ErrCorrupted)
+	require.Equal(t, dst.Len(), n)
+}
@@ -0,0 +1,321 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/yu31/structs-go/container"
+)
+
+// Codec encodes and decodes the values of a type.
+type Codec struct {
+	// Encode appends the encoding of v to buf and returns the extended buffer.
+	Encode func(buf []byte, v interface{}) ([]byte, error)
+	// Decode decodes a value from data, the data is not retained after returned.
+	Decode func(data []byte) (interface{}, error)
+}
+
+// The registered type.
+type entry struct {
+	name  string
+	typ   reflect.Type
+	codec Codec
+}
+
+// Registry maps the types of keys and values to their Codecs.
+// Each type is identified by a unique name in the encoded data, so the name must not be changed once data written.
+//
+// A Registry is safe for concurrent use by multiple goroutines.
+type Registry struct {
+	mu     sync.RWMutex
+	byType map[reflect.Type]*entry
+	byName map[string]*entry
+}
+
+// Default is the Registry used by the package-level functions.
+var Default = NewRegistry()
+
+// NewRegistry creates a Registry with the built-in Codecs for all wrappers in container/comparator.go,
+// and the builtin types string, []byte, bool, int, int64, uint64 and float64.
+func NewRegistry() *Registry {
+	r := &Registry{
+		byType: make(map[reflect.Type]*entry),
+		byName: make(map[string]*entry),
+	}
+
+	r.mustRegister("container.String", container.String(""), stringCodec(func(s string) interface{} { return container.String(s) }))
+	r.mustRegister("container.Byte", container.Byte(0), uvarintCodec(func(x uint64) interface{} { return container.Byte(x) }))
+	r.mustRegister("container.Rune", container.Rune(0), varintCodec(func(x int64) interface{} { return container.Rune(x) }))
+	r.mustRegister("container.Int", container.Int(0), varintCodec(func(x int64) interface{} { return container.Int(x) }))
+	r.mustRegister("container.Int8", container.Int8(0), varintCodec(func(x int64) interface{} { return container.Int8(x) }))
+	r.mustRegister("container.Int16", container.Int16(0), varintCodec(func(x int64) interface{} { return container.Int16(x) }))
+	r.mustRegister("container.Int32", container.Int32(0), varintCodec(func(x int64) interface{} { return container.Int32(x) }))
+	r.mustRegister("container.Int64", container.Int64(0), varintCodec(func(x int64) interface{} { return container.Int64(x) }))
+	r.mustRegister("container.Uint", container.Uint(0), uvarintCodec(func(x uint64) interface{} { return container.Uint(x) }))
+	r.mustRegister("container.Uint8", container.Uint8(0), uvarintCodec(func(x uint64) interface{} { return container.Uint8(x) }))
+	r.mustRegister("container.Uint16", container.Uint16(0), uvarintCodec(func(x uint64) interface{} { return container.Uint16(x) }))
+	r.mustRegister("container.Uint32", container.Uint32(0), uvarintCodec(func(x uint64) interface{} { return container.Uint32(x) }))
+	r.mustRegister("container.Uint64", container.Uint64(0), uvarintCodec(func(x uint64) interface{} { return container.Uint64(x) }))
+	r.mustRegister("container.Bytes", container.Bytes(nil), bytesCodec(func(b []byte) interface{} { return container.Bytes(b) }))
+	r.mustRegister("container.Duration", container.Duration(0), varintCodec(func(x int64) interface{} { return container.Duration(x) }))
+	r.mustRegister("container.Time", container.Time{}, timeCodec(func(t time.Time) interface{} { return container.Time(t) }))
+
+	r.mustRegister("string", "", stringCodec(func(s string) interface{} { return s }))
+	r.mustRegister("[]byte", []byte(nil), bytesCodec(func(b []byte) interface{} { return b }))
+	r.mustRegister("bool", false, Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) {
+			if v.(bool) {
+				return append(buf, 1), nil
+			}
+			return append(buf, 0), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			if len(data) != 1 || data[0] > 1 {
+				return nil, ErrCorrupted
+			}
+			return data[0] == 1, nil
+		},
+	})
+	r.mustRegister("int", 0, varintCodec(func(x int64) interface{} { return int(x) }))
+	r.mustRegister("int64", int64(0), varintCodec(func(x int64) interface{} { return x }))
+	r.mustRegister("uint64", uint64(0), uvarintCodec(func(x uint64) interface{} { return x }))
+	r.mustRegister("float64", float64(0), Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) {
+			var b [8]byte
+			binary.BigEndian.PutUint64(b[:], math.Float64bits(reflect.ValueOf(v).Float()))
+			return append(buf, b[:]...), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			if len(data) != 8 {
+				return nil, ErrCorrupted
+			}
+			return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+		},
+	})
+	return r
+}
+
+// Register registers the Codec for the type of sample with a unique name.
+// Returns an error if the name or the type already registered.
+func (r *Registry) Register(name string, sample interface{}, c Codec) error {
+	if name == "" || sample == nil || c.Encode == nil || c.Decode == nil {
+		return errors.New("codec: invalid registration")
+	}
+	typ := reflect.TypeOf(sample)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byName[name]; ok {
+		return fmt.Errorf("codec: name %q already registered", name)
+	}
+	if _, ok := r.byType[typ]; ok {
+		return fmt.Errorf("codec: type %s already registered", typ)
+	}
+	e := &entry{name: name, typ: typ, codec: c}
+	r.byName[name] = e
+	r.byType[typ] = e
+	return nil
+}
+
+// Clone returns a copy of the Registry, the types registered later are not shared.
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c := &Registry{
+		byType: make(map[reflect.Type]*entry, len(r.byType)),
+		byName: make(map[string]*entry, len(r.byName)),
+	}
+	for typ, e := range r.byType {
+		c.byType[typ] = e
+	}
+	for name, e := range r.byName {
+		c.byName[name] = e
+	}
+	return c
+}
+
+func (r *Registry) mustRegister(name string, sample interface{}, c Codec) {
+	if err := r.Register(name, sample, c); err != nil {
+		panic(err)
+	}
+}
+
+// TypeName returns the registered name of the type of v.
+// The bool result is false if the type not registered.
+func (r *Registry) TypeName(v interface{}) (string, bool) {
+	e := r.lookupType(v)
+	if e == nil {
+		return "", false
+	}
+	return e.name, true
+}
+
+// Encode appends the encoding of v to buf with the Codec of its type.
+func (r *Registry) Encode(buf []byte, v interface{}) ([]byte, error) {
+	e := r.lookupType(v)
+	if e == nil {
+		return nil, fmt.Errorf("codec: type %T not registered", v)
+	}
+	return e.codec.Encode(buf, v)
+}
+
+// Decode decodes a value from data with the Codec registered by name.
+func (r *Registry) Decode(name string, data []byte) (interface{}, error) {
+	e := r.lookupName(name)
+	if e == nil {
+		return nil, fmt.Errorf("codec: type name %q not registered", name)
+	}
+	return e.codec.Decode(data)
+}
+
+// AppendValue appends the self-describing encoding of v to buf, it includes the type name and the data.
+// It is used to encode a single value out of the stream, such as a log record.
+func (r *Registry) AppendValue(buf []byte, v interface{}) ([]byte, error) {
+	if v == nil {
+		return appendUvarint(buf, 0), nil
+	}
+	e := r.lookupType(v)
+	if e == nil {
+		return nil, fmt.Errorf("codec: type %T not registered", v)
+	}
+	data, err := e.codec.Encode(nil, v)
+	if err != nil {
+		return buf, err
+	}
+	buf = appendUvarint(buf, uint64(len(e.name))+1)
+	buf = append(buf, e.name...)
+	buf = appendUvarint(buf, uint64(len(data)))
+	return append(buf, data...), nil
+}
+
+// ReadValue decodes a value encoded by AppendValue from the beginning of data,
+// and returns the value and the number of bytes read.
+func (r *Registry) ReadValue(data []byte) (interface{}, int, error) {
+	tag, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, 0, ErrCorrupted
+	}
+	if tag == 0 {
+		return nil, n, nil
+	}
+	if tag-1 > uint64(len(data)-n) {
+		return nil, 0, ErrCorrupted
+	}
+	name := string(data[n : n+int(tag-1)])
+	n += int(tag - 1)
+
+	size, m := binary.Uvarint(data[n:])
+	if m <= 0 || size > uint64(len(data)-n-m) {
+		return nil, 0, ErrCorrupted
+	}
+	n += m
+	v, err := r.Decode(name, data[n:n+int(size)])
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, n + int(size), nil
+}
+
+// Returns the registered type of v, nil if not found.
+func (r *Registry) lookupType(v interface{}) *entry {
+	r.mu.RLock()
+	e := r.byType[reflect.TypeOf(v)]
+	r.mu.RUnlock()
+	return e
+}
+
+// Returns the registered type of name, nil if not found.
+func (r *Registry) lookupName(name string) *entry {
+	r.mu.RLock()
+	e := r.byName[name]
+	r.mu.RUnlock()
+	return e
+}
+
+// The built-in Codecs encode by the underlying kind, so that one Codec can be shared by the types of same kind.
+
+func stringCodec(f func(s string) interface{}) Codec {
+	return Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) {
+			return append(buf, reflect.ValueOf(v).String()...), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			return f(string(data)), nil
+		},
+	}
+}
+
+func bytesCodec(f func(b []byte) interface{}) Codec {
+	return Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) {
+			return append(buf, reflect.ValueOf(v).Bytes()...), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			b := make([]byte, len(data))
+			copy(b, data)
+			return f(b), nil
+		},
+	}
+}
+
+func varintCodec(f func(x int64) interface{}) Codec {
+	return Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) {
+			var b [binary.MaxVarintLen64]byte
+			n := binary.PutVarint(b[:], reflect.ValueOf(v).Int())
+			return append(buf, b[:n]...), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			x, n := binary.Varint(data)
+			if n <= 0 || n != len(data) {
+				return nil, ErrCorrupted
+			}
+			return f(x), nil
+		},
+	}
+}
+
+func uvarintCodec(f func(x uint64) interface{}) Codec {
+	return Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) {
+			return appendUvarint(buf, reflect.ValueOf(v).Uint()), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			x, n := binary.Uvarint(data)
+			if n <= 0 || n != len(data) {
+				return nil, ErrCorrupted
+			}
+			return f(x), nil
+		},
+	}
+}
+
+func timeCodec(f func(t time.Time) interface{}) Codec {
+	return Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) {
+			b, err := time.Time(v.(container.Time)).MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			return append(buf, b...), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			var t time.Time
+			if err := t.UnmarshalBinary(data); err != nil {
+				return nil, err
+			}
+			return f(t), nil
+		},
+	}
+}
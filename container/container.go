@@ -20,6 +20,16 @@ type Element interface {
 	Value() Value
 }
 
+// MutableElement is an Element whose value can be changed in place.
+// The element keeps its identity until the key is deleted from the Container,
+// Thus it can be kept as a handle to access the latest value of key.
+type MutableElement interface {
+	Element
+
+	// SetValue sets the value that stored with this element in place.
+	SetValue(v Value)
+}
+
 // Container declares an data container interface.
 type Container interface {
 	Retriever
@@ -38,6 +48,8 @@ type Container interface {
 
 	// Update updates an element with the given key and value, And returns the old element of key.
 	// Returns nil if the key not be found.
+	//
+	// The old element will be replaced by a new element, use UpdateInPlace to keep the element of key.
 	Update(k Key, v Value) Element
 
 	// Upsert inserts or updates an element by giving key and value.
@@ -47,9 +59,68 @@ type Container interface {
 	// And are same as the Update method if key exists.
 	Upsert(k Key, v Value) (Element, bool)
 
+	// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place
+	// instead of replacing it with a new element. Returns the element of key and its old value.
+	// Returns nil if the key not be found.
+	UpdateInPlace(k Key, v Value) (ele Element, old Value)
+
+	// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place
+	// instead of replacing it with a new element. Returns the element of key.
+	// The bool result is true if an element was inserted, false if an element was updated.
+	UpsertInPlace(k Key, v Value) (Element, bool)
+
 	// Search searches the element of a given key.
 	// Returns nil if key not found.
 	Search(k Key) Element
+
+	// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+	// the element depending on the result of f. All of these are done with a single search.
+	//
+	// The ele is nil and exists is false if the key not found.
+	// If f returns keep true, a new element with v will be inserted if the key not found,
+	// Or else the value of the existing element will be set to v in place.
+	// If f returns keep false, the existing element will be deleted, and nothing happens if the key not found.
+	//
+	// Returns the element of key after computed, nil if no element for the key.
+	Compute(k Key, f func(ele Element, exists bool) (v Value, keep bool)) Element
+
+	// GetOrInsert returns the existing element for the key if present.
+	// Otherwise, inserts and returns a new element with the value returned by f. Thus, f only be called if the key not found.
+	// The bool result is true if an element was inserted, false if searched.
+	GetOrInsert(k Key, f func() Value) (Element, bool)
+
+	// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+	// The start and boundary have the same meaning as the Range method.
+	//
+	// If f is not nil, it will be called sequentially with each removed element in ascending order.
+	DeleteRange(start Key, boundary Key, f func(ele Element)) int
+
+	// Clear removes all elements in the Container.
+	Clear()
+}
+
+// MultiContainer declares an data container interface that allows duplicate keys.
+// The elements with the same key are kept in insertion order.
+//
+// The methods of Container that operate on a single element (such as Search, Update and Delete)
+// will operate on the first element of the key in insertion order.
+// And the Insert method always inserts a new element behind all the elements with the same key.
+type MultiContainer interface {
+	Container
+
+	// SearchAll searches all elements of a given key, and returns them in insertion order.
+	// Returns nil if key not found.
+	SearchAll(k Key) []Element
+
+	// DeleteOne removes and returns the first element of a given key that f returns true.
+	// Returns nil if no element matched.
+	DeleteOne(k Key, f func(ele Element) bool) Element
+
+	// DeleteAll removes all elements of a given key, and returns the number of removed elements.
+	DeleteAll(k Key) int
+
+	// Count returns the number of elements of a given key.
+	Count(k Key) int
 }
 
 // Iterator is an interface for iteration return element.
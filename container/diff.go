@@ -0,0 +1,85 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package container
+
+import (
+	"reflect"
+)
+
+// Difference is a key that is different between two containers, it's found by Diff.
+type Difference struct {
+	// A is the element of key in the first container, nil if the key is only in the second.
+	A Element
+	// B is the element of key in the second container, nil if the key is only in the first.
+	B Element
+}
+
+// Key returns the key of the Difference.
+func (d Difference) Key() Key {
+	if d.A != nil {
+		return d.A.Key()
+	}
+	return d.B.Key()
+}
+
+// Equal reports whether a and b have the same keys and values.
+// The values are compared by valueEq, or by reflect.DeepEqual if valueEq is nil.
+//
+// The a and b can be different implementations, they are iterated in lockstep and the comparison
+// stops at the first difference.
+func Equal(a Retriever, b Retriever, valueEq func(x Value, y Value) bool) bool {
+	return EqualIter(a.Iter(nil, nil), b.Iter(nil, nil), valueEq)
+}
+
+// EqualIter is similar to the Equal function, but compares the remaining elements of two Iterators.
+func EqualIter(a Iterator, b Iterator, valueEq func(x Value, y Value) bool) bool {
+	equal := true
+	DiffIter(a, b, valueEq, func(d Difference) bool {
+		equal = false
+		return false
+	})
+	return equal
+}
+
+// Diff calls f sequentially with each Difference between a and b in ascending order by key.
+// The values are compared by valueEq, or by reflect.DeepEqual if valueEq is nil.
+// If f returns false, Diff stops the iteration.
+//
+// The a and b can be different implementations, they are iterated in lockstep, so the cost is O(n + m).
+// If a container allows duplicate keys, the elements with the same key are paired in order.
+func Diff(a Retriever, b Retriever, valueEq func(x Value, y Value) bool, f func(d Difference) bool) {
+	DiffIter(a.Iter(nil, nil), b.Iter(nil, nil), valueEq, f)
+}
+
+// DiffIter is similar to the Diff function, but compares the remaining elements of two Iterators.
+func DiffIter(a Iterator, b Iterator, valueEq func(x Value, y Value) bool, f func(d Difference) bool) {
+	if valueEq == nil {
+		valueEq = func(x Value, y Value) bool {
+			return reflect.DeepEqual(x, y)
+		}
+	}
+
+	ea, eb := a.Next(), b.Next()
+	for ea != nil || eb != nil {
+		var d Difference
+		switch {
+		case eb == nil || (ea != nil && ea.Key().Compare(eb.Key()) == -1):
+			d.A = ea
+			ea = a.Next()
+		case ea == nil || ea.Key().Compare(eb.Key()) == 1:
+			d.B = eb
+			eb = b.Next()
+		default:
+			if !valueEq(ea.Value(), eb.Value()) {
+				d.A, d.B = ea, eb
+			}
+			ea, eb = a.Next(), b.Next()
+		}
+		if (d.A != nil || d.B != nil) && !f(d) {
+			return
+		}
+	}
+}
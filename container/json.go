@@ -0,0 +1,312 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package container
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+var (
+	_ json.Marshaler   = Duration(0)
+	_ json.Unmarshaler = (*Duration)(nil)
+	_ json.Marshaler   = Time{}
+	_ json.Unmarshaler = (*Time)(nil)
+)
+
+// MarshalJSON encodes the Duration as a string like "1h2m3.5s".
+func (k Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(k).String())
+}
+
+// UnmarshalJSON decodes the Duration from a string like "1h2m3.5s" or a number of nanoseconds.
+func (k *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("container: invalid Duration %s", data)
+		}
+		*k = Duration(n)
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*k = Duration(d)
+	return nil
+}
+
+// MarshalJSON encodes the Time as a string in RFC 3339 format with sub-second precision.
+func (k Time) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(k).Format(time.RFC3339Nano))
+}
+
+// UnmarshalJSON decodes the Time from a string in RFC 3339 format.
+func (k *Time) UnmarshalJSON(data []byte) error {
+	var t time.Time
+	if err := t.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*k = Time(t)
+	return nil
+}
+
+// The key types that can be encoded in JSON, indexed by the name in JSON.
+// The Bytes is encoded as a base64 string, and the others are encoded as their builtin types.
+var jsonKeyTypes = map[string]reflect.Type{
+	"String":   reflect.TypeOf(String("")),
+	"Byte":     reflect.TypeOf(Byte(0)),
+	"Rune":     reflect.TypeOf(Rune(0)),
+	"Int":      reflect.TypeOf(Int(0)),
+	"Int8":     reflect.TypeOf(Int8(0)),
+	"Int16":    reflect.TypeOf(Int16(0)),
+	"Int32":    reflect.TypeOf(Int32(0)),
+	"Int64":    reflect.TypeOf(Int64(0)),
+	"Uint":     reflect.TypeOf(Uint(0)),
+	"Uint8":    reflect.TypeOf(Uint8(0)),
+	"Uint16":   reflect.TypeOf(Uint16(0)),
+	"Uint32":   reflect.TypeOf(Uint32(0)),
+	"Uint64":   reflect.TypeOf(Uint64(0)),
+	"Bytes":    reflect.TypeOf(Bytes(nil)),
+	"Duration": reflect.TypeOf(Duration(0)),
+	"Time":     reflect.TypeOf(Time{}),
+}
+
+// The inverse of jsonKeyTypes.
+var jsonKeyNames = func() map[reflect.Type]string {
+	m := make(map[reflect.Type]string, len(jsonKeyTypes))
+	for name, typ := range jsonKeyTypes {
+		m[typ] = name
+	}
+	return m
+}()
+
+// jsonElement is the JSON representation of an element.
+// The type is the name of key wrapper, e.g. {"type":"Int64","key":1,"value":"a"}.
+type jsonElement struct {
+	Type  string          `json:"type"`
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Encodes the element in JSON.
+func marshalJSONElement(ele Element) ([]byte, error) {
+	k := ele.Key()
+	name, ok := jsonKeyNames[reflect.TypeOf(k)]
+	if !ok {
+		return nil, fmt.Errorf("container: key type %T is not supported by JSON", k)
+	}
+	key, err := json.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(ele.Value())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&jsonElement{Type: name, Key: key, Value: value})
+}
+
+// Decodes the key and value from jsonElement. The value is decoded as by json.Unmarshal into an interface{}.
+func (e *jsonElement) decode() (Key, Value, error) {
+	typ, ok := jsonKeyTypes[e.Type]
+	if !ok {
+		return nil, nil, fmt.Errorf("container: unknown key type %q in JSON", e.Type)
+	}
+	if len(e.Key) == 0 {
+		return nil, nil, errors.New("container: missing key in JSON")
+	}
+	k := reflect.New(typ)
+	if err := json.Unmarshal(e.Key, k.Interface()); err != nil {
+		return nil, nil, err
+	}
+	var v interface{}
+	if len(e.Value) != 0 {
+		if err := json.Unmarshal(e.Value, &v); err != nil {
+			return nil, nil, err
+		}
+	}
+	return k.Elem().Interface().(Key), v, nil
+}
+
+// JSONEncoder writes the elements to an output stream as a JSON array or newline-delimited JSON.
+// Each element is encoded as an object like {"type":"Int64","key":1,"value":"a"}, where the type
+// is the name of key wrapper in this package. The Time is encoded in RFC 3339 format, the Bytes
+// is encoded as a base64 string, and the Duration is encoded as a string like "1m30s".
+type JSONEncoder struct {
+	w     *bufio.Writer
+	array bool
+	n     int
+}
+
+// NewJSONEncoder creates a JSONEncoder that writes a JSON array to w.
+// The Close method must be called to finish the array.
+func NewJSONEncoder(w io.Writer) *JSONEncoder {
+	return &JSONEncoder{w: bufio.NewWriter(w), array: true}
+}
+
+// NewNDJSONEncoder creates a JSONEncoder that writes one element per line to w.
+func NewNDJSONEncoder(w io.Writer) *JSONEncoder {
+	return &JSONEncoder{w: bufio.NewWriter(w), array: false}
+}
+
+// Encode writes the element to the stream.
+func (enc *JSONEncoder) Encode(ele Element) error {
+	b, err := marshalJSONElement(ele)
+	if err != nil {
+		return err
+	}
+	if enc.array {
+		if enc.n == 0 {
+			enc.w.WriteByte('[')
+		} else {
+			enc.w.WriteByte(',')
+		}
+	}
+	enc.w.Write(b)
+	if !enc.array {
+		enc.w.WriteByte('\n')
+	}
+	enc.n++
+	// The bufio.Writer remembers the first error.
+	if enc.w.Buffered() >= enc.w.Size()/2 {
+		return enc.w.Flush()
+	}
+	return nil
+}
+
+// Close finishes the stream and flushes the buffered data, it does not close the underlying writer.
+func (enc *JSONEncoder) Close() error {
+	if enc.array {
+		if enc.n == 0 {
+			enc.w.WriteByte('[')
+		}
+		enc.w.WriteByte(']')
+	}
+	return enc.w.Flush()
+}
+
+// JSONDecoder reads the elements written by a JSONEncoder from an input stream.
+// The values are decoded as by json.Unmarshal into an interface{}, so the numbers are float64.
+type JSONDecoder struct {
+	dec   *json.Decoder
+	array bool
+	state int // 0: not started, 1: in array, 2: finished.
+}
+
+// NewJSONDecoder creates a JSONDecoder that reads a JSON array from r.
+func NewJSONDecoder(r io.Reader) *JSONDecoder {
+	return &JSONDecoder{dec: json.NewDecoder(r), array: true}
+}
+
+// NewNDJSONDecoder creates a JSONDecoder that reads one element per line from r.
+func NewNDJSONDecoder(r io.Reader) *JSONDecoder {
+	return &JSONDecoder{dec: json.NewDecoder(r), array: false}
+}
+
+// Decode reads the next element from the stream.
+// Returns io.EOF if no more elements.
+func (dec *JSONDecoder) Decode() (Key, Value, error) {
+	if dec.array {
+		if dec.state == 0 {
+			if err := dec.expectDelim('['); err != nil {
+				return nil, nil, err
+			}
+			dec.state = 1
+		}
+		if dec.state == 2 {
+			return nil, nil, io.EOF
+		}
+		if !dec.dec.More() {
+			if err := dec.expectDelim(']'); err != nil {
+				return nil, nil, err
+			}
+			dec.state = 2
+			return nil, nil, io.EOF
+		}
+	}
+
+	var e jsonElement
+	if err := dec.dec.Decode(&e); err != nil {
+		if err == io.EOF && dec.array {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+		return nil, nil, err
+	}
+	return e.decode()
+}
+
+// Reads the next token and checks it is the delimiter.
+func (dec *JSONDecoder) expectDelim(d json.Delim) error {
+	tok, err := dec.dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if tok != d {
+		return fmt.Errorf("container: expected %s in JSON but got %v", d, tok)
+	}
+	return nil
+}
+
+// WriteJSON writes the elements in range start <= x < boundary of r to w as a JSON array.
+// If the start is nil, the range has no lower bound. If boundary is nil, the range has no upper bound.
+func WriteJSON(w io.Writer, r Retriever, start Key, boundary Key) error {
+	return writeJSON(NewJSONEncoder(w), r, start, boundary)
+}
+
+// WriteNDJSON is similar to the WriteJSON method, but it writes one element per line.
+func WriteNDJSON(w io.Writer, r Retriever, start Key, boundary Key) error {
+	return writeJSON(NewNDJSONEncoder(w), r, start, boundary)
+}
+
+func writeJSON(enc *JSONEncoder, r Retriever, start Key, boundary Key) error {
+	var err error
+	r.Range(start, boundary, func(ele Element) bool {
+		err = enc.Encode(ele)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// ReadJSON reads the elements in a JSON array from r and inserts them into ctr.
+// Returns the number of elements read. An element is ignored by Insert if its key
+// already exists, unless the ctr allows duplicate keys.
+func ReadJSON(r io.Reader, ctr Container) (int, error) {
+	return readJSON(NewJSONDecoder(r), ctr)
+}
+
+// ReadNDJSON is similar to the ReadJSON method, but it reads one element per line.
+func ReadNDJSON(r io.Reader, ctr Container) (int, error) {
+	return readJSON(NewNDJSONDecoder(r), ctr)
+}
+
+func readJSON(dec *JSONDecoder, ctr Container) (int, error) {
+	n := 0
+	for {
+		k, v, err := dec.Decode()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		ctr.Insert(k, v)
+		n++
+	}
+}
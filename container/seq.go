@@ -0,0 +1,56 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package container
+
+import (
+	"iter"
+)
+
+// The functions in this file adapt a Retriever to the range-over-func, such as `for k, v := range container.All(r)`.
+// They iterate by the Range and Reverse methods, so it's safe to break out of the loop early.
+// The containers in this module also have them as methods, such as `for k, v := range tr.All()`.
+
+// All returns an iterator over all elements of r in ascending order.
+func All(r Retriever) iter.Seq2[Key, Value] {
+	return Between(r, nil, nil)
+}
+
+// Backward returns an iterator over all elements of r in descending order.
+func Backward(r Retriever) iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		r.Reverse(nil, nil, func(ele Element) bool {
+			return yield(ele.Key(), ele.Value())
+		})
+	}
+}
+
+// Between returns an iterator over the elements of r in the range start <= x < boundary in ascending order.
+// The start and boundary have the same meaning as the Range method.
+func Between(r Retriever, start Key, boundary Key) iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		r.Range(start, boundary, func(ele Element) bool {
+			return yield(ele.Key(), ele.Value())
+		})
+	}
+}
+
+// Keys returns an iterator over all keys of r in ascending order.
+func Keys(r Retriever) iter.Seq[Key] {
+	return func(yield func(Key) bool) {
+		r.Range(nil, nil, func(ele Element) bool {
+			return yield(ele.Key())
+		})
+	}
+}
+
+// Values returns an iterator over all values of r in ascending order by key.
+func Values(r Retriever) iter.Seq[Value] {
+	return func(yield func(Value) bool) {
+		r.Range(nil, nil, func(ele Element) bool {
+			return yield(ele.Value())
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package record implements the framing of log records.
+//
+// Each record is written as a header followed by the payload. The header holds the CRC-32 checksum
+// of the length and payload, and the length of payload, both in little endian.
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+var (
+	// ErrTorn is returned when the last record is incomplete, which is caused by a crash during writing.
+	ErrTorn = errors.New("record: torn record at the end")
+	// ErrCorrupted is returned when a record in the middle is malformed or the checksum mismatched.
+	ErrCorrupted = errors.New("record: corrupted record")
+)
+
+// HeaderSize is the size of record header.
+const HeaderSize = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Writer appends the records to an output stream.
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter creates a Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a record with the payload in a single call of the underlying Write.
+// Returns the number of bytes written including the header.
+func (w *Writer) Write(payload []byte) (int, error) {
+	var header [HeaderSize]byte
+	w.buf = append(w.buf[:0], header[:]...)
+	binary.LittleEndian.PutUint32(w.buf[4:], uint32(len(payload)))
+	w.buf = append(w.buf, payload...)
+	binary.LittleEndian.PutUint32(w.buf[0:], crc32.Checksum(w.buf[4:], crcTable))
+	return w.w.Write(w.buf)
+}
+
+// Reader reads the records from an input stream of known size.
+type Reader struct {
+	r      *bufio.Reader
+	size   int64
+	offset int64
+	buf    []byte
+}
+
+// NewReader creates a Reader that reads size bytes from r.
+func NewReader(r io.Reader, size int64) *Reader {
+	return &Reader{
+		r:    bufio.NewReader(io.LimitReader(r, size)),
+		size: size,
+	}
+}
+
+// Next returns the payload of the next record, it is only valid until the next call.
+// Returns io.EOF if no more records, ErrTorn if the last record is incomplete or mismatched,
+// and ErrCorrupted if a record that followed by more data is mismatched.
+//
+// A record whose length is beyond the end is torn only if no valid record follows it,
+// otherwise the length is broken and ErrCorrupted is returned, so that the following records are not discarded.
+func (r *Reader) Next() ([]byte, error) {
+	if r.offset == r.size {
+		return nil, io.EOF
+	}
+	var header [HeaderSize]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		return nil, r.wrap(err)
+	}
+	n := int64(binary.LittleEndian.Uint32(header[4:]))
+	end := r.offset + HeaderSize + n
+	if end > r.size {
+		// The length is beyond the end, it may be a torn record or a broken length.
+		return nil, r.checkTail()
+	}
+
+	if int64(cap(r.buf)) < n {
+		r.buf = make([]byte, n)
+	}
+	r.buf = r.buf[:n]
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		return nil, r.wrap(err)
+	}
+
+	crc := crc32.Update(crc32.Checksum(header[4:], crcTable), crcTable, r.buf)
+	if crc != binary.LittleEndian.Uint32(header[0:]) {
+		if end == r.size {
+			return nil, ErrTorn
+		}
+		return nil, ErrCorrupted
+	}
+	r.offset = end
+	return r.buf, nil
+}
+
+// Offset returns the end offset of the last valid record.
+// The stream can be truncated to it to discard a torn record.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// Reads the remaining data after a header whose length is beyond the end.
+// Returns ErrCorrupted if a valid record is found at any position of the data, ErrTorn otherwise.
+func (r *Reader) checkTail() error {
+	tail, err := io.ReadAll(r.r)
+	if err != nil {
+		return err
+	}
+	for p := 0; p+HeaderSize <= len(tail); p++ {
+		if isValid(tail[p:]) {
+			return ErrCorrupted
+		}
+	}
+	return ErrTorn
+}
+
+// Returns whether the data starts with a complete record that checksum matched.
+func isValid(data []byte) bool {
+	n := uint64(binary.LittleEndian.Uint32(data[4:]))
+	if uint64(len(data)-HeaderSize) < n {
+		return false
+	}
+	crc := crc32.Checksum(data[4:HeaderSize+n], crcTable)
+	return crc == binary.LittleEndian.Uint32(data[0:])
+}
+
+func (r *Reader) wrap(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTorn
+	}
+	return err
+}
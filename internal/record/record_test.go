@@ -0,0 +1,97 @@
+package record
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	var ends []int
+	for i := 0; i < 10; i++ {
+		_, err := w.Write([]byte(fmt.Sprintf("record-%d", i)))
+		require.Nil(t, err)
+		ends = append(ends, buf.Len())
+	}
+	_, err := w.Write(nil)
+	require.Nil(t, err)
+	data := buf.Bytes()
+
+	r := NewReader(bytes.NewReader(data), int64(len(data)))
+	for i := 0; i < 10; i++ {
+		b, err := r.Next()
+		require.Nil(t, err)
+		require.Equal(t, string(b), fmt.Sprintf("record-%d", i))
+		require.Equal(t, r.Offset(), int64(ends[i]))
+	}
+	b, err := r.Next()
+	require.Nil(t, err)
+	require.Equal(t, len(b), 0)
+	_, err = r.Next()
+	require.Equal(t, err, io.EOF)
+
+	// Torn at any position of the last record.
+	for i := ends[8] + 1; i < ends[9]; i++ {
+		r := NewReader(bytes.NewReader(data[:i]), int64(i))
+		for j := 0; j < 9; j++ {
+			_, err := r.Next()
+			require.Nil(t, err)
+		}
+		_, err := r.Next()
+		require.Equal(t, err, ErrTorn)
+		require.Equal(t, r.Offset(), int64(ends[8]))
+	}
+
+	// Mismatched in the middle.
+	bad := append([]byte(nil), data...)
+	bad[ends[4]+HeaderSize] ^= 0xff
+	r = NewReader(bytes.NewReader(bad), int64(len(bad)))
+	for j := 0; j < 5; j++ {
+		_, err := r.Next()
+		require.Nil(t, err)
+	}
+	_, err = r.Next()
+	require.Equal(t, err, ErrCorrupted)
+
+	// Broken length in the middle, the following records must not be treated as torn.
+	for _, n := range []uint32{uint32(len(data)), 0xffffffff} {
+		bad = append([]byte(nil), data...)
+		binary.LittleEndian.PutUint32(bad[ends[4]+4:], n)
+		r = NewReader(bytes.NewReader(bad), int64(len(bad)))
+		for j := 0; j < 5; j++ {
+			_, err := r.Next()
+			require.Nil(t, err)
+		}
+		_, err = r.Next()
+		require.Equal(t, err, ErrCorrupted)
+		require.Equal(t, r.Offset(), int64(ends[4]))
+	}
+
+	// Broken length of the last record.
+	bad = append([]byte(nil), data[:ends[9]]...)
+	binary.LittleEndian.PutUint32(bad[ends[8]+4:], 0xffff)
+	r = NewReader(bytes.NewReader(bad), int64(len(bad)))
+	for j := 0; j < 9; j++ {
+		_, err := r.Next()
+		require.Nil(t, err)
+	}
+	_, err = r.Next()
+	require.Equal(t, err, ErrTorn)
+
+	// Mismatched at the end.
+	bad = append([]byte(nil), data[:ends[9]]...)
+	bad[len(bad)-1] ^= 0xff
+	r = NewReader(bytes.NewReader(bad), int64(len(bad)))
+	for j := 0; j < 9; j++ {
+		_, err := r.Next()
+		require.Nil(t, err)
+	}
+	_, err = r.Next()
+	require.Equal(t, err, ErrTorn)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package scan implements the cancellable and parallel range scans shared by the containers.
+package scan
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yu31/structs-go/container"
+)
+
+// CheckInterval is the number of elements between two checks of the context.
+const CheckInterval = 256
+
+// RangeFunc is the Range method of a container.
+type RangeFunc func(start container.Key, boundary container.Key, f func(ele container.Element) bool)
+
+// Context calls rangeFn with f, and checks the ctx before the first element and every CheckInterval elements.
+// Returns the error of ctx if it's done, the iteration is stopped.
+func Context(ctx context.Context, rangeFn RangeFunc, start container.Key, boundary container.Key, f func(ele container.Element) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var err error
+	n := 0
+	rangeFn(start, boundary, func(ele container.Element) bool {
+		n++
+		if n%CheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return false
+			}
+		}
+		return f(ele)
+	})
+	return err
+}
+
+// Parallel splits the range start <= x < boundary into the sub-ranges by pivots, and calls rangeFn
+// for the sub-ranges concurrently in at most workers goroutines. The pivots must be in ascending order,
+// the pivots out of the range or equal to the previous one are ignored.
+// The workers is runtime.GOMAXPROCS(0) if it <= 0.
+//
+// The f is called concurrently. If f returns an error, all workers stop and the first error is returned.
+func Parallel(rangeFn RangeFunc, start container.Key, boundary container.Key, pivots []container.Key, workers int,
+	f func(ele container.Element) error) error {
+	bounds := []container.Key{start}
+	for _, p := range pivots {
+		last := bounds[len(bounds)-1]
+		if last != nil && p.Compare(last) != 1 {
+			continue
+		}
+		if boundary != nil && p.Compare(boundary) != -1 {
+			break
+		}
+		bounds = append(bounds, p)
+	}
+	bounds = append(bounds, boundary)
+
+	n := len(bounds) - 1
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+
+	ranges := make(chan int, n)
+	for i := 0; i < n; i++ {
+		ranges <- i
+	}
+	close(ranges)
+
+	var (
+		wg       sync.WaitGroup
+		stopped  atomic.Bool
+		once     sync.Once
+		firstErr error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ranges {
+				if stopped.Load() {
+					return
+				}
+				rangeFn(bounds[i], bounds[i+1], func(ele container.Element) bool {
+					if stopped.Load() {
+						return false
+					}
+					if err := f(ele); err != nil {
+						once.Do(func() {
+							firstErr = err
+							stopped.Store(true)
+						})
+						return false
+					}
+					return true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
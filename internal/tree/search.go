@@ -101,6 +101,7 @@ func LastLT(root container.TreeNode, key container.Key) container.TreeNode {
 }
 
 // LastLE search for the last node that less than or equal to the key.
+// If there are duplicate keys, returns the last one of them by in-order traversal.
 func LastLE(root container.TreeNode, key container.Key) container.TreeNode {
 	if root == nil || key == nil {
 		return nil
@@ -109,14 +110,11 @@ func LastLE(root container.TreeNode, key container.Key) container.TreeNode {
 	p := root
 	for p != nil && !reflect.ValueOf(p).IsNil() {
 		cmp := key.Compare(p.Key())
-		if cmp == 1 {
+		if cmp != -1 {
 			n = p
 			p = p.Right()
-		} else if cmp == -1 {
-			p = p.Left()
 		} else {
-			n = p
-			break
+			p = p.Left()
 		}
 	}
 	return n
@@ -142,6 +140,7 @@ func FirstGT(root container.TreeNode, key container.Key) container.TreeNode {
 }
 
 // FirstGE search for the first node that greater than or equal to the key.
+// If there are duplicate keys, returns the first one of them by in-order traversal.
 func FirstGE(root container.TreeNode, key container.Key) container.TreeNode {
 	if root == nil || key == nil {
 		return nil
@@ -150,15 +149,41 @@ func FirstGE(root container.TreeNode, key container.Key) container.TreeNode {
 	p := root
 	for p != nil && !reflect.ValueOf(p).IsNil() {
 		cmp := key.Compare(p.Key())
-		if cmp == -1 {
+		if cmp != 1 {
 			n = p
 			p = p.Left()
-		} else if cmp == 1 {
-			p = p.Right()
 		} else {
-			n = p
-			break
+			p = p.Right()
 		}
 	}
 	return n
 }
+
+// Pivots returns the keys of nodes in the range start < x < boundary in ascending order. Only the
+// nodes in the range are counted in depth, so it descends to the topmost node in the range and returns
+// the nodes in the top depth levels from there. The keys split the range into the sub-ranges by the shape
+// of tree, so they're balanced if the tree is balanced.
+func Pivots(root container.TreeNode, start container.Key, boundary container.Key, depth int) []container.Key {
+	var pivots []container.Key
+	var walk func(p container.TreeNode, depth int)
+	walk = func(p container.TreeNode, depth int) {
+		if depth == 0 || p == nil || reflect.ValueOf(p).IsNil() {
+			return
+		}
+		if start != nil && p.Key().Compare(start) != 1 {
+			// The left subtree is out of the range.
+			walk(p.Right(), depth)
+			return
+		}
+		if boundary != nil && p.Key().Compare(boundary) != -1 {
+			// The right subtree is out of the range.
+			walk(p.Left(), depth)
+			return
+		}
+		walk(p.Left(), depth-1)
+		pivots = append(pivots, p.Key())
+		walk(p.Right(), depth-1)
+	}
+	walk(root, depth)
+	return pivots
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package iterator implements the lazy combinators of container.Iterator.
+//
+// The combinators read the source iterators on demand, one element ahead of the consumer.
+// They work with the iterators in both ascending and descending order, such as the results
+// of Iter and IterReverse methods, unless the doc says otherwise.
+package iterator
+
+import (
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	_ container.Iterator = (*iterator)(nil)
+	_ container.Element  = (*element)(nil)
+)
+
+// element is the Element created by the combinators.
+type element struct {
+	key   container.Key
+	value container.Value
+}
+
+// Key returns the key that stored with this element.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value that stored with this element.
+func (e *element) Value() container.Value {
+	return e.value
+}
+
+// iterator is an Iterator that reads the elements from the function next until it returns nil.
+type iterator struct {
+	next func() container.Element
+	node container.Element
+}
+
+func newIterator(next func() container.Element) *iterator {
+	iter := &iterator{next: next}
+	iter.node = next()
+	return iter
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *iterator) Valid() bool {
+	return iter.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *iterator) Next() container.Element {
+	if iter.node == nil {
+		return nil
+	}
+	n := iter.node
+	iter.node = iter.next()
+	return n
+}
+
+// Returns the next element of it, nil if no more.
+func pull(it container.Iterator) container.Element {
+	if !it.Valid() {
+		return nil
+	}
+	return it.Next()
+}
+
+// Filter returns an Iterator that yields the elements of it that f returns true.
+func Filter(it container.Iterator, f func(ele container.Element) bool) container.Iterator {
+	return newIterator(func() container.Element {
+		for ele := pull(it); ele != nil; ele = pull(it) {
+			if f(ele) {
+				return ele
+			}
+		}
+		return nil
+	})
+}
+
+// Map returns an Iterator that yields the elements of it with the values replaced by f.
+// The keys are not changed, so the order of elements is kept.
+func Map(it container.Iterator, f func(ele container.Element) container.Value) container.Iterator {
+	return newIterator(func() container.Element {
+		ele := pull(it)
+		if ele == nil {
+			return nil
+		}
+		return &element{key: ele.Key(), value: f(ele)}
+	})
+}
+
+// TakeWhile returns an Iterator that yields the elements of it until f returns false.
+func TakeWhile(it container.Iterator, f func(ele container.Element) bool) container.Iterator {
+	return newIterator(func() container.Element {
+		ele := pull(it)
+		if ele == nil || !f(ele) {
+			return nil
+		}
+		return ele
+	})
+}
+
+// DropWhile returns an Iterator that skips the elements of it until f returns false,
+// and then yields the remaining elements.
+func DropWhile(it container.Iterator, f func(ele container.Element) bool) container.Iterator {
+	dropped := false
+	return newIterator(func() container.Element {
+		ele := pull(it)
+		for !dropped && ele != nil && f(ele) {
+			ele = pull(it)
+		}
+		dropped = true
+		return ele
+	})
+}
+
+// Take returns an Iterator that yields the first n elements of it at most.
+func Take(it container.Iterator, n int) container.Iterator {
+	return newIterator(func() container.Element {
+		if n <= 0 {
+			return nil
+		}
+		n--
+		return pull(it)
+	})
+}
+
+// Skip returns an Iterator that skips the first n elements of it, and then yields the remaining elements.
+func Skip(it container.Iterator, n int) container.Iterator {
+	return newIterator(func() container.Element {
+		for ; n > 0; n-- {
+			if pull(it) == nil {
+				return nil
+			}
+		}
+		return pull(it)
+	})
+}
+
+// Chain returns an Iterator that yields the elements of iterators one by one.
+// The result is not sorted if the ranges of iterators are overlapped, use the merge package instead.
+func Chain(iterators ...container.Iterator) container.Iterator {
+	return newIterator(func() container.Element {
+		for len(iterators) > 0 {
+			if ele := pull(iterators[0]); ele != nil {
+				return ele
+			}
+			iterators = iterators[1:]
+		}
+		return nil
+	})
+}
+
+// Pair is the value of elements yielded by Zip.
+type Pair struct {
+	// A is the value of key in the first iterator.
+	A container.Value
+	// B is the value of key in the second iterator.
+	B container.Value
+}
+
+// Zip returns an Iterator that yields the keys present in both a and b, the value of element is
+// a Pair of the values. The a and b must be in ascending order, use ZipReverse for the descending order.
+func Zip(a container.Iterator, b container.Iterator) container.Iterator {
+	return zip(a, b, 1)
+}
+
+// ZipReverse is similar to the Zip function, but the a and b must be in descending order.
+func ZipReverse(a container.Iterator, b container.Iterator) container.Iterator {
+	return zip(a, b, -1)
+}
+
+func zip(a container.Iterator, b container.Iterator, dir int) container.Iterator {
+	return newIterator(func() container.Element {
+		ea, eb := pull(a), pull(b)
+		for ea != nil && eb != nil {
+			switch ea.Key().Compare(eb.Key()) {
+			case 0:
+				return &element{key: ea.Key(), value: Pair{A: ea.Value(), B: eb.Value()}}
+			case dir:
+				eb = pull(b)
+			default:
+				ea = pull(a)
+			}
+		}
+		return nil
+	})
+}
+
+// Dedup returns an Iterator that yields the first element of each run of elements with the same key.
+// It's useful for the iterators of container.MultiContainer.
+func Dedup(it container.Iterator) container.Iterator {
+	var last container.Element
+	return newIterator(func() container.Element {
+		for ele := pull(it); ele != nil; ele = pull(it) {
+			if last == nil || ele.Key().Compare(last.Key()) != 0 {
+				last = ele
+				return ele
+			}
+		}
+		return nil
+	})
+}
+
+// Collect returns the remaining elements of it in a slice.
+func Collect(it container.Iterator) []container.Element {
+	var result []container.Element
+	for ele := pull(it); ele != nil; ele = pull(it) {
+		result = append(result, ele)
+	}
+	return result
+}
+
+// CollectInto inserts the remaining elements of it into ctr. Returns the number of elements read.
+// An element is ignored by Insert if its key already exists, unless the ctr allows duplicate keys.
+func CollectInto(it container.Iterator, ctr container.Container) int {
+	n := 0
+	for ele := pull(it); ele != nil; ele = pull(it) {
+		ctr.Insert(ele.Key(), ele.Value())
+		n++
+	}
+	return n
+}
@@ -0,0 +1,169 @@
+package iterator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/avl"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+func keys(it container.Iterator) []int {
+	var result []int
+	for _, ele := range Collect(it) {
+		result = append(result, int(ele.Key().(container.Int)))
+	}
+	return result
+}
+
+// Returns a tree with keys 0 to n-1 and values of the keys.
+func seq(n int) *rb.Tree {
+	tr := rb.New()
+	for i := 0; i < n; i++ {
+		tr.Insert(container.Int(i), i)
+	}
+	return tr
+}
+
+func isEven(ele container.Element) bool {
+	return ele.Value().(int)%2 == 0
+}
+
+func lessThan(n int) func(ele container.Element) bool {
+	return func(ele container.Element) bool {
+		return ele.Value().(int) < n
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tr := seq(10)
+	require.Equal(t, keys(Filter(tr.Iter(nil, nil), isEven)), []int{0, 2, 4, 6, 8})
+	require.Equal(t, keys(Filter(tr.IterReverse(nil, nil), isEven)), []int{8, 6, 4, 2, 0})
+	require.Nil(t, keys(Filter(tr.Iter(nil, nil), lessThan(0))))
+	require.Nil(t, keys(Filter(rb.New().Iter(nil, nil), isEven)))
+}
+
+func TestMap(t *testing.T) {
+	tr := seq(3)
+	it := Map(tr.IterReverse(nil, nil), func(ele container.Element) container.Value {
+		return ele.Value().(int) * 10
+	})
+	var values []container.Value
+	for it.Valid() {
+		values = append(values, it.Next().Value())
+	}
+	require.Nil(t, it.Next())
+	require.Equal(t, values, []container.Value{20, 10, 0})
+	// The source is not changed.
+	require.Equal(t, tr.Search(container.Int(1)).Value(), 1)
+}
+
+func TestTakeWhile_DropWhile(t *testing.T) {
+	tr := seq(10)
+	require.Equal(t, keys(TakeWhile(tr.Iter(nil, nil), lessThan(3))), []int{0, 1, 2})
+	require.Nil(t, keys(TakeWhile(tr.Iter(nil, nil), lessThan(0))))
+	require.Equal(t, keys(DropWhile(tr.Iter(nil, nil), lessThan(7))), []int{7, 8, 9})
+	require.Equal(t, keys(DropWhile(tr.Iter(nil, nil), lessThan(0))), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	require.Nil(t, keys(DropWhile(tr.Iter(nil, nil), lessThan(10))))
+
+	// Only the leading elements are dropped.
+	require.Equal(t, keys(DropWhile(tr.Iter(nil, nil), isEven)), []int{1, 2, 3, 4, 5, 6, 7, 8, 9})
+
+	// The first element failed the predicate is consumed, the rest are not read.
+	it := tr.Iter(nil, nil)
+	require.Equal(t, keys(TakeWhile(it, lessThan(3))), []int{0, 1, 2})
+	require.Equal(t, keys(it), []int{4, 5, 6, 7, 8, 9})
+}
+
+func TestTake_Skip(t *testing.T) {
+	tr := seq(10)
+	require.Equal(t, keys(Take(tr.Iter(nil, nil), 3)), []int{0, 1, 2})
+	require.Equal(t, keys(Take(tr.IterReverse(nil, nil), 3)), []int{9, 8, 7})
+	require.Nil(t, keys(Take(tr.Iter(nil, nil), 0)))
+	require.Equal(t, len(keys(Take(tr.Iter(nil, nil), 20))), 10)
+
+	require.Equal(t, keys(Skip(tr.Iter(nil, nil), 7)), []int{7, 8, 9})
+	require.Equal(t, keys(Skip(tr.Iter(nil, nil), 0)), keys(tr.Iter(nil, nil)))
+	require.Nil(t, keys(Skip(tr.Iter(nil, nil), 20)))
+
+	// Pagination.
+	require.Equal(t, keys(Take(Skip(tr.Iter(nil, nil), 4), 3)), []int{4, 5, 6})
+
+	// Take does not read more elements than n.
+	it := tr.Iter(nil, nil)
+	require.Equal(t, keys(Take(it, 2)), []int{0, 1})
+	require.Equal(t, keys(Take(it, 2)), []int{2, 3})
+}
+
+func TestChain(t *testing.T) {
+	tr := seq(10)
+	it := Chain(tr.Iter(nil, container.Int(2)), rb.New().Iter(nil, nil), tr.IterReverse(container.Int(7), nil))
+	require.Equal(t, keys(it), []int{0, 1, 9, 8, 7})
+	require.Nil(t, keys(Chain()))
+}
+
+func TestZip(t *testing.T) {
+	a, b := avl.New(), skip.New()
+	for i := 0; i < 20; i++ {
+		if i%2 == 0 {
+			a.Insert(container.Int(i), i)
+		}
+		if i%3 == 0 {
+			b.Insert(container.Int(i), -i)
+		}
+	}
+
+	it := Zip(a.Iter(nil, nil), b.Iter(nil, nil))
+	var result []interface{}
+	for it.Valid() {
+		ele := it.Next()
+		result = append(result, ele.Key(), ele.Value())
+	}
+	require.Equal(t, result, []interface{}{
+		container.Int(0), Pair{A: 0, B: 0},
+		container.Int(6), Pair{A: 6, B: -6},
+		container.Int(12), Pair{A: 12, B: -12},
+		container.Int(18), Pair{A: 18, B: -18},
+	})
+
+	require.Equal(t, keys(ZipReverse(a.IterReverse(nil, nil), b.IterReverse(nil, nil))), []int{18, 12, 6, 0})
+	require.Nil(t, keys(Zip(a.Iter(nil, nil), rb.New().Iter(nil, nil))))
+}
+
+func TestDedup(t *testing.T) {
+	m := rb.NewMulti()
+	for i := 0; i < 5; i++ {
+		for j := 0; j <= i; j++ {
+			m.Insert(container.Int(i), j)
+		}
+	}
+	require.Equal(t, keys(Dedup(m.Iter(nil, nil))), []int{0, 1, 2, 3, 4})
+	require.Equal(t, keys(Dedup(m.IterReverse(nil, nil))), []int{4, 3, 2, 1, 0})
+
+	// The first element of each key is yielded.
+	for _, ele := range Collect(Dedup(m.Iter(nil, nil))) {
+		require.Equal(t, ele.Value(), 0)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	tr := seq(10)
+	elements := Collect(Filter(tr.Iter(nil, nil), isEven))
+	require.Equal(t, len(elements), 5)
+	for i, ele := range elements {
+		require.Equal(t, ele.Key(), container.Int(i*2))
+		require.Equal(t, ele.Value(), i*2)
+	}
+	require.Nil(t, Collect(rb.New().Iter(nil, nil)))
+
+	ctr := skip.New()
+	ctr.Insert(container.Int(4), -4)
+	require.Equal(t, CollectInto(Filter(tr.IterReverse(nil, nil), isEven), ctr), 5)
+	require.Equal(t, ctr.Len(), 5)
+	// The existing key is not replaced.
+	require.Equal(t, ctr.Search(container.Int(4)).Value(), -4)
+	require.Equal(t, ctr.Search(container.Int(8)).Value(), 8)
+}
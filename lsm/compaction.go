@@ -0,0 +1,294 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package lsm
+
+import (
+	"bufio"
+	"os"
+	"sort"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/internal/record"
+	"github.com/yu31/structs-go/skip"
+	"github.com/yu31/structs-go/sstable"
+)
+
+// Writes the memtable to a file in level 0, switches to a new log and compacts the levels if needed.
+func (db *DB) flush() error {
+	if db.mem.Len() == 0 {
+		return nil
+	}
+
+	tables, err := db.writeTables(db.mem.Iter(nil, nil), false, false)
+	if err != nil {
+		return err
+	}
+
+	logNum := db.allocNum()
+	log, err := os.Create(fileName(db.dir, logNum, logExt))
+	if err != nil {
+		db.removeTables(tables)
+		return err
+	}
+
+	levels := db.levels
+	levels[0] = append(append([]*table(nil), tables...), levels[0]...)
+	if err := db.saveManifest(logNum, &levels); err != nil {
+		log.Close()
+		os.Remove(fileName(db.dir, logNum, logExt))
+		db.removeTables(tables)
+		return err
+	}
+
+	db.log.Close()
+	os.Remove(fileName(db.dir, db.logNum, logExt))
+	db.log = log
+	db.logw = record.NewWriter(log)
+	db.logNum = logNum
+	db.levels = levels
+	db.mem = skip.New()
+	db.memMax = nil
+	db.memSize = 0
+
+	return db.compact()
+}
+
+// Compacts the levels until all levels are within their limits.
+func (db *DB) compact() error {
+	for {
+		level, inputs := db.pickCompaction()
+		if inputs == nil {
+			return nil
+		}
+		if err := db.compactLevel(level, inputs); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns the level and the files to compact into the next level, nil if no compaction needed.
+func (db *DB) pickCompaction() (int, []*table) {
+	if len(db.levels[0]) >= db.opts.L0Trigger {
+		return 0, db.levels[0]
+	}
+
+	limit := db.opts.BaseLevelSize
+	for level := 1; level < numLevels-1; level++ {
+		var size int64
+		for _, t := range db.levels[level] {
+			size += t.size
+		}
+		if size > limit {
+			// Picks the files in turn by the key, so that the whole key space is compacted evenly.
+			tables := db.levels[level]
+			i := 0
+			if p := db.pointers[level]; p != nil {
+				i = sort.Search(len(tables), func(i int) bool {
+					return tables[i].smallest.Compare(p) == 1
+				})
+				if i == len(tables) {
+					i = 0
+				}
+			}
+			return level, tables[i : i+1]
+		}
+		limit *= int64(db.opts.LevelMultiplier)
+	}
+	return 0, nil
+}
+
+// Merges the inputs with the overlapping files in the next level, and replaces them with the outputs.
+func (db *DB) compactLevel(level int, inputs []*table) error {
+	smallest, largest := inputs[0].smallest, inputs[0].largest
+	for _, t := range inputs[1:] {
+		if t.smallest.Compare(smallest) == -1 {
+			smallest = t.smallest
+		}
+		if t.largest.Compare(largest) == 1 {
+			largest = t.largest
+		}
+	}
+
+	var overlaps []*table
+	for _, t := range db.levels[level+1] {
+		if t.overlapsInclusive(smallest, largest) {
+			overlaps = append(overlaps, t)
+		}
+	}
+
+	// The inputs of level 0 are ordered by newest first, and they are newer than the next level.
+	var sources []container.Iterator
+	for _, t := range inputs {
+		sources = append(sources, t.rd.Iter(nil, nil))
+	}
+	if len(overlaps) != 0 {
+		sources = append(sources, newLevelIterator(overlaps, nil, nil, false))
+	}
+
+	// The tombstones can be dropped if no older files contain the keys.
+	// The tombstones come from both inputs and overlaps, so the range of overlaps is included.
+	lo, hi := smallest, largest
+	for _, t := range overlaps {
+		if t.smallest.Compare(lo) == -1 {
+			lo = t.smallest
+		}
+		if t.largest.Compare(hi) == 1 {
+			hi = t.largest
+		}
+	}
+	drop := true
+	for l := level + 2; l < numLevels && drop; l++ {
+		for _, t := range db.levels[l] {
+			if t.overlapsInclusive(lo, hi) {
+				drop = false
+				break
+			}
+		}
+	}
+
+	outputs, err := db.writeTables(newMergingIterator(sources, false), drop, true)
+	if err != nil {
+		return err
+	}
+
+	levels := db.levels
+	levels[level] = excludeTables(levels[level], inputs)
+	next := append(excludeTables(levels[level+1], overlaps), outputs...)
+	sort.Slice(next, func(i, j int) bool {
+		return next[i].smallest.Compare(next[j].smallest) == -1
+	})
+	levels[level+1] = next
+
+	if err := db.saveManifest(db.logNum, &levels); err != nil {
+		db.removeTables(outputs)
+		return err
+	}
+	db.levels = levels
+	db.pointers[level] = largest
+	// Each file in inputs and overlaps is removed from the levels.
+	db.removeTables(inputs)
+	db.removeTables(overlaps)
+	return nil
+}
+
+// Writes the elements to new files. If split is true, a new file is started when the current file
+// reaches the TableSize. If drop is true, the tombstones are dropped.
+func (db *DB) writeTables(it container.Iterator, drop bool, split bool) ([]*table, error) {
+	var tables []*table
+	var f *os.File
+	var bw *bufio.Writer
+	var w *sstable.Writer
+	var num uint64
+
+	finish := func() error {
+		err := w.Close()
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err == nil {
+			err = f.Sync()
+		}
+		if e := f.Close(); err == nil {
+			err = e
+		}
+		w = nil
+		if err != nil {
+			os.Remove(fileName(db.dir, num, tableExt))
+			return err
+		}
+		t, err := openTable(db.dir, num, db.tblOpts)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, t)
+		return nil
+	}
+
+	for it.Valid() {
+		ele := it.Next()
+		if _, ok := ele.Value().(tombstone); ok && drop {
+			continue
+		}
+		if w == nil {
+			num = db.allocNum()
+			var err error
+			if f, err = os.Create(fileName(db.dir, num, tableExt)); err != nil {
+				db.removeTables(tables)
+				return nil, err
+			}
+			bw = bufio.NewWriter(f)
+			w = sstable.NewWriter(bw, db.tblOpts)
+		}
+		if err := w.Add(ele.Key(), ele.Value()); err != nil {
+			f.Close()
+			os.Remove(fileName(db.dir, num, tableExt))
+			db.removeTables(tables)
+			return nil, err
+		}
+		if split && w.Size() >= db.opts.TableSize {
+			if err := finish(); err != nil {
+				db.removeTables(tables)
+				return nil, err
+			}
+		}
+	}
+	if w != nil {
+		if err := finish(); err != nil {
+			db.removeTables(tables)
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// Saves the manifest with the given log and levels.
+func (db *DB) saveManifest(logNum uint64, levels *[numLevels][]*table) error {
+	m := &manifest{
+		NextFile: db.nextNum,
+		Log:      logNum,
+		Levels:   make([][]uint64, numLevels),
+	}
+	for level, tables := range levels {
+		m.Levels[level] = make([]uint64, 0, len(tables))
+		for _, t := range tables {
+			m.Levels[level] = append(m.Levels[level], t.num)
+		}
+	}
+	return writeManifest(db.dir, m)
+}
+
+// Allocates a new file number.
+func (db *DB) allocNum() uint64 {
+	num := db.nextNum
+	db.nextNum++
+	return num
+}
+
+// Closes and removes the files of tables.
+func (db *DB) removeTables(tables []*table) {
+	for _, t := range tables {
+		t.file.Close()
+		os.Remove(fileName(db.dir, t.num, tableExt))
+	}
+}
+
+// Returns the tables that not in excludes.
+func excludeTables(tables []*table, excludes []*table) []*table {
+	var result []*table
+	for _, t := range tables {
+		excluded := false
+		for _, e := range excludes {
+			if t == e {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			result = append(result, t)
+		}
+	}
+	return result
+}
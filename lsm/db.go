@@ -0,0 +1,645 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package lsm implements an embedded ordered key-value store based on the log-structured merge-tree.
+//
+// The writes are appended to a write-ahead log and applied to an in-memory skip.List, called the memtable.
+// When the memtable is full, it's flushed to an immutable sstable file in level 0. The files in level 0
+// may overlap each other, and they are compacted with the overlapping files in level 1 when there are too many.
+// The files in each level from 1 are not overlapping, and a file is compacted into the next level when the
+// total size of level exceeds its limit. The deleted keys are kept as tombstones until they are compacted
+// into the last level that contains the key.
+//
+// A read merges the memtable and the files of all levels, the newer value of a key shadows the older.
+package lsm
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/yu31/structs-go/codec"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/internal/record"
+	"github.com/yu31/structs-go/skip"
+	"github.com/yu31/structs-go/sstable"
+)
+
+var (
+	_ container.Retriever = (*DB)(nil)
+	_ container.Searcher  = (*DB)(nil)
+	_ container.Element   = (*element)(nil)
+)
+
+var (
+	// ErrClosed is returned when writing to a closed DB.
+	ErrClosed = errors.New("lsm: db is closed")
+	// ErrCorrupted is returned when the log is corrupted.
+	ErrCorrupted = errors.New("lsm: corrupted log")
+)
+
+// The number of levels.
+const numLevels = 7
+
+// The operations in the log.
+const (
+	opPut byte = iota + 1
+	opDelete
+)
+
+// tombstone is the value of a deleted key in the memtable and files.
+type tombstone struct{}
+
+const tombstoneName = "lsm.tombstone"
+
+// element is a snapshot of an element in the DB.
+type element struct {
+	key   container.Key
+	value container.Value
+}
+
+// Key returns the key.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value.
+func (e *element) Value() container.Value {
+	return e.value
+}
+
+// Options is the options of DB.
+type Options struct {
+	// Registry is used to encode and decode the keys and values, default codec.Default.
+	Registry *codec.Registry
+	// MemtableSize is the approximate size of memtable in bytes before flushed, default 4 MiB.
+	MemtableSize int
+	// TableSize is the approximate size of files written by compaction, default 2 MiB.
+	TableSize int64
+	// BlockSize is the size of data blocks in files, see sstable.Options.
+	BlockSize int
+	// Compression is the compression algorithm of blocks in files.
+	Compression sstable.Compression
+	// L0Trigger is the number of files in level 0 to trigger a compaction, default 4.
+	L0Trigger int
+	// BaseLevelSize is the limit of total size of level 1, default 10 MiB.
+	BaseLevelSize int64
+	// LevelMultiplier is the ratio of size limits between adjacent levels, default 10.
+	LevelMultiplier int
+	// SyncWrites represents whether to sync the log after each write.
+	// If false, the recent writes may be lost on machine crash but not on process crash.
+	SyncWrites bool
+}
+
+// Returns a copy of opts with defaults filled.
+func copyOptions(opts *Options) *Options {
+	o := &Options{}
+	if opts != nil {
+		*o = *opts
+	}
+	if o.Registry == nil {
+		o.Registry = codec.Default
+	}
+	if o.MemtableSize <= 0 {
+		o.MemtableSize = 4 << 20
+	}
+	if o.TableSize <= 0 {
+		o.TableSize = 2 << 20
+	}
+	if o.L0Trigger <= 0 {
+		o.L0Trigger = 4
+	}
+	if o.BaseLevelSize <= 0 {
+		o.BaseLevelSize = 10 << 20
+	}
+	if o.LevelMultiplier <= 1 {
+		o.LevelMultiplier = 10
+	}
+	return o
+}
+
+// DB is an embedded ordered key-value store in a local directory.
+//
+// The keys and values must be registered in the codec.Registry of Options.
+// The reads are done under a read lock and the writes are done under a write lock, so a DB is safe
+// for concurrent use by multiple goroutines. But the Iterators returned by Iter and IterReverse are
+// invalidated by the subsequent writes like the iterators of other containers.
+//
+// The methods of Retriever and Searcher can not return errors, so an I/O error or a corrupted
+// file is treated as no more elements, and it's recorded to be returned by the Err method.
+type DB struct {
+	dir      string
+	opts     *Options
+	registry *codec.Registry // The registry of options with tombstone.
+	tblOpts  *sstable.Options
+
+	mu       sync.RWMutex
+	mem      *skip.List
+	memMax   container.Key // The largest key in memtable.
+	memSize  int
+	log      *os.File
+	logw     *record.Writer
+	logNum   uint64
+	nextNum  uint64
+	levels   [numLevels][]*table // The level 0 is ordered by newest first, others are ordered by key.
+	pointers [numLevels]container.Key
+	err      error // The first write error, the DB is read-only after that.
+	closed   bool
+	buf      []byte
+}
+
+// Open opens the DB in dir, it's created if not exists. The default options is used if opts is nil.
+// The log is replayed to recover the memtable, a torn record at the end of log is discarded.
+func Open(dir string, opts *Options) (*DB, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	db := &DB{
+		dir:  dir,
+		opts: copyOptions(opts),
+		mem:  skip.New(),
+	}
+	db.registry = db.opts.Registry.Clone()
+	if err := db.registry.Register(tombstoneName, tombstone{}, codec.Codec{
+		Encode: func(buf []byte, v interface{}) ([]byte, error) {
+			return buf, nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			return tombstone{}, nil
+		},
+	}); err != nil {
+		return nil, err
+	}
+	db.tblOpts = &sstable.Options{
+		BlockSize:   db.opts.BlockSize,
+		Compression: db.opts.Compression,
+		Registry:    db.registry,
+	}
+
+	m, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.recover(m); err != nil {
+		db.closeFiles()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Recovers the DB from manifest, a new DB is created if the manifest is nil.
+func (db *DB) recover(m *manifest) error {
+	if m == nil {
+		m = &manifest{NextFile: 2, Log: 1}
+		f, err := os.Create(fileName(db.dir, m.Log, logExt))
+		if err != nil {
+			return err
+		}
+		f.Close()
+		if err := writeManifest(db.dir, m); err != nil {
+			return err
+		}
+	}
+	db.nextNum = m.NextFile
+	db.logNum = m.Log
+
+	for level, nums := range m.Levels {
+		if level >= numLevels {
+			return errors.New("lsm: corrupted manifest: too many levels")
+		}
+		for _, num := range nums {
+			t, err := openTable(db.dir, num, db.tblOpts)
+			if err != nil {
+				return err
+			}
+			db.levels[level] = append(db.levels[level], t)
+		}
+	}
+
+	if err := db.replay(); err != nil {
+		return err
+	}
+	db.removeObsolete()
+
+	if db.memSize >= db.opts.MemtableSize {
+		return db.flush()
+	}
+	return nil
+}
+
+// Replays the log into memtable, and opens it for appending.
+func (db *DB) replay() error {
+	name := fileName(db.dir, db.logNum, logExt)
+	f, err := os.OpenFile(name, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r := record.NewReader(f, fi.Size())
+	for {
+		payload, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == record.ErrTorn {
+			// Discards the torn record, so that the new records are appended after the valid records.
+			if err := f.Truncate(r.Offset()); err != nil {
+				f.Close()
+				return err
+			}
+			break
+		}
+		if err == record.ErrCorrupted {
+			err = ErrCorrupted
+		}
+		if err == nil {
+			err = db.apply(payload)
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+		db.memSize += len(payload) + record.HeaderSize
+	}
+
+	if _, err := f.Seek(r.Offset(), io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	db.log = f
+	db.logw = record.NewWriter(f)
+	return nil
+}
+
+// Applies a log record to memtable.
+func (db *DB) apply(payload []byte) error {
+	if len(payload) == 0 {
+		return ErrCorrupted
+	}
+	op, data := payload[0], payload[1:]
+	k, n, err := db.registry.ReadValue(data)
+	if err != nil {
+		return err
+	}
+	key, ok := k.(container.Key)
+	if !ok {
+		return ErrCorrupted
+	}
+	switch op {
+	case opPut:
+		v, _, err := db.registry.ReadValue(data[n:])
+		if err != nil {
+			return err
+		}
+		db.applyMem(key, v)
+	case opDelete:
+		db.applyMem(key, tombstone{})
+	default:
+		return ErrCorrupted
+	}
+	return nil
+}
+
+func (db *DB) applyMem(k container.Key, v container.Value) {
+	db.mem.Upsert(k, v)
+	if db.memMax == nil || k.Compare(db.memMax) == 1 {
+		db.memMax = k
+	}
+}
+
+// Removes the files that not referenced, they are left by a crash during flush or compaction.
+func (db *DB) removeObsolete() {
+	live := make(map[uint64]bool)
+	for _, tables := range db.levels {
+		for _, t := range tables {
+			live[t.num] = true
+		}
+	}
+	entries, err := os.ReadDir(db.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		num, ext, ok := parseFileName(entry.Name())
+		if !ok {
+			continue
+		}
+		if (ext == tableExt && !live[num]) || (ext == logExt && num != db.logNum) {
+			os.Remove(fileName(db.dir, num, ext))
+		}
+	}
+}
+
+// Put sets the value of key.
+func (db *DB) Put(k container.Key, v container.Value) error {
+	return db.write(opPut, k, v)
+}
+
+// Delete removes the key, it does nothing if the key not found.
+func (db *DB) Delete(k container.Key) error {
+	return db.write(opDelete, k, nil)
+}
+
+func (db *DB) write(op byte, k container.Key, v container.Value) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	if db.err != nil {
+		return db.err
+	}
+
+	var err error
+	db.buf = append(db.buf[:0], op)
+	if db.buf, err = db.registry.AppendValue(db.buf, k); err != nil {
+		return err
+	}
+	if op == opPut {
+		if db.buf, err = db.registry.AppendValue(db.buf, v); err != nil {
+			return err
+		}
+	}
+
+	n, err := db.logw.Write(db.buf)
+	if err == nil && db.opts.SyncWrites {
+		err = db.log.Sync()
+	}
+	if err != nil {
+		db.err = err
+		return err
+	}
+
+	if op == opPut {
+		db.applyMem(k, v)
+	} else {
+		db.applyMem(k, tombstone{})
+	}
+	db.memSize += n
+
+	if db.memSize >= db.opts.MemtableSize {
+		if err := db.flush(); err != nil {
+			db.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes the memtable to a file in level 0 and starts a new log.
+func (db *DB) Flush() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	if db.err != nil {
+		return db.err
+	}
+	if err := db.flush(); err != nil {
+		db.err = err
+		return err
+	}
+	return nil
+}
+
+// Get returns the value of key. The bool result is false if key not found.
+func (db *DB) Get(k container.Key) (container.Value, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ele := db.get(k)
+	if ele == nil {
+		return nil, false
+	}
+	return ele.Value(), true
+}
+
+// Returns the element of key, nil if not found or deleted.
+func (db *DB) get(k container.Key) container.Element {
+	ele := db.mem.Search(k)
+	if ele == nil {
+		ele = db.getTables(k)
+	}
+	if ele == nil {
+		return nil
+	}
+	if _, ok := ele.Value().(tombstone); ok {
+		return nil
+	}
+	return &element{key: ele.Key(), value: ele.Value()}
+}
+
+// Searches the key in files from the newest to the oldest.
+func (db *DB) getTables(k container.Key) container.Element {
+	for _, t := range db.levels[0] {
+		if ele := t.get(k); ele != nil {
+			return ele
+		}
+	}
+	for level := 1; level < numLevels; level++ {
+		tables := db.levels[level]
+		i := sort.Search(len(tables), func(i int) bool {
+			return tables[i].largest.Compare(k) != -1
+		})
+		if i == len(tables) {
+			continue
+		}
+		if ele := tables[i].get(k); ele != nil {
+			return ele
+		}
+	}
+	return nil
+}
+
+// Err returns the first write error, or the first error encountered when reading files.
+func (db *DB) Err() error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.err != nil {
+		return db.err
+	}
+	for _, tables := range db.levels {
+		for _, t := range tables {
+			if err := t.rd.Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes the files. The memtable is not flushed, it will be recovered from the log.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	db.closed = true
+	return db.closeFiles()
+}
+
+func (db *DB) closeFiles() error {
+	var err error
+	if db.log != nil {
+		err = db.log.Close()
+	}
+	for _, tables := range db.levels {
+		for _, t := range tables {
+			if e := t.file.Close(); err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Iter return an Iterator include element of range start <= x < boundary.
+// The elements will return from the beginning if start is nil,
+// And return until the end if the boundary is nil.
+func (db *DB) Iter(start container.Key, boundary container.Key) container.Iterator {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.iter(start, boundary, false)
+}
+
+// IterReverse return a reversed Iterator include element of range start <= x < boundary.
+// The elements will return from the end if boundary is nil,
+// And return until the beginning if start is nil.
+func (db *DB) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.iter(start, boundary, true)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (db *DB) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(db)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (db *DB) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(db)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (db *DB) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(db, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (db *DB) Keys() iter.Seq[container.Key] {
+	return container.Keys(db)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (db *DB) Values() iter.Seq[container.Value] {
+	return container.Values(db)
+}
+
+// Range calls f sequentially each element present in the DB.
+// If f returns false, range stops the iteration.
+//
+// The f is called under a read lock, so it must not write to the DB.
+func (db *DB) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	it := db.iter(start, boundary, false)
+	for it.Valid() {
+		if !f(it.Next()) {
+			return
+		}
+	}
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+func (db *DB) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	it := db.iter(start, boundary, true)
+	for it.Valid() {
+		if !f(it.Next()) {
+			return
+		}
+	}
+}
+
+// LastLT searches for the last element that less than the key.
+func (db *DB) LastLT(k container.Key) container.Element {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.iter(nil, k, true).Next()
+}
+
+// LastLE search for the last element that less than or equal to the key.
+func (db *DB) LastLE(k container.Key) container.Element {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if ele := db.get(k); ele != nil {
+		return ele
+	}
+	return db.iter(nil, k, true).Next()
+}
+
+// FirstGT search for the first element that greater than to the key.
+func (db *DB) FirstGT(k container.Key) container.Element {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	it := db.iter(k, nil, false)
+	ele := it.Next()
+	if ele != nil && ele.Key().Compare(k) == 0 {
+		ele = it.Next()
+	}
+	return ele
+}
+
+// FirstGE search for the first element that greater than or equal to the key.
+func (db *DB) FirstGE(k container.Key) container.Element {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.iter(k, nil, false).Next()
+}
+
+// Creates an Iterator that merges the memtable and files.
+func (db *DB) iter(start container.Key, boundary container.Key, reverse bool) *Iterator {
+	var sources []container.Iterator
+	if reverse {
+		sources = append(sources, newMemReverseIterator(db.mem, db.memMax, start, boundary))
+	} else {
+		sources = append(sources, db.mem.Iter(start, boundary))
+	}
+	for _, t := range db.levels[0] {
+		if t.overlaps(start, boundary) {
+			sources = append(sources, newLevelIterator([]*table{t}, start, boundary, reverse))
+		}
+	}
+	for level := 1; level < numLevels; level++ {
+		var tables []*table
+		for _, t := range db.levels[level] {
+			if t.overlaps(start, boundary) {
+				tables = append(tables, t)
+			}
+		}
+		if len(tables) == 0 {
+			continue
+		}
+		if reverse {
+			for i, j := 0, len(tables)-1; i < j; i, j = i+1, j-1 {
+				tables[i], tables[j] = tables[j], tables[i]
+			}
+		}
+		sources = append(sources, newLevelIterator(tables, start, boundary, reverse))
+	}
+	return newDBIterator(newMergingIterator(sources, reverse))
+}
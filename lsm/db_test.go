@@ -0,0 +1,242 @@
+package lsm
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/tests"
+)
+
+// The options to trigger flushes and compactions frequently.
+var testOptions = &Options{
+	MemtableSize:    1 << 10,
+	TableSize:       1 << 10,
+	BlockSize:       128,
+	L0Trigger:       2,
+	BaseLevelSize:   4 << 10,
+	LevelMultiplier: 2,
+}
+
+// Checks the DB has the same content as the model.
+func checkDB(t *testing.T, r *rand.Rand, db *DB, model *rb.Tree) {
+	require.Equal(t, tests.Pairs(db, nil, nil, false), tests.Pairs(model, nil, nil, false))
+	require.Equal(t, tests.Pairs(db, nil, nil, true), tests.Pairs(model, nil, nil, true))
+
+	for i := 0; i < 50; i++ {
+		k := container.Int(r.Intn(600) - 50)
+		v, ok := db.Get(k)
+		ele := model.Search(k)
+		require.Equal(t, ok, ele != nil)
+		if ok {
+			require.Equal(t, v, ele.Value())
+		}
+
+		require.Equal(t, tests.ElementPair(db.LastLT(k)), tests.ElementPair(model.LastLT(k)))
+		require.Equal(t, tests.ElementPair(db.LastLE(k)), tests.ElementPair(model.LastLE(k)))
+		require.Equal(t, tests.ElementPair(db.FirstGT(k)), tests.ElementPair(model.FirstGT(k)))
+		require.Equal(t, tests.ElementPair(db.FirstGE(k)), tests.ElementPair(model.FirstGE(k)))
+
+		boundary := k + container.Int(r.Intn(100))
+		require.Equal(t, tests.Pairs(db, k, boundary, false), tests.Pairs(model, k, boundary, false))
+		require.Equal(t, tests.Pairs(db, k, boundary, true), tests.Pairs(model, k, boundary, true))
+		require.Equal(t, tests.IterPairs(db.Iter(k, boundary)), tests.IterPairs(model.Iter(k, boundary)))
+		require.Equal(t, tests.IterPairs(db.IterReverse(k, nil)), tests.IterPairs(model.IterReverse(k, nil)))
+	}
+	require.Nil(t, db.Err())
+}
+
+func TestDB(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	dir := t.TempDir()
+
+	db, err := Open(dir, testOptions)
+	require.Nil(t, err)
+	model := rb.New()
+
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 2000; i++ {
+			k := container.Int(r.Intn(500))
+			if r.Intn(3) == 0 {
+				require.Nil(t, db.Delete(k))
+				model.Delete(k)
+			} else {
+				v := r.Intn(1000)
+				require.Nil(t, db.Put(k, v))
+				model.Upsert(k, v)
+			}
+		}
+		checkDB(t, r, db, model)
+
+		// Recovers from the log and files.
+		require.Nil(t, db.Close())
+		require.Equal(t, db.Close(), ErrClosed)
+		require.Equal(t, db.Put(container.Int(0), 0), ErrClosed)
+		db, err = Open(dir, testOptions)
+		require.Nil(t, err)
+		checkDB(t, r, db, model)
+	}
+
+	// The compaction moved the files to deeper levels.
+	n := 0
+	for level := 1; level < numLevels; level++ {
+		n += len(db.levels[level])
+	}
+	require.Greater(t, n, 0)
+	require.Less(t, len(db.levels[0]), testOptions.L0Trigger)
+	for level := 1; level < numLevels; level++ {
+		tables := db.levels[level]
+		for i := 1; i < len(tables); i++ {
+			require.Equal(t, tables[i-1].largest.Compare(tables[i].smallest), -1)
+		}
+	}
+
+	// Only the live files are left.
+	require.Nil(t, db.Close())
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	live := 1 + 1 // The manifest and log.
+	for _, tables := range db.levels {
+		live += len(tables)
+	}
+	require.Equal(t, len(entries), live)
+}
+
+func TestDB_DeleteAll(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	db, err := Open(t.TempDir(), testOptions)
+	require.Nil(t, err)
+	defer db.Close()
+
+	for i := 0; i < 500; i++ {
+		require.Nil(t, db.Put(container.Int(i), i))
+	}
+	for i := 0; i < 500; i++ {
+		require.Nil(t, db.Delete(container.Int(i)))
+	}
+	require.Nil(t, db.Flush())
+	checkDB(t, r, db, rb.New())
+}
+
+func TestDB_DropTombstone(t *testing.T) {
+	// The compactions are triggered manually.
+	db, err := Open(t.TempDir(), &Options{L0Trigger: 100, BaseLevelSize: 1 << 30})
+	require.Nil(t, err)
+	defer db.Close()
+
+	compact := func(level int) {
+		require.Nil(t, db.Flush())
+		require.Nil(t, db.compactLevel(level, db.levels[level]))
+	}
+
+	// The key 25 is pushed to level 3.
+	require.Nil(t, db.Put(container.Int(25), "old"))
+	for level := 0; level < 3; level++ {
+		compact(level)
+	}
+
+	// The tombstone of 25 is pushed to level 2, in a file with range [15, 30].
+	require.Nil(t, db.Put(container.Int(15), 15))
+	require.Nil(t, db.Put(container.Int(30), 30))
+	require.Nil(t, db.Delete(container.Int(25)))
+	compact(0)
+	compact(1)
+
+	// The inputs [10, 20] of level 1 are merged with the file [15, 30] in level 2, the tombstone
+	// of 25 is out of the range of inputs, but must be kept since the level 3 has the key.
+	require.Nil(t, db.Put(container.Int(10), 10))
+	require.Nil(t, db.Put(container.Int(20), 20))
+	compact(0)
+	compact(1)
+	require.Equal(t, len(db.levels[3]), 1)
+
+	_, ok := db.Get(container.Int(25))
+	require.False(t, ok)
+	require.Equal(t, db.FirstGT(container.Int(20)).Key(), container.Int(30))
+}
+
+func TestDB_TornLog(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(dir, nil)
+	require.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		require.Nil(t, db.Put(container.Int(i), "value"))
+	}
+	logNum := db.logNum
+	require.Nil(t, db.Close())
+
+	// Cuts the last record in half.
+	name := fileName(dir, logNum, logExt)
+	fi, err := os.Stat(name)
+	require.Nil(t, err)
+	require.Nil(t, os.Truncate(name, fi.Size()-3))
+
+	db, err = Open(dir, nil)
+	require.Nil(t, err)
+	_, ok := db.Get(container.Int(9))
+	require.False(t, ok)
+	_, ok = db.Get(container.Int(8))
+	require.True(t, ok)
+
+	// The new records are appended after the valid records.
+	require.Nil(t, db.Put(container.Int(10), "value"))
+	require.Nil(t, db.Close())
+	db, err = Open(dir, nil)
+	require.Nil(t, err)
+	_, ok = db.Get(container.Int(10))
+	require.True(t, ok)
+	require.Nil(t, db.Close())
+
+	// A corrupted record in the middle fails the Open.
+	data, err := os.ReadFile(name)
+	require.Nil(t, err)
+	data[10] ^= 0xff
+	require.Nil(t, os.WriteFile(name, data, 0644))
+	_, err = Open(dir, nil)
+	require.Equal(t, err, ErrCorrupted)
+
+	// A broken length in the middle fails the Open, and the log is not truncated.
+	data[10] ^= 0xff
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)))
+	require.Nil(t, os.WriteFile(name, data, 0644))
+	_, err = Open(dir, nil)
+	require.Equal(t, err, ErrCorrupted)
+	fi, err = os.Stat(name)
+	require.Nil(t, err)
+	require.Equal(t, fi.Size(), int64(len(data)))
+}
+
+func TestDB_Obsolete(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(dir, testOptions)
+	require.Nil(t, err)
+	require.Nil(t, db.Put(container.Int(1), 1))
+	require.Nil(t, db.Flush())
+	require.Nil(t, db.Close())
+
+	// The files left by a crash are removed.
+	for _, name := range []string{"000100.sst", "000101.log"} {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644))
+	}
+	db, err = Open(dir, testOptions)
+	require.Nil(t, err)
+	defer db.Close()
+	for _, name := range []string{"000100.sst", "000101.log"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		require.True(t, os.IsNotExist(err))
+	}
+	v, ok := db.Get(container.Int(1))
+	require.True(t, ok)
+	require.Equal(t, v, 1)
+
+	// Unregistered types are rejected.
+	require.NotNil(t, db.Put(container.Int(2), struct{}{}))
+	require.Nil(t, db.Err())
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package lsm
+
+import (
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/skip"
+)
+
+var (
+	_ container.Iterator = (*Iterator)(nil)
+	_ container.Iterator = (*mergingIterator)(nil)
+	_ container.Iterator = (*levelIterator)(nil)
+	_ container.Iterator = (*memReverseIterator)(nil)
+)
+
+// Iterator is the Iterator of DB, it yields the latest value of each key and skips the deleted keys.
+type Iterator struct {
+	it   *mergingIterator
+	node container.Element
+}
+
+func newDBIterator(it *mergingIterator) *Iterator {
+	iter := &Iterator{it: it}
+	iter.advance()
+	return iter
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *Iterator) Valid() bool {
+	return iter.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *Iterator) Next() container.Element {
+	if iter.node == nil {
+		return nil
+	}
+	n := iter.node
+	iter.advance()
+	return n
+}
+
+func (iter *Iterator) advance() {
+	iter.node = nil
+	for iter.it.Valid() {
+		ele := iter.it.Next()
+		if _, ok := ele.Value().(tombstone); !ok {
+			iter.node = &element{key: ele.Key(), value: ele.Value()}
+			return
+		}
+	}
+}
+
+// mergingIterator merges the sorted sources. For the duplicate keys, only the element from the source
+// with the smallest index is yielded, so the sources must be ordered from newest to oldest.
+type mergingIterator struct {
+	sources []container.Iterator
+	heads   []container.Element
+	dir     int // 1 for ascending, -1 for descending.
+	node    container.Element
+}
+
+func newMergingIterator(sources []container.Iterator, reverse bool) *mergingIterator {
+	it := &mergingIterator{
+		sources: sources,
+		heads:   make([]container.Element, len(sources)),
+		dir:     1,
+	}
+	if reverse {
+		it.dir = -1
+	}
+	for i := range sources {
+		it.heads[i] = it.pull(i)
+	}
+	it.advance()
+	return it
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (it *mergingIterator) Valid() bool {
+	return it.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (it *mergingIterator) Next() container.Element {
+	if it.node == nil {
+		return nil
+	}
+	n := it.node
+	it.advance()
+	return n
+}
+
+func (it *mergingIterator) advance() {
+	it.node = nil
+	best := -1
+	for i, h := range it.heads {
+		if h == nil {
+			continue
+		}
+		if best == -1 || h.Key().Compare(it.heads[best].Key()) == -it.dir {
+			best = i
+		}
+	}
+	if best == -1 {
+		return
+	}
+	it.node = it.heads[best]
+	// Skips the shadowed elements of the same key.
+	for i, h := range it.heads {
+		if h != nil && h.Key().Compare(it.node.Key()) == 0 {
+			it.heads[i] = it.pull(i)
+		}
+	}
+}
+
+// Returns the next element of source i, nil if no more.
+func (it *mergingIterator) pull(i int) container.Element {
+	if !it.sources[i].Valid() {
+		return nil
+	}
+	return it.sources[i].Next()
+}
+
+// levelIterator iterates the non-overlapping tables of a level one by one.
+type levelIterator struct {
+	tables   []*table // The remaining tables in iteration order.
+	start    container.Key
+	boundary container.Key
+	reverse  bool
+	cur      container.Iterator
+}
+
+func newLevelIterator(tables []*table, start container.Key, boundary container.Key, reverse bool) *levelIterator {
+	return &levelIterator{
+		tables:   tables,
+		start:    start,
+		boundary: boundary,
+		reverse:  reverse,
+	}
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (it *levelIterator) Valid() bool {
+	for (it.cur == nil || !it.cur.Valid()) && len(it.tables) != 0 {
+		t := it.tables[0]
+		it.tables = it.tables[1:]
+		if it.reverse {
+			it.cur = t.rd.IterReverse(it.start, it.boundary)
+		} else {
+			it.cur = t.rd.Iter(it.start, it.boundary)
+		}
+	}
+	return it.cur != nil && it.cur.Valid()
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (it *levelIterator) Next() container.Element {
+	if !it.Valid() {
+		return nil
+	}
+	return it.cur.Next()
+}
+
+// memReverseIterator iterates the memtable in reverse by searching the previous key.
+type memReverseIterator struct {
+	mem   *skip.List
+	start container.Key
+	node  container.Element
+}
+
+// Creates a memReverseIterator, the max is the largest key in the memtable.
+func newMemReverseIterator(mem *skip.List, max container.Key, start container.Key, boundary container.Key) *memReverseIterator {
+	it := &memReverseIterator{
+		mem:   mem,
+		start: start,
+	}
+	switch {
+	case max == nil:
+	case boundary == nil:
+		it.seek(mem.LastLE(max))
+	default:
+		it.seek(mem.LastLT(boundary))
+	}
+	return it
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (it *memReverseIterator) Valid() bool {
+	return it.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (it *memReverseIterator) Next() container.Element {
+	if it.node == nil {
+		return nil
+	}
+	n := it.node
+	it.seek(it.mem.LastLT(n.Key()))
+	return n
+}
+
+func (it *memReverseIterator) seek(ele container.Element) {
+	if ele == nil || (it.start != nil && ele.Key().Compare(it.start) == -1) {
+		it.node = nil
+		return
+	}
+	it.node = ele
+}
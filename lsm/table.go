@@ -0,0 +1,174 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package lsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/sstable"
+)
+
+const (
+	manifestName = "MANIFEST"
+	tableExt     = ".sst"
+	logExt       = ".log"
+)
+
+// Returns the path of the numbered file.
+func fileName(dir string, num uint64, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("%06d%s", num, ext))
+}
+
+// Parses the number and extension from a file name, the ok is false if it's not a numbered file.
+func parseFileName(name string) (num uint64, ext string, ok bool) {
+	ext = filepath.Ext(name)
+	if ext != tableExt && ext != logExt {
+		return 0, "", false
+	}
+	num, err := strconv.ParseUint(strings.TrimSuffix(name, ext), 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return num, ext, true
+}
+
+// table is an opened sstable file.
+type table struct {
+	num      uint64
+	file     *os.File
+	size     int64
+	rd       *sstable.Reader
+	smallest container.Key
+	largest  container.Key
+}
+
+// Opens the numbered sstable file.
+func openTable(dir string, num uint64, opts *sstable.Options) (*table, error) {
+	f, err := os.Open(fileName(dir, num, tableExt))
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	rd, err := sstable.Open(f, fi.Size(), opts)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lsm: open table %d: %v", num, err)
+	}
+	t := &table{
+		num:  num,
+		file: f,
+		size: fi.Size(),
+		rd:   rd,
+	}
+	t.smallest, t.largest = rd.Bounds()
+	return t, nil
+}
+
+// Represents whether the keys of table may overlap the range start <= x < boundary.
+func (t *table) overlaps(start container.Key, boundary container.Key) bool {
+	if t.smallest == nil {
+		return false
+	}
+	if start != nil && t.largest.Compare(start) == -1 {
+		return false
+	}
+	if boundary != nil && t.smallest.Compare(boundary) != -1 {
+		return false
+	}
+	return true
+}
+
+// Represents whether the keys of table may overlap the range smallest <= x <= largest.
+func (t *table) overlapsInclusive(smallest container.Key, largest container.Key) bool {
+	if t.smallest == nil {
+		return false
+	}
+	return t.largest.Compare(smallest) != -1 && t.smallest.Compare(largest) != 1
+}
+
+// Returns the element of the key, nil if not found.
+func (t *table) get(k container.Key) container.Element {
+	if !t.overlapsInclusive(k, k) {
+		return nil
+	}
+	ele := t.rd.FirstGE(k)
+	if ele == nil || ele.Key().Compare(k) != 0 {
+		return nil
+	}
+	return ele
+}
+
+// manifest records the live files of the DB.
+type manifest struct {
+	NextFile uint64     `json:"next_file"`
+	Log      uint64     `json:"log"`
+	Levels   [][]uint64 `json:"levels"`
+}
+
+// Reads the manifest in dir, returns nil if not exists.
+func readManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := new(manifest)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("lsm: corrupted manifest: %v", err)
+	}
+	return m, nil
+}
+
+// Replaces the manifest in dir atomically.
+func writeManifest(dir string, m *manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, manifestName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, manifestName)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Syncs the directory to persist the renamed and created files.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// Some platforms do not support to sync a directory.
+	d.Sync()
+	return nil
+}
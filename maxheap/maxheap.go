@@ -6,6 +6,8 @@
 package maxheap
 
 import (
+	"iter"
+
 	"github.com/yu31/structs-go/container"
 )
 
@@ -130,6 +132,56 @@ func (h *MaxHeap) Peek() *Item {
 	return h.items[0]
 }
 
+// All returns an iterator over the elements in pop order, the heap is not changed.
+// The heap must not be changed during the iteration.
+func (h *MaxHeap) All() iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		if h.Empty() {
+			return
+		}
+		// The candidates are the items whose parent has been yielded, the largest one is the next.
+		// It's also a heap, thus only O(k log k) for the first k elements.
+		candidates := []int{0}
+		for len(candidates) > 0 {
+			i := candidates[0]
+			n := len(candidates) - 1
+			candidates[0] = candidates[n]
+			candidates = candidates[:n]
+			h.downIndexes(candidates, 0)
+
+			if !yield(h.items[i].key, h.items[i].value) {
+				return
+			}
+			for c := (i << 1) + 1; c <= (i<<1)+2 && c < h.len; c++ {
+				candidates = append(candidates, c)
+				h.upIndexes(candidates, len(candidates)-1)
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys in pop order, the heap is not changed.
+func (h *MaxHeap) Keys() iter.Seq[Key] {
+	return func(yield func(Key) bool) {
+		for k := range h.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values in pop order, the heap is not changed.
+func (h *MaxHeap) Values() iter.Seq[Value] {
+	return func(yield func(Value) bool) {
+		for _, v := range h.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
 func (h *MaxHeap) delete(i int) *Item {
 	item := h.items[i]
 	h.len--
@@ -178,6 +230,36 @@ func (h *MaxHeap) down(i0 int, n int) bool {
 	return i > i0
 }
 
+// upIndexes build the heap of indexes with bottom-up.
+func (h *MaxHeap) upIndexes(indexes []int, i int) {
+	for i > 0 {
+		p := (i - 1) >> 1 // parent
+		if h.compare(indexes[i], indexes[p]) != 1 {
+			break
+		}
+		indexes[p], indexes[i] = indexes[i], indexes[p]
+		i = p
+	}
+}
+
+// downIndexes build the heap of indexes with top-down.
+func (h *MaxHeap) downIndexes(indexes []int, i int) {
+	for {
+		c := (i << 1) + 1 // left child
+		if c >= len(indexes) {
+			break
+		}
+		if r := c + 1; r < len(indexes) && h.compare(indexes[r], indexes[c]) == 1 {
+			c = r // right child
+		}
+		if h.compare(indexes[c], indexes[i]) != 1 {
+			break
+		}
+		indexes[i], indexes[c] = indexes[c], indexes[i]
+		i = c
+	}
+}
+
 func (h *MaxHeap) swap(i, j int) {
 	h.items[i], h.items[j] = h.items[j], h.items[i]
 	h.items[i].index = i
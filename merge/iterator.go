@@ -0,0 +1,220 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package merge implements the k-way merge of sorted container.Iterators.
+package merge
+
+import (
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/maxheap"
+	"github.com/yu31/structs-go/minheap"
+)
+
+var (
+	_ container.Iterator = (*Iterator)(nil)
+	_ container.Iterator = (*ReverseIterator)(nil)
+)
+
+// Policy decides which elements are yielded for the duplicate keys.
+type Policy int
+
+const (
+	// FirstWins yields only the element from the source with the smallest index.
+	FirstWins Policy = iota
+	// LastWins yields only the element from the source with the largest index.
+	LastWins
+	// YieldAll yields all elements, the elements with the same key are yielded by the order of sources.
+	YieldAll
+)
+
+// head is the current element of a source, it's the key of items in the heap.
+type head struct {
+	ele     container.Element
+	src     int
+	reverse bool
+}
+
+// Compare compares the keys of elements, and then the indexes of sources. The smaller index
+// is always popped first from both the minheap and maxheap.
+func (h *head) Compare(k container.Key) int {
+	h2 := k.(*head)
+	if cmp := h.ele.Key().Compare(h2.ele.Key()); cmp != 0 {
+		return cmp
+	}
+	if h.src == h2.src {
+		return 0
+	}
+	if (h.src < h2.src) != h.reverse {
+		return -1
+	}
+	return 1
+}
+
+// heap is the common operations of minheap and maxheap.
+type heap interface {
+	push(h *head)
+	pop() *head
+	peek() *head
+}
+
+type minHeap struct{ *minheap.MinHeap }
+
+func (h minHeap) push(x *head) { h.Push(x, nil) }
+
+func (h minHeap) pop() *head {
+	if item := h.Pop(); item != nil {
+		return item.Key().(*head)
+	}
+	return nil
+}
+
+func (h minHeap) peek() *head {
+	if item := h.Peek(); item != nil {
+		return item.Key().(*head)
+	}
+	return nil
+}
+
+type maxHeap struct{ *maxheap.MaxHeap }
+
+func (h maxHeap) push(x *head) { h.Push(x, nil) }
+
+func (h maxHeap) pop() *head {
+	if item := h.Pop(); item != nil {
+		return item.Key().(*head)
+	}
+	return nil
+}
+
+func (h maxHeap) peek() *head {
+	if item := h.Peek(); item != nil {
+		return item.Key().(*head)
+	}
+	return nil
+}
+
+// merger holds the common logic of Iterator and ReverseIterator.
+type merger struct {
+	sources []container.Iterator
+	policy  Policy
+	reverse bool
+	h       heap
+	node    container.Element
+}
+
+func (m *merger) init() {
+	for i := range m.sources {
+		m.pull(i)
+	}
+	m.advance()
+}
+
+// Pushes the next element of source i to the heap.
+func (m *merger) pull(i int) {
+	if !m.sources[i].Valid() {
+		return
+	}
+	if ele := m.sources[i].Next(); ele != nil {
+		m.h.push(&head{ele: ele, src: i, reverse: m.reverse})
+	}
+}
+
+func (m *merger) advance() {
+	m.node = nil
+	top := m.h.pop()
+	if top == nil {
+		return
+	}
+	m.pull(top.src)
+	m.node = top.ele
+	if m.policy == YieldAll {
+		return
+	}
+
+	// Resolves the duplicate keys, the elements are popped by the order of sources.
+	for {
+		next := m.h.peek()
+		if next == nil || next.ele.Key().Compare(top.ele.Key()) != 0 {
+			return
+		}
+		m.h.pop()
+		m.pull(next.src)
+		if m.policy == LastWins {
+			m.node = next.ele
+		}
+	}
+}
+
+func (m *merger) next() container.Element {
+	if m.node == nil {
+		return nil
+	}
+	n := m.node
+	m.advance()
+	return n
+}
+
+// Iterator merges the iterators of ascending order by key, it yields the elements in ascending order.
+// The duplicate keys are resolved by the Policy.
+type Iterator struct {
+	m merger
+}
+
+// NewIterator creates an Iterator that merges the sources, each source must be in ascending order,
+// such as the Iterators returned by the Iter method of container.
+func NewIterator(policy Policy, sources ...container.Iterator) *Iterator {
+	iter := &Iterator{
+		m: merger{
+			sources: sources,
+			policy:  policy,
+			h:       minHeap{minheap.New(len(sources) + 1)},
+		},
+	}
+	iter.m.init()
+	return iter
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *Iterator) Valid() bool {
+	return iter.m.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *Iterator) Next() container.Element {
+	return iter.m.next()
+}
+
+// ReverseIterator merges the iterators of descending order by key, it yields the elements in descending order.
+// The duplicate keys are resolved by the Policy.
+type ReverseIterator struct {
+	m merger
+}
+
+// NewReverseIterator creates an ReverseIterator that merges the sources, each source must be in descending
+// order, such as the Iterators returned by the IterReverse method of container.
+func NewReverseIterator(policy Policy, sources ...container.Iterator) *ReverseIterator {
+	iter := &ReverseIterator{
+		m: merger{
+			sources: sources,
+			policy:  policy,
+			reverse: true,
+			h:       maxHeap{maxheap.New(len(sources) + 1)},
+		},
+	}
+	iter.m.init()
+	return iter
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *ReverseIterator) Valid() bool {
+	return iter.m.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *ReverseIterator) Next() container.Element {
+	return iter.m.next()
+}
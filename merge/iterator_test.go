@@ -0,0 +1,115 @@
+package merge
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+	"github.com/yu31/structs-go/tests"
+)
+
+// The pair of source index and key.
+type pair struct {
+	src int
+	key container.Int
+}
+
+// Returns the merged result by brute force.
+func expected(pairs []pair, policy Policy, reverse bool) []interface{} {
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].key != pairs[j].key {
+			return (pairs[i].key < pairs[j].key) != reverse
+		}
+		return pairs[i].src < pairs[j].src
+	})
+	var result []interface{}
+	for i, p := range pairs {
+		dup := i > 0 && pairs[i-1].key == p.key
+		switch policy {
+		case FirstWins:
+			if dup {
+				continue
+			}
+		case LastWins:
+			if dup {
+				result = result[:len(result)-2]
+			}
+		}
+		result = append(result, p.key, p.src)
+	}
+	return result
+}
+
+func TestIterator(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for round := 0; round < 50; round++ {
+		// Each source is kept in both a skip.List and a rb.Tree, the iterators of them are mixed.
+		n := r.Intn(6)
+		lists := make([]*skip.List, n)
+		trees := make([]*rb.Tree, n)
+		var pairs []pair
+		for i := 0; i < n; i++ {
+			lists[i], trees[i] = skip.New(), rb.New()
+			for j := r.Intn(100); j > 0; j-- {
+				k := container.Int(r.Intn(200))
+				if _, ok := lists[i].Insert(k, i); ok {
+					trees[i].Insert(k, i)
+					pairs = append(pairs, pair{src: i, key: k})
+				}
+			}
+		}
+
+		for _, policy := range []Policy{FirstWins, LastWins, YieldAll} {
+			iters := make([]container.Iterator, n)
+			for i := 0; i < n; i++ {
+				if i%2 == 0 {
+					iters[i] = lists[i].Iter(nil, nil)
+				} else {
+					iters[i] = trees[i].Iter(nil, nil)
+				}
+			}
+			require.Equal(t, tests.IterPairs(NewIterator(policy, iters...)), expected(pairs, policy, false))
+
+			for i := 0; i < n; i++ {
+				if i%2 == 0 {
+					iters[i] = trees[i].IterReverse(nil, nil)
+				} else {
+					iters[i] = lists[i].IterReverse(nil, nil)
+				}
+			}
+			require.Equal(t, tests.IterPairs(NewReverseIterator(policy, iters...)), expected(pairs, policy, true))
+		}
+	}
+}
+
+func TestIterator_MultiContainer(t *testing.T) {
+	a, b := rb.NewMulti(), rb.NewMulti()
+	a.Insert(container.Int(1), "a1")
+	a.Insert(container.Int(1), "a2")
+	b.Insert(container.Int(1), "b1")
+	b.Insert(container.Int(2), "b2")
+
+	var result []interface{}
+	it := NewIterator(YieldAll, a.Iter(nil, nil), b.Iter(nil, nil))
+	for it.Valid() {
+		result = append(result, it.Next().Value())
+	}
+	require.Equal(t, result, []interface{}{"a1", "a2", "b1", "b2"})
+
+	result = nil
+	it = NewIterator(FirstWins, a.Iter(nil, nil), b.Iter(nil, nil))
+	for it.Valid() {
+		result = append(result, it.Next().Value())
+	}
+	require.Equal(t, result, []interface{}{"a1", "b2"})
+
+	require.False(t, NewIterator(FirstWins).Valid())
+	require.False(t, NewReverseIterator(LastWins).Valid())
+}
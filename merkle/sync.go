@@ -0,0 +1,252 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/yu31/structs-go/codec"
+	"github.com/yu31/structs-go/container"
+)
+
+var _ Peer = (*Tree)(nil)
+
+// Peer is a replica compared by the Diff method, it can be a Tree or a client of a remote Tree.
+// The start and boundary have the same meaning as the Range method.
+type Peer interface {
+	// RangeHash returns the hash of elements in the range start <= x < boundary.
+	RangeHash(start container.Key, boundary container.Key) Hash
+
+	// RangeLen returns the number of elements in the range start <= x < boundary.
+	RangeLen(start container.Key, boundary container.Key) int
+
+	// Median returns the key of middle element in the range start <= x < boundary.
+	// Returns nil if the range is empty.
+	Median(start container.Key, boundary container.Key) container.Key
+}
+
+// RootHash returns the Merkle hash of the tree, it is the zero Hash if the tree is empty.
+//
+// The Merkle hash depends on the shape of tree, so the trees with the same elements may have
+// different Merkle hashes. Use RangeHash(nil, nil) to compare the elements of two trees.
+func (tr *Tree) RootHash() Hash {
+	return nodeHash(tr.root)
+}
+
+// RangeHash returns the hash of elements in the range start <= x < boundary in O(log n).
+// The hash only depends on the elements in the range.
+func (tr *Tree) RangeHash(start container.Key, boundary container.Key) Hash {
+	sum, count := tr.summary(start, boundary)
+	var buf [sha256.Size + 8]byte
+	copy(buf[:], sum[:])
+	binary.BigEndian.PutUint64(buf[sha256.Size:], uint64(count))
+	return sha256.Sum256(buf[:])
+}
+
+// RangeLen returns the number of elements in the range start <= x < boundary in O(log n).
+func (tr *Tree) RangeLen(start container.Key, boundary container.Key) int {
+	_, count := tr.summary(start, boundary)
+	return count
+}
+
+// Median returns the key of middle element in the range start <= x < boundary in O(log n).
+// Returns nil if the range is empty.
+func (tr *Tree) Median(start container.Key, boundary container.Key) container.Key {
+	count := tr.RangeLen(start, boundary)
+	if count == 0 {
+		return nil
+	}
+	rank := count / 2
+	if start != nil {
+		rank += tr.rank(start)
+	}
+	return tr.selectNode(rank).key
+}
+
+// Diff finds the ranges that the elements are different between the tree and peer, and calls f sequentially
+// with each range in ascending order. If f returns false, Diff stops the finding.
+//
+// The ranges are split by the median key until the hashes are equal or the range has only one element
+// on both sides. So the hashes of O(d log n) ranges are compared for d different elements.
+func (tr *Tree) Diff(peer Peer, f func(start container.Key, boundary container.Key) bool) {
+	tr.diff(peer, nil, nil, f)
+}
+
+func (tr *Tree) diff(peer Peer, start container.Key, boundary container.Key, f func(start container.Key, boundary container.Key) bool) bool {
+	if tr.RangeHash(start, boundary) == peer.RangeHash(start, boundary) {
+		return true
+	}
+
+	// Splits the range by the side with more elements.
+	var pivot container.Key
+	n, m := tr.RangeLen(start, boundary), peer.RangeLen(start, boundary)
+	if n >= m && n > 1 {
+		pivot = tr.Median(start, boundary)
+	} else if m > n && m > 1 {
+		pivot = peer.Median(start, boundary)
+	}
+	// The pivot is greater than the first element of range, so both sub-ranges are smaller.
+	if pivot == nil || (start != nil && pivot.Compare(start) != 1) {
+		return f(start, boundary)
+	}
+	return tr.diff(peer, start, pivot, f) && tr.diff(peer, pivot, boundary, f)
+}
+
+// Returns the sum of digests and the number of elements in the range start <= x < boundary.
+func (tr *Tree) summary(start container.Key, boundary container.Key) (Hash, int) {
+	// Finds the topmost node in the range, the range of its left subtree is bounded by start only,
+	// and the range of its right subtree is bounded by boundary only.
+	p := tr.root
+	for p != nil {
+		if start != nil && p.key.Compare(start) == -1 {
+			p = p.right
+		} else if boundary != nil && p.key.Compare(boundary) != -1 {
+			p = p.left
+		} else {
+			break
+		}
+	}
+	if p == nil {
+		return Hash{}, 0
+	}
+
+	sum, count := p.digest, 1
+	// The elements >= start in the left subtree.
+	for q := p.left; q != nil; {
+		if start != nil && q.key.Compare(start) == -1 {
+			q = q.right
+			continue
+		}
+		sum = addHash(addHash(sum, q.digest), nodeSum(q.right))
+		count += 1 + nodeCount(q.right)
+		q = q.left
+	}
+	// The elements < boundary in the right subtree.
+	for q := p.right; q != nil; {
+		if boundary != nil && q.key.Compare(boundary) != -1 {
+			q = q.left
+			continue
+		}
+		sum = addHash(addHash(sum, q.digest), nodeSum(q.left))
+		count += 1 + nodeCount(q.left)
+		q = q.right
+	}
+	return sum, count
+}
+
+// Returns the number of elements less than k.
+func (tr *Tree) rank(k container.Key) int {
+	rank := 0
+	for p := tr.root; p != nil; {
+		if p.key.Compare(k) == -1 {
+			rank += nodeCount(p.left) + 1
+			p = p.right
+		} else {
+			p = p.left
+		}
+	}
+	return rank
+}
+
+// Returns the node at the given rank in ascending order, the rank must be less than the length.
+func (tr *Tree) selectNode(rank int) *treeNode {
+	p := tr.root
+	for {
+		lc := nodeCount(p.left)
+		switch {
+		case rank < lc:
+			p = p.left
+		case rank > lc:
+			rank -= lc + 1
+			p = p.right
+		default:
+			return p
+		}
+	}
+}
+
+// Proof is a proof of inclusion of an element in a tree with the Merkle hash.
+type Proof struct {
+	// Left and Right are the Merkle hashes of children of the element.
+	Left  Hash
+	Right Hash
+	// Path are the ancestors of the element from the parent to the root.
+	Path []ProofStep
+}
+
+// ProofStep is an ancestor in the Proof.
+type ProofStep struct {
+	// Digest is the digest of the ancestor's element.
+	Digest Hash
+	// Sibling is the Merkle hash of the other child of ancestor.
+	Sibling Hash
+	// Right is true if the path goes through the right child of ancestor.
+	Right bool
+}
+
+// Prove returns the proof that the element of key is in the tree with the current RootHash.
+// The bool result is false if the key not found.
+func (tr *Tree) Prove(k container.Key) (*Proof, bool) {
+	var path []*treeNode
+	p := tr.root
+	for p != nil {
+		cmp := k.Compare(p.key)
+		if cmp == 0 {
+			break
+		}
+		path = append(path, p)
+		if cmp == -1 {
+			p = p.left
+		} else {
+			p = p.right
+		}
+	}
+	if p == nil {
+		return nil, false
+	}
+
+	proof := &Proof{
+		Left:  nodeHash(p.left),
+		Right: nodeHash(p.right),
+		Path:  make([]ProofStep, 0, len(path)),
+	}
+	child := p
+	for i := len(path) - 1; i >= 0; i-- {
+		a := path[i]
+		step := ProofStep{Digest: a.digest, Right: a.right == child}
+		if step.Right {
+			step.Sibling = nodeHash(a.left)
+		} else {
+			step.Sibling = nodeHash(a.right)
+		}
+		proof.Path = append(proof.Path, step)
+		child = a
+	}
+	return proof, true
+}
+
+// Verify reports whether the proof proves that the element of key and value is in the tree with the
+// Merkle hash root. The registry is used to encode the key and value, default codec.Default if nil.
+// Returns an error if the key or value can't be encoded.
+func (p *Proof) Verify(root Hash, k container.Key, v container.Value, registry *codec.Registry) (bool, error) {
+	if registry == nil {
+		registry = codec.Default
+	}
+	d, err := digest(registry, nil, k, v)
+	if err != nil {
+		return false, err
+	}
+	h := hashNode(p.Left, d, p.Right)
+	for _, step := range p.Path {
+		if step.Right {
+			h = hashNode(step.Sibling, step.Digest, h)
+		} else {
+			h = hashNode(h, step.Digest, step.Sibling)
+		}
+	}
+	return h == root, nil
+}
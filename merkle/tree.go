@@ -0,0 +1,642 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package merkle implements an AVL tree augmented with hashes for the replication between replicas.
+//
+// Each node stores two hashes of its subtree. The Merkle hash is the SHA-256 of the hashes of children
+// and the digest of element, it authenticates the shape and elements of tree, and is used by the proofs
+// of inclusion. The sum is the sum of digests of elements modulo 2^256, it only depends on the elements,
+// so two trees with the same elements have the same RangeHash for any range, whatever the shape of trees.
+// Both hashes are kept up to date along the changed paths, so the cost of changes is still O(log n).
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"iter"
+
+	"github.com/yu31/structs-go/codec"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/internal/tree"
+)
+
+var (
+	_ container.Container = (*Tree)(nil)
+	_ container.Element   = (*treeNode)(nil)
+	_ container.Tree      = (*Tree)(nil)
+	_ container.TreeNode  = (*treeNode)(nil)
+)
+
+// Hash is a SHA-256 hash.
+type Hash [sha256.Size]byte
+
+// treeNode is used for merkle tree.
+type treeNode struct {
+	key    container.Key
+	value  container.Value
+	left   *treeNode
+	right  *treeNode
+	height int
+	count  int  // The number of nodes in the subtree.
+	digest Hash // The hash of key and value.
+	sum    Hash // The sum of digests in the subtree.
+	hash   Hash // The Merkle hash of the subtree.
+}
+
+// Key returns the key.
+func (n *treeNode) Key() container.Key {
+	return n.key
+}
+
+// Value returns the value.
+func (n *treeNode) Value() container.Value {
+	return n.value
+}
+
+// Left returns the left child of the TreeNode.
+func (n *treeNode) Left() container.TreeNode {
+	if n.left == nil {
+		return nil
+	}
+	return n.left
+}
+
+// Right returns the right child of the TreeNode.
+func (n *treeNode) Right() container.TreeNode {
+	if n.right == nil {
+		return nil
+	}
+	return n.right
+}
+
+// Options is the options of Tree.
+type Options struct {
+	// Registry is used to encode the keys and values for hashing, default codec.Default.
+	// The changes panic if the type of key or value is not registered, and the tree is unchanged.
+	// Use the Put method to validate them with an error instead.
+	Registry *codec.Registry
+}
+
+// Returns a copy of opts with defaults filled.
+func copyOptions(opts *Options) *Options {
+	o := &Options{}
+	if opts != nil {
+		*o = *opts
+	}
+	if o.Registry == nil {
+		o.Registry = codec.Default
+	}
+	return o
+}
+
+// Tree implements the AVL Tree with Merkle hashes.
+//
+// The elements returned by Tree can not be changed in place, since the hashes must be updated along
+// the path. Use the UpdateInPlace method instead.
+type Tree struct {
+	root    *treeNode
+	len     int
+	opts    *Options
+	scratch []byte
+}
+
+// New creates a Tree, the default options is used if opts is nil.
+func New(opts *Options) *Tree {
+	return &Tree{
+		root: nil,
+		len:  0,
+		opts: copyOptions(opts),
+	}
+}
+
+// Root returns the root node of the tree.
+func (tr *Tree) Root() container.TreeNode {
+	if tr.root == nil {
+		return nil
+	}
+	return tr.root
+}
+
+// Len returns the number of elements.
+func (tr *Tree) Len() int {
+	return tr.len
+}
+
+// Insert inserts a new element if the key doesn't exist, or returns the existing element for the key if present.
+// The bool result is true if an element was inserted, false if searched.
+func (tr *Tree) Insert(k container.Key, v container.Value) (container.Element, bool) {
+	var node *treeNode
+	var ok bool
+	tr.root, _ = tr.modify(tr.root, k, func(old *treeNode) (*treeNode, bool) {
+		if old != nil {
+			node = old
+			return old, false
+		}
+		node, ok = tr.createNode(k, v), true
+		return node, true
+	})
+	return node, ok
+}
+
+// Delete removes and returns the element of a given key.
+// Returns nil if key not found.
+func (tr *Tree) Delete(k container.Key) container.Element {
+	var d *treeNode
+	tr.root, _ = tr.modify(tr.root, k, func(old *treeNode) (*treeNode, bool) {
+		d = old
+		return nil, old != nil
+	})
+	if d == nil {
+		return nil
+	}
+	return d
+}
+
+// Update updates an element with the given key and value, And returns the old element of key.
+// Returns nil if the key not be found.
+func (tr *Tree) Update(k container.Key, v container.Value) container.Element {
+	var node *treeNode
+	tr.root, _ = tr.modify(tr.root, k, func(old *treeNode) (*treeNode, bool) {
+		if old == nil {
+			return nil, false
+		}
+		node = old
+		return tr.createNode(k, v), true
+	})
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// Upsert inserts or updates an element by giving key and value.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (tr *Tree) Upsert(k container.Key, v container.Value) (container.Element, bool) {
+	var node *treeNode
+	var ok bool
+	tr.root, _ = tr.modify(tr.root, k, func(old *treeNode) (*treeNode, bool) {
+		n := tr.createNode(k, v)
+		if old == nil {
+			node, ok = n, true
+		} else {
+			node = old
+		}
+		return n, true
+	})
+	return node, ok
+}
+
+// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place.
+// Returns the element of key and its old value, returns nil if the key not be found.
+func (tr *Tree) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	var node *treeNode
+	var value container.Value
+	tr.root, _ = tr.modify(tr.root, k, func(old *treeNode) (*treeNode, bool) {
+		if old == nil {
+			return nil, false
+		}
+		node, value = old, old.value
+		tr.setValue(old, v)
+		return old, true
+	})
+	if node == nil {
+		return nil, nil
+	}
+	return node, value
+}
+
+// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (tr *Tree) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	var ok bool
+	ele := tr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		ok = !exists
+		return v, true
+	})
+	return ele, ok
+}
+
+// Put inserts or sets the value of key in place, it is similar to the UpsertInPlace method,
+// but returns an error instead of panic if the type of key or value is not registered.
+// The tree is unchanged if an error returned.
+func (tr *Tree) Put(k container.Key, v container.Value) error {
+	d, err := digest(tr.opts.Registry, tr.scratch[:0], k, v)
+	if err != nil {
+		return fmt.Errorf("merkle: %v", err)
+	}
+	tr.root, _ = tr.modify(tr.root, k, func(old *treeNode) (*treeNode, bool) {
+		if old == nil {
+			return newNode(k, v, d), true
+		}
+		old.value = v
+		old.digest = d
+		return old, true
+	})
+	return nil
+}
+
+// Search searches the element of a given key.
+// Returns nil if key not found.
+func (tr *Tree) Search(k container.Key) container.Element {
+	p := tr.root
+	for p != nil {
+		switch k.Compare(p.key) {
+		case -1:
+			p = p.left
+		case 1:
+			p = p.right
+		default:
+			return p
+		}
+	}
+	return nil
+}
+
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f. All of these are done with a single search.
+//
+// Returns the element of key after computed, nil if no element for the key.
+func (tr *Tree) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) container.Element {
+	var node *treeNode
+	tr.root, _ = tr.modify(tr.root, k, func(old *treeNode) (*treeNode, bool) {
+		if old == nil {
+			v, keep := f(nil, false)
+			if !keep {
+				return nil, false
+			}
+			node = tr.createNode(k, v)
+			return node, true
+		}
+		v, keep := f(old, true)
+		if !keep {
+			return nil, true
+		}
+		node = old
+		tr.setValue(old, v)
+		return old, true
+	})
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+func (tr *Tree) GetOrInsert(k container.Key, f func() container.Value) (container.Element, bool) {
+	var node *treeNode
+	var ok bool
+	tr.root, _ = tr.modify(tr.root, k, func(old *treeNode) (*treeNode, bool) {
+		if old != nil {
+			node = old
+			return old, false
+		}
+		node, ok = tr.createNode(k, f()), true
+		return node, true
+	})
+	return node, ok
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+//
+// The elements are removed one by one, so the cost is O(m log n) for m removed elements.
+func (tr *Tree) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	var keys []container.Key
+	tree.Range(tr.root, start, boundary, func(node container.TreeNode) bool {
+		keys = append(keys, node.Key())
+		return true
+	})
+	for _, k := range keys {
+		d := tr.Delete(k)
+		if f != nil {
+			f(d)
+		}
+	}
+	return len(keys)
+}
+
+// Clear removes all elements in the tree.
+func (tr *Tree) Clear() {
+	tr.root = nil
+	tr.len = 0
+}
+
+// Iter return an Iterator, it's a wrap for tree.Iterator.
+func (tr *Tree) Iter(start container.Key, boundary container.Key) container.Iterator {
+	return tree.NewIterator(tr.root, start, boundary)
+}
+
+// IterReverse return an Iterator, it's a wrap for tree.IterReverse.
+func (tr *Tree) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	return tree.NewIteratorReverse(tr.root, start, boundary)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (tr *Tree) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(tr)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (tr *Tree) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(tr)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (tr *Tree) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(tr, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (tr *Tree) Keys() iter.Seq[container.Key] {
+	return container.Keys(tr)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (tr *Tree) Values() iter.Seq[container.Value] {
+	return container.Values(tr)
+}
+
+// Range calls f sequentially each TreeNode present in the Tree.
+// If f returns false, range stops the iteration.
+func (tr *Tree) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	tree.Range(tr.root, start, boundary, func(node container.TreeNode) bool {
+		return f(node)
+	})
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+// If f returns false, range stops the iteration.
+func (tr *Tree) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	tree.Reverse(tr.root, start, boundary, func(node container.TreeNode) bool {
+		return f(node)
+	})
+}
+
+// LastLT searches for the last node that less than the key.
+func (tr *Tree) LastLT(k container.Key) container.Element {
+	return tree.LastLT(tr.root, k)
+}
+
+// LastLE search for the last node that less than or equal to the key.
+func (tr *Tree) LastLE(k container.Key) container.Element {
+	return tree.LastLE(tr.root, k)
+}
+
+// FirstGT search for the first node that greater than to the key.
+func (tr *Tree) FirstGT(k container.Key) container.Element {
+	return tree.FirstGT(tr.root, k)
+}
+
+// FirstGE search for the first node that greater than or equal to the key.
+func (tr *Tree) FirstGE(k container.Key) container.Element {
+	return tree.FirstGE(tr.root, k)
+}
+
+// Searches the node of key in the tree r0 and calls f with it, f is called with nil if the key not found.
+// If f returns changed true, the node is replaced by the returned node, or deleted if it is nil.
+// The returned node can be the same node if its value has been changed in place.
+// Returns the root node, and whether the tree r0 has been changed.
+func (tr *Tree) modify(r0 *treeNode, k container.Key, f func(old *treeNode) (*treeNode, bool)) (root *treeNode, changed bool) {
+	if r0 == nil {
+		node, changed := f(nil)
+		if !changed || node == nil {
+			return nil, false
+		}
+		tr.len++
+		return tr.fix(node), true
+	}
+
+	root = r0
+	switch k.Compare(root.key) {
+	case -1:
+		root.left, changed = tr.modify(root.left, k, f)
+	case 1:
+		root.right, changed = tr.modify(root.right, k, f)
+	default:
+		var node *treeNode
+		if node, changed = f(root); !changed {
+			return
+		}
+		if node == nil {
+			tr.len--
+			return tr.deleteNode(root), true
+		}
+		if node != root {
+			node.left, node.right = root.left, root.right
+			// reset the unused field.
+			root.left = nil
+			root.right = nil
+			root.height = -1
+		}
+		return tr.fix(node), true
+	}
+
+	if changed {
+		root = tr.reBalance(root)
+	}
+	return
+}
+
+// Removes the node from its subtree, returns the new root of subtree.
+func (tr *Tree) deleteNode(d *treeNode) *treeNode {
+	var root *treeNode
+	switch {
+	case d.left == nil:
+		root = d.right
+	case d.right == nil:
+		root = d.left
+	default:
+		// Replace the location of the deleted node with its successor
+		var x *treeNode
+		root, x = tr.deleteMin(d.right)
+		x.left = d.left
+		x.right = root
+		root = tr.reBalance(x)
+	}
+
+	// reset the unused field.
+	d.left = nil
+	d.right = nil
+	d.height = -1
+	return root
+}
+
+// Deletes the node with minimum key in the tree r0, returns the root node and deleted node.
+func (tr *Tree) deleteMin(r0 *treeNode) (root *treeNode, d *treeNode) {
+	if r0.left == nil {
+		return r0.right, r0
+	}
+	r0.left, d = tr.deleteMin(r0.left)
+	root = tr.reBalance(r0)
+	return
+}
+
+// Creates a new node with the giving key and value.
+func (tr *Tree) createNode(k container.Key, v container.Value) *treeNode {
+	return newNode(k, v, tr.digest(k, v))
+}
+
+// Creates a new node with the giving key, value and their digest.
+func newNode(k container.Key, v container.Value, d Hash) *treeNode {
+	return &treeNode{
+		key:    k,
+		value:  v,
+		left:   nil,
+		right:  nil,
+		height: 1,
+		count:  1,
+		digest: d,
+	}
+}
+
+// Sets the value of node, the caller must fix the hashes of path.
+// The digest is computed before any change, so the node is unchanged if it panics.
+func (tr *Tree) setValue(node *treeNode, v container.Value) {
+	d := tr.digest(node.key, v)
+	node.value = v
+	node.digest = d
+}
+
+// Returns the digest of key and value.
+func (tr *Tree) digest(k container.Key, v container.Value) Hash {
+	d, err := digest(tr.opts.Registry, tr.scratch[:0], k, v)
+	if err != nil {
+		panic(fmt.Errorf("merkle: %v", err))
+	}
+	return d
+}
+
+func (tr *Tree) reBalance(node *treeNode) *treeNode {
+	if node == nil {
+		return nil
+	}
+
+	factor := nodeHeight(node.left) - nodeHeight(node.right)
+	switch factor {
+	case -1, 0, 1:
+		tr.fix(node)
+	case 2:
+		// Left subtree higher than right subtree.
+		if nodeHeight(node.left.right) > nodeHeight(node.left.left) {
+			node.left = tr.leftRotate(node.left)
+		}
+		node = tr.rightRotate(node)
+	case -2:
+		// Left subtree lower than right subtree.
+		if nodeHeight(node.right.left) > nodeHeight(node.right.right) {
+			node.right = tr.rightRotate(node.right)
+		}
+		node = tr.leftRotate(node)
+	default:
+		panic(fmt.Errorf("merkle: unexpected cases with invalid factor <%d>", factor))
+	}
+	return node
+}
+
+func (tr *Tree) leftRotate(node *treeNode) *treeNode {
+	r := node.right
+
+	node.right = r.left
+	r.left = node
+
+	tr.fix(node)
+	return tr.fix(r)
+}
+
+func (tr *Tree) rightRotate(node *treeNode) *treeNode {
+	l := node.left
+
+	node.left = l.right
+	l.right = node
+
+	tr.fix(node)
+	return tr.fix(l)
+}
+
+// Recalculates the height, count and hashes of node from its children, returns the node.
+func (tr *Tree) fix(node *treeNode) *treeNode {
+	lh, rh := nodeHeight(node.left), nodeHeight(node.right)
+	if lh > rh {
+		node.height = lh + 1
+	} else {
+		node.height = rh + 1
+	}
+	node.count = nodeCount(node.left) + nodeCount(node.right) + 1
+	node.sum = addHash(addHash(nodeSum(node.left), node.digest), nodeSum(node.right))
+	node.hash = hashNode(nodeHash(node.left), node.digest, nodeHash(node.right))
+	return node
+}
+
+func nodeHeight(node *treeNode) int {
+	if node == nil {
+		return 0
+	}
+	return node.height
+}
+
+func nodeCount(node *treeNode) int {
+	if node == nil {
+		return 0
+	}
+	return node.count
+}
+
+func nodeSum(node *treeNode) Hash {
+	if node == nil {
+		return Hash{}
+	}
+	return node.sum
+}
+
+func nodeHash(node *treeNode) Hash {
+	if node == nil {
+		return Hash{}
+	}
+	return node.hash
+}
+
+// Returns the digest of key and value, buf is used as the scratch space of encoding.
+func digest(registry *codec.Registry, buf []byte, k container.Key, v container.Value) (Hash, error) {
+	buf = append(buf[:0], 0)
+	buf, err := registry.AppendValue(buf, k)
+	if err != nil {
+		return Hash{}, err
+	}
+	if buf, err = registry.AppendValue(buf, v); err != nil {
+		return Hash{}, err
+	}
+	return sha256.Sum256(buf), nil
+}
+
+// Returns the Merkle hash of a node.
+func hashNode(left Hash, digest Hash, right Hash) Hash {
+	var buf [1 + 3*sha256.Size]byte
+	buf[0] = 1
+	copy(buf[1:], left[:])
+	copy(buf[1+sha256.Size:], digest[:])
+	copy(buf[1+2*sha256.Size:], right[:])
+	return sha256.Sum256(buf[:])
+}
+
+// Returns a + b modulo 2^256, the hashes are big-endian numbers.
+func addHash(a Hash, b Hash) Hash {
+	var c Hash
+	var carry uint64
+	for i := len(c) - 8; i >= 0; i -= 8 {
+		x := binary.BigEndian.Uint64(a[i:])
+		y := binary.BigEndian.Uint64(b[i:])
+		s := x + y + carry
+		if s < x || (carry == 1 && s == x) {
+			carry = 1
+		} else {
+			carry = 0
+		}
+		binary.BigEndian.PutUint64(c[i:], s)
+	}
+	return c
+}
@@ -0,0 +1,330 @@
+package merkle
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/codec"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/tests"
+)
+
+// Checks the balance, counts and hashes of each node, returns the height of tree r0.
+func checkNode(t *testing.T, r0 *treeNode) int {
+	if r0 == nil {
+		return 0
+	}
+	lh := checkNode(t, r0.left)
+	rh := checkNode(t, r0.right)
+	require.LessOrEqual(t, lh-rh, 1)
+	require.GreaterOrEqual(t, lh-rh, -1)
+	if r0.left != nil {
+		require.Equal(t, r0.left.key.Compare(r0.key), -1)
+	}
+	if r0.right != nil {
+		require.Equal(t, r0.right.key.Compare(r0.key), 1)
+	}
+
+	d, err := digest(codec.Default, nil, r0.key, r0.value)
+	require.Nil(t, err)
+	require.Equal(t, r0.digest, d)
+	require.Equal(t, r0.count, nodeCount(r0.left)+nodeCount(r0.right)+1)
+	require.Equal(t, r0.sum, addHash(addHash(nodeSum(r0.left), d), nodeSum(r0.right)))
+	require.Equal(t, r0.hash, hashNode(nodeHash(r0.left), d, nodeHash(r0.right)))
+
+	h := lh + 1
+	if rh > lh {
+		h = rh + 1
+	}
+	require.Equal(t, r0.height, h)
+	return h
+}
+
+// Returns the RangeHash of a tree built from the elements of model in the range.
+func modelHash(model *rb.Tree, start container.Key, boundary container.Key) Hash {
+	tr := New(nil)
+	model.Range(start, boundary, func(ele container.Element) bool {
+		tr.Insert(ele.Key(), ele.Value())
+		return true
+	})
+	return tr.RangeHash(nil, nil)
+}
+
+func TestTree(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	tr := New(nil)
+	model := rb.New()
+
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 200; i++ {
+			k := container.Int(r.Intn(300))
+			v := r.Intn(1000)
+			switch r.Intn(9) {
+			case 0:
+				ele, ok := tr.Insert(k, v)
+				mEle, mOK := model.Insert(k, v)
+				require.Equal(t, ok, mOK)
+				require.Equal(t, ele.Value(), mEle.Value())
+			case 1:
+				ele := tr.Delete(k)
+				require.Equal(t, ele != nil, model.Delete(k) != nil)
+			case 2:
+				ele := tr.Update(k, v)
+				mEle := model.Search(k)
+				require.Equal(t, ele != nil, mEle != nil)
+				if ele != nil {
+					require.Equal(t, ele.Value(), mEle.Value())
+				}
+				model.Update(k, v)
+			case 3:
+				_, ok := tr.Upsert(k, v)
+				_, mOK := model.Upsert(k, v)
+				require.Equal(t, ok, mOK)
+			case 4:
+				ele, old := tr.UpdateInPlace(k, v)
+				mEle, mOld := model.UpdateInPlace(k, v)
+				require.Equal(t, ele != nil, mEle != nil)
+				require.Equal(t, old, mOld)
+			case 5:
+				_, ok := tr.UpsertInPlace(k, v)
+				_, mOK := model.UpsertInPlace(k, v)
+				require.Equal(t, ok, mOK)
+			case 6:
+				f := func(ele container.Element, exists bool) (container.Value, bool) {
+					return v, v%3 != 0
+				}
+				ele := tr.Compute(k, f)
+				mEle := model.Compute(k, f)
+				require.Equal(t, ele != nil, mEle != nil)
+			case 7:
+				f := func() container.Value { return v }
+				ele, ok := tr.GetOrInsert(k, f)
+				mEle, mOK := model.GetOrInsert(k, f)
+				require.Equal(t, ok, mOK)
+				require.Equal(t, ele.Value(), mEle.Value())
+			default:
+				if r.Intn(10) == 0 {
+					boundary := k + container.Int(r.Intn(30))
+					var removed []interface{}
+					n := tr.DeleteRange(k, boundary, func(ele container.Element) {
+						removed = append(removed, ele.Key(), ele.Value())
+					})
+					require.Equal(t, removed, tests.Pairs(model, k, boundary, false))
+					require.Equal(t, n, model.DeleteRange(k, boundary, nil))
+				}
+			}
+		}
+
+		checkNode(t, tr.root)
+		require.Equal(t, tr.Len(), model.Len())
+		require.Equal(t, tests.Pairs(tr, nil, nil, false), tests.Pairs(model, nil, nil, false))
+		require.Equal(t, tr.RangeHash(nil, nil), modelHash(model, nil, nil))
+
+		for i := 0; i < 20; i++ {
+			start := container.Int(r.Intn(320) - 10)
+			boundary := start + container.Int(r.Intn(100))
+			require.Equal(t, tr.RangeHash(start, boundary), modelHash(model, start, boundary))
+			require.Equal(t, tr.RangeHash(start, nil), modelHash(model, start, nil))
+			require.Equal(t, tr.RangeHash(nil, boundary), modelHash(model, nil, boundary))
+
+			keys := tests.Pairs(model, start, boundary, false)
+			require.Equal(t, tr.RangeLen(start, boundary), len(keys)/2)
+			if len(keys) == 0 {
+				require.Nil(t, tr.Median(start, boundary))
+			} else {
+				require.Equal(t, tr.Median(start, boundary), keys[len(keys)/4*2])
+			}
+		}
+	}
+
+	tr.Clear()
+	require.Equal(t, tr.Len(), 0)
+	require.Equal(t, tr.RootHash(), Hash{})
+	require.Equal(t, tr.RangeHash(nil, nil), New(nil).RangeHash(nil, nil))
+}
+
+func TestTree_Unregistered(t *testing.T) {
+	type unregistered struct{}
+
+	tr := New(nil)
+	for i := 0; i < 64; i++ {
+		require.Nil(t, tr.Put(container.Int(i), i))
+	}
+	require.Nil(t, tr.Put(container.Int(1), 11))
+	require.Equal(t, tr.Search(container.Int(1)).Value(), 11)
+	require.Equal(t, tr.Len(), 64)
+
+	root := tr.RootHash()
+	expected := tests.Pairs(tr, nil, nil, false)
+	check := func() {
+		require.Equal(t, tr.Len(), 64)
+		require.Equal(t, tr.RootHash(), root)
+		require.Equal(t, tests.Pairs(tr, nil, nil, false), expected)
+		checkNode(t, tr.root)
+	}
+
+	// Put returns an error without changes.
+	require.NotNil(t, tr.Put(container.Int(1), unregistered{}))
+	require.NotNil(t, tr.Put(container.Int(100), unregistered{}))
+	check()
+
+	// The changes panic without changes.
+	changes := []func(){
+		func() { tr.Insert(container.Int(100), unregistered{}) },
+		func() { tr.Update(container.Int(1), unregistered{}) },
+		func() { tr.Upsert(container.Int(1), unregistered{}) },
+		func() { tr.UpdateInPlace(container.Int(1), unregistered{}) },
+		func() { tr.UpsertInPlace(container.Int(1), unregistered{}) },
+		func() { tr.UpsertInPlace(container.Int(100), unregistered{}) },
+		func() {
+			tr.Compute(container.Int(1), func(ele container.Element, exists bool) (container.Value, bool) {
+				return unregistered{}, true
+			})
+		},
+	}
+	for _, f := range changes {
+		require.Panics(t, f)
+		check()
+	}
+}
+
+func TestTree_RangeHash(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	a, b := New(nil), New(nil)
+
+	// The trees with the same elements have the same RangeHash, whatever the insertion order.
+	for _, i := range r.Perm(500) {
+		a.Insert(container.Int(i), i)
+	}
+	for i := 0; i < 500; i++ {
+		b.Insert(container.Int(i), i)
+	}
+	require.Equal(t, a.RangeHash(nil, nil), b.RangeHash(nil, nil))
+	require.Equal(t, a.RangeHash(container.Int(100), container.Int(200)), b.RangeHash(container.Int(100), container.Int(200)))
+
+	b.UpdateInPlace(container.Int(150), -1)
+	require.NotEqual(t, a.RangeHash(nil, nil), b.RangeHash(nil, nil))
+	require.NotEqual(t, a.RangeHash(container.Int(100), container.Int(200)), b.RangeHash(container.Int(100), container.Int(200)))
+	require.Equal(t, a.RangeHash(container.Int(151), container.Int(300)), b.RangeHash(container.Int(151), container.Int(300)))
+}
+
+func TestTree_Diff(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for _, d := range []int{0, 1, 10, 100} {
+		a, b := New(nil), New(nil)
+		for i := 0; i < 1000; i++ {
+			a.Insert(container.Int(i*2), i)
+			b.Insert(container.Int(i*2), i)
+		}
+		for i := 0; i < d; i++ {
+			k := container.Int(r.Intn(2100))
+			switch r.Intn(3) {
+			case 0:
+				a.Upsert(k, -1)
+			case 1:
+				b.Delete(k)
+			default:
+				b.Upsert(k, -2)
+			}
+		}
+
+		// The different keys by brute force.
+		var keys []container.Key
+		ia, ib := a.Iter(nil, nil), b.Iter(nil, nil)
+		ea, eb := ia.Next(), ib.Next()
+		for ea != nil || eb != nil {
+			switch {
+			case eb == nil || (ea != nil && ea.Key().Compare(eb.Key()) == -1):
+				keys = append(keys, ea.Key())
+				ea = ia.Next()
+			case ea == nil || ea.Key().Compare(eb.Key()) == 1:
+				keys = append(keys, eb.Key())
+				eb = ib.Next()
+			default:
+				if ea.Value() != eb.Value() {
+					keys = append(keys, ea.Key())
+				}
+				ea, eb = ia.Next(), ib.Next()
+			}
+		}
+
+		var ranges [][2]container.Key
+		a.Diff(b, func(start container.Key, boundary container.Key) bool {
+			require.NotEqual(t, a.RangeHash(start, boundary), b.RangeHash(start, boundary))
+			require.LessOrEqual(t, a.RangeLen(start, boundary), 1)
+			require.LessOrEqual(t, b.RangeLen(start, boundary), 1)
+			if n := len(ranges); n != 0 {
+				require.NotEqual(t, ranges[n-1][1].Compare(start), 1)
+			}
+			ranges = append(ranges, [2]container.Key{start, boundary})
+			return true
+		})
+
+		// Each different key is in a range.
+		j := 0
+		for _, k := range keys {
+			for j < len(ranges) && ranges[j][1] != nil && ranges[j][1].Compare(k) != 1 {
+				j++
+			}
+			require.Less(t, j, len(ranges))
+			if ranges[j][0] != nil {
+				require.NotEqual(t, ranges[j][0].Compare(k), 1)
+			}
+		}
+		require.LessOrEqual(t, len(ranges), len(keys))
+		if len(keys) == 0 {
+			require.Equal(t, a.RangeHash(nil, nil), b.RangeHash(nil, nil))
+		}
+
+		// Stops the finding.
+		n := 0
+		a.Diff(b, func(start container.Key, boundary container.Key) bool {
+			n++
+			return false
+		})
+		require.Equal(t, n, min(1, len(ranges)))
+	}
+}
+
+func TestTree_Prove(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 300; i++ {
+		tr.Insert(container.Int(i), i)
+	}
+	root := tr.RootHash()
+
+	for i := 0; i < 300; i++ {
+		proof, ok := tr.Prove(container.Int(i))
+		require.True(t, ok)
+		ok, err := proof.Verify(root, container.Int(i), i, nil)
+		require.Nil(t, err)
+		require.True(t, ok)
+
+		ok, err = proof.Verify(root, container.Int(i), i+1, nil)
+		require.Nil(t, err)
+		require.False(t, ok)
+		ok, err = proof.Verify(Hash{}, container.Int(i), i, nil)
+		require.Nil(t, err)
+		require.False(t, ok)
+	}
+	_, ok := tr.Prove(container.Int(300))
+	require.False(t, ok)
+
+	// The proofs are invalid after changed.
+	proof, _ := tr.Prove(container.Int(10))
+	tr.UpdateInPlace(container.Int(20), -1)
+	ok, err := proof.Verify(tr.RootHash(), container.Int(10), 10, nil)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	_, err = proof.Verify(root, container.Int(10), struct{}{}, nil)
+	require.NotNil(t, err)
+	require.Panics(t, func() {
+		tr.Insert(container.Int(1000), struct{}{})
+	})
+}
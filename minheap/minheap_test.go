@@ -139,3 +139,33 @@ func TestMinHeap(t *testing.T) {
 	}
 
 }
+
+func TestMinHeap_All(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	h := New(8)
+	for _, v := range r.Perm(200) {
+		h.Push(container.Int(v%50), v)
+	}
+
+	var keys []container.Key
+	for k := range h.Keys() {
+		keys = append(keys, k)
+	}
+	require.Equal(t, len(keys), 200)
+	// The heap is not changed, and the keys are in pop order.
+	checkCorrect(t, h)
+	for _, k := range keys {
+		require.Equal(t, h.Pop().Key(), k)
+	}
+
+	// Breaks early.
+	h.Push(container.Int(1), 1)
+	h.Push(container.Int(2), 2)
+	n := 0
+	for range h.All() {
+		n++
+		break
+	}
+	require.Equal(t, n, 1)
+	require.Equal(t, h.Len(), 2)
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package mvcc
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/yu31/structs-go/container"
+)
+
+// ErrBelowWatermark is returned when writes with a timestamp less than the watermark.
+var ErrBelowWatermark = errors.New("mvcc: timestamp is below the watermark")
+
+var _ container.Element = (*element)(nil)
+
+// element is a snapshot of a key and its visible value.
+type element struct {
+	key   container.Key
+	value container.Value
+}
+
+// Key returns the key.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value.
+func (e *element) Value() container.Value {
+	return e.value
+}
+
+// version is a value of key written at the timestamp.
+type version struct {
+	ts      uint64
+	value   container.Value
+	deleted bool // The version is a tombstone if deleted is true.
+}
+
+// chain is the versions of key in ascending order by timestamp.
+type chain []version
+
+// Returns the index of the last version that ts <= the given ts, -1 if not found.
+func (c chain) visible(ts uint64) int {
+	return sort.Search(len(c), func(i int) bool {
+		return c[i].ts > ts
+	}) - 1
+}
+
+// Store implements a multi-version container layered on a container.Container, each key holds a version chain.
+// It is safe for concurrent use by multiple goroutines.
+//
+// A read at timestamp ts observes the latest version of each key that written at or before ts,
+// the key is invisible if the latest version is a deletion. A write at a timestamp that already has a version
+// for the key replaces that version. So the writes may come in any order of timestamps.
+//
+// The GC removes the versions that are invisible to any read at or after the watermark.
+// Thus the reads at or after the watermark are exact, the reads before the watermark are undefined,
+// and the writes before the watermark are rejected with ErrBelowWatermark.
+type Store struct {
+	mu        sync.RWMutex
+	ctr       container.Container
+	watermark uint64
+}
+
+// New creates a Store with an empty container, such as rb.New() or skip.New().
+func New(ctr container.Container) *Store {
+	return &Store{
+		ctr:       ctr,
+		watermark: 0,
+	}
+}
+
+// Len returns the number of keys that have versions, including the keys deleted but not yet garbage collected.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	n := s.ctr.Len()
+	s.mu.RUnlock()
+	return n
+}
+
+// Watermark returns the current watermark.
+func (s *Store) Watermark() uint64 {
+	s.mu.RLock()
+	w := s.watermark
+	s.mu.RUnlock()
+	return w
+}
+
+// Put writes the value of key at the timestamp ts.
+func (s *Store) Put(k container.Key, v container.Value, ts uint64) error {
+	return s.write(k, version{ts: ts, value: v})
+}
+
+// Delete writes a deletion of key at the timestamp ts.
+// The key is invisible for the reads at or after ts until it is put again.
+func (s *Store) Delete(k container.Key, ts uint64) error {
+	return s.write(k, version{ts: ts, deleted: true})
+}
+
+// Get returns the value of key visible at the timestamp ts.
+// The bool result is false if the key does not exist or has been deleted at ts.
+func (s *Store) Get(k container.Key, ts uint64) (container.Value, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ele := s.ctr.Search(k)
+	if ele == nil {
+		return nil, false
+	}
+	return lookup(ele.Value().(chain), ts)
+}
+
+// Range calls f sequentially each key and its value visible at the timestamp ts in the range start <= x < boundary.
+// If f returns false, range stops the iteration.
+//
+// The read lock is held during the iteration, so f must not call the write methods of the Store.
+func (s *Store) Range(start container.Key, boundary container.Key, ts uint64, f func(ele container.Element) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	s.ctr.Range(start, boundary, func(ele container.Element) bool {
+		v, ok := lookup(ele.Value().(chain), ts)
+		if !ok {
+			return true
+		}
+		return f(&element{key: ele.Key(), value: v})
+	})
+}
+
+// GC advances the watermark and removes the versions that are invisible to any read at or after the watermark.
+// The keys without versions are removed from the container. Returns the number of removed versions.
+//
+// Nothing happens if the given watermark is less than or equal to the current one.
+func (s *Store) GC(watermark uint64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if watermark <= s.watermark {
+		return 0
+	}
+	s.watermark = watermark
+
+	n := 0
+	var empty []container.Key
+	var trimmed []container.Element
+	s.ctr.Range(nil, nil, func(ele container.Element) bool {
+		c := ele.Value().(chain)
+		i := c.visible(watermark)
+		if i < 0 {
+			return true
+		}
+		// The version i is visible to the reads at watermark, keep it unless it's a deletion.
+		if !c[i].deleted {
+			i--
+		}
+		if i < 0 {
+			return true
+		}
+		n += i + 1
+		if i+1 == len(c) {
+			empty = append(empty, ele.Key())
+			return true
+		}
+		trimmed = append(trimmed, &element{key: ele.Key(), value: append(chain(nil), c[i+1:]...)})
+		return true
+	})
+	for _, k := range empty {
+		s.ctr.Delete(k)
+	}
+	for _, ele := range trimmed {
+		s.ctr.UpdateInPlace(ele.Key(), ele.Value())
+	}
+	return n
+}
+
+// Writes a version of key.
+func (s *Store) write(k container.Key, ver version) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ver.ts < s.watermark {
+		return ErrBelowWatermark
+	}
+	s.ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		if !exists {
+			return chain{ver}, true
+		}
+		c := ele.Value().(chain)
+		i := c.visible(ver.ts)
+		if i >= 0 && c[i].ts == ver.ts {
+			c[i] = ver
+			return c, true
+		}
+		// Inserts the version after i, it's appended in most case.
+		c = append(c, version{})
+		copy(c[i+2:], c[i+1:])
+		c[i+1] = ver
+		return c, true
+	})
+	return nil
+}
+
+// Returns the value visible at the timestamp ts in the version chain.
+func lookup(c chain, ts uint64) (container.Value, bool) {
+	i := c.visible(ts)
+	if i < 0 || c[i].deleted {
+		return nil, false
+	}
+	return c[i].value, true
+}
@@ -0,0 +1,214 @@
+package mvcc
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+var containers = map[string]func() container.Container{
+	"rbtree": func() container.Container {
+		return rb.New()
+	},
+	"skiplist": func() container.Container {
+		return skip.New()
+	},
+}
+
+// model is a reference model that keeps all writes without garbage collection.
+type model map[int]map[uint64]modelVersion
+
+type modelVersion struct {
+	value   int
+	deleted bool
+}
+
+func (m model) put(k int, ver modelVersion, ts uint64) {
+	if m[k] == nil {
+		m[k] = make(map[uint64]modelVersion)
+	}
+	m[k][ts] = ver
+}
+
+func (m model) get(k int, ts uint64) (int, bool) {
+	found := false
+	var latest uint64
+	for t := range m[k] {
+		if t <= ts && (!found || t > latest) {
+			found = true
+			latest = t
+		}
+	}
+	if !found || m[k][latest].deleted {
+		return 0, false
+	}
+	return m[k][latest].value, true
+}
+
+func (m model) keys(ts uint64) []int {
+	var keys []int
+	for k := range m {
+		if _, ok := m.get(k, ts); ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func TestStore(t *testing.T) {
+	process := func(s *Store) {
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		ref := make(model)
+		maxKey := 64
+
+		check := func() {
+			w := s.Watermark()
+			for x := 0; x < 32; x++ {
+				ts := w + uint64(r.Intn(64))
+				for k := 0; k < maxKey; k++ {
+					v1, ok1 := s.Get(container.Int(k), ts)
+					v2, ok2 := ref.get(k, ts)
+					require.Equal(t, ok1, ok2)
+					if ok1 {
+						require.Equal(t, v1, v2)
+					}
+				}
+
+				var keys []int
+				s.Range(nil, nil, ts, func(ele container.Element) bool {
+					v, _ := ref.get(int(ele.Key().(container.Int)), ts)
+					require.Equal(t, ele.Value(), v)
+					keys = append(keys, int(ele.Key().(container.Int)))
+					return true
+				})
+				require.Equal(t, keys, ref.keys(ts))
+			}
+		}
+
+		for i := 0; i < 4096; i++ {
+			k := r.Intn(maxKey)
+			ts := s.Watermark() + uint64(r.Intn(64))
+			switch r.Intn(8) {
+			case 0:
+				ref.put(k, modelVersion{deleted: true}, ts)
+				require.Nil(t, s.Delete(container.Int(k), ts))
+			case 1:
+				if r.Intn(8) == 0 {
+					s.GC(s.Watermark() + uint64(r.Intn(16)))
+					check()
+				}
+			default:
+				ref.put(k, modelVersion{value: i}, ts)
+				require.Nil(t, s.Put(container.Int(k), i, ts))
+			}
+		}
+		check()
+
+		// Writes below the watermark are rejected.
+		w := s.Watermark()
+		if w > 0 {
+			require.Equal(t, s.Put(container.Int(0), 0, w-1), ErrBelowWatermark)
+			require.Equal(t, s.Delete(container.Int(0), w-1), ErrBelowWatermark)
+		}
+
+		// All versions are collected after the latest write is deleted.
+		ts := w + 1024
+		for k := 0; k < maxKey; k++ {
+			require.Nil(t, s.Delete(container.Int(k), ts))
+		}
+		s.GC(ts)
+		require.Equal(t, s.Len(), 0)
+	}
+
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			process(New(f()))
+		})
+	}
+}
+
+func TestStore_GC(t *testing.T) {
+	s := New(rb.New())
+
+	require.Nil(t, s.Put(container.Int(1), "a", 10))
+	require.Nil(t, s.Put(container.Int(1), "b", 20))
+	require.Nil(t, s.Put(container.Int(1), "c", 30))
+	require.Nil(t, s.Put(container.Int(2), "x", 10))
+	require.Nil(t, s.Delete(container.Int(2), 20))
+
+	// Writes out of order and replaces a version.
+	require.Nil(t, s.Put(container.Int(1), "B", 20))
+	require.Nil(t, s.Put(container.Int(1), "A", 5))
+
+	v, ok := s.Get(container.Int(1), 5)
+	require.True(t, ok)
+	require.Equal(t, v, "A")
+	v, _ = s.Get(container.Int(1), 25)
+	require.Equal(t, v, "B")
+	_, ok = s.Get(container.Int(2), 20)
+	require.False(t, ok)
+
+	// Keeps the version visible at watermark 25 for key 1, and removes key 2.
+	require.Equal(t, s.GC(25), 4)
+	require.Equal(t, s.GC(25), 0)
+	require.Equal(t, s.Watermark(), uint64(25))
+	require.Equal(t, s.Len(), 1)
+
+	v, _ = s.Get(container.Int(1), 25)
+	require.Equal(t, v, "B")
+	v, _ = s.Get(container.Int(1), 30)
+	require.Equal(t, v, "c")
+	_, ok = s.Get(container.Int(2), 25)
+	require.False(t, ok)
+}
+
+// Run with `go test -race` to check the data race.
+func TestStore_Concurrent(t *testing.T) {
+	s := New(rb.New())
+
+	const (
+		writers = 4
+		loops   = 1000
+	)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < loops; i++ {
+				// Each writer writes its own keys with increasing timestamps.
+				require.Nil(t, s.Put(container.Int(w), i, uint64(i+loops)))
+			}
+		}(w)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < loops; i++ {
+			// The reads as of a past timestamp are consistent while writes continue.
+			s.Range(nil, nil, uint64(loops), func(ele container.Element) bool {
+				require.Equal(t, ele.Value(), 0)
+				return true
+			})
+			s.GC(uint64(loops))
+		}
+	}()
+	wg.Wait()
+
+	for w := 0; w < writers; w++ {
+		v, ok := s.Get(container.Int(w), uint64(loops*2))
+		require.True(t, ok)
+		require.Equal(t, v, loops-1)
+	}
+}
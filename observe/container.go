@@ -0,0 +1,331 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package observe
+
+import (
+	"iter"
+	"sync"
+
+	"github.com/yu31/structs-go/container"
+)
+
+var _ container.Container = (*Container)(nil)
+
+// Container wraps a container.Container to emit an Event for each change to the registered Watchers.
+// The events are emitted after the change applied, in the order of changes.
+//
+// The changes made by MutableElement.SetValue on the returned elements are not observed,
+// use the UpdateInPlace method instead. Like the wrapped container, the Container is not safe for
+// concurrent writes, but the Watchers can be registered and closed from any goroutine.
+type Container struct {
+	ctr container.Container
+
+	mu       sync.RWMutex // Protects watchers.
+	watchers []*Watcher
+}
+
+// New creates a Container that wraps the given container.
+func New(ctr container.Container) *Container {
+	return &Container{
+		ctr: ctr,
+	}
+}
+
+// Listen registers f to receive all events synchronously. It is same as Watch(nil, nil, f).
+func (c *Container) Listen(f func(ev Event)) *Watcher {
+	return c.Watch(nil, nil, f)
+}
+
+// Watch registers f to receive the events of keys in range start <= x < boundary synchronously.
+// If the start is nil, the range has no lower bound. If boundary is nil, the range has no upper bound.
+//
+// The f is called by the writer after the change applied, and it must not call Close of the Watcher.
+// The f may change the Container, the events of these changes are delivered before f returns.
+func (c *Container) Watch(start container.Key, boundary container.Key, f func(ev Event)) *Watcher {
+	w := &Watcher{
+		owner:    c,
+		start:    start,
+		boundary: boundary,
+		f:        f,
+	}
+	w.idle = sync.NewCond(&w.mu)
+	c.register(w)
+	return w
+}
+
+// WatchChan registers a Watcher to receive the events of keys in range start <= x < boundary
+// through a buffered channel with the given size. The policy decides what to do when the channel is full.
+func (c *Container) WatchChan(start container.Key, boundary container.Key, size int, policy OverflowPolicy) *Watcher {
+	w := &Watcher{
+		owner:    c,
+		start:    start,
+		boundary: boundary,
+		ch:       make(chan Event, size),
+		done:     make(chan struct{}),
+		policy:   policy,
+	}
+	c.register(w)
+	return w
+}
+
+// Len returns the number of elements.
+func (c *Container) Len() int {
+	return c.ctr.Len()
+}
+
+// Insert inserts a new element if the key doesn't exist, or returns the existing element for the key if present.
+// The bool result is true if an element was inserted, false if searched.
+func (c *Container) Insert(k container.Key, v container.Value) (container.Element, bool) {
+	ele, ok := c.ctr.Insert(k, v)
+	if ok {
+		c.emit(Event{Type: EventInsert, Key: ele.Key(), NewValue: v})
+	}
+	return ele, ok
+}
+
+// Delete removes and returns the element of a given key.
+// Returns nil if key not found.
+func (c *Container) Delete(k container.Key) container.Element {
+	ele := c.ctr.Delete(k)
+	if ele != nil {
+		c.emit(Event{Type: EventDelete, Key: ele.Key(), OldValue: ele.Value()})
+	}
+	return ele
+}
+
+// Update updates an element with the given key and value, And returns the old element of key.
+// Returns nil if the key not be found.
+func (c *Container) Update(k container.Key, v container.Value) container.Element {
+	ele := c.ctr.Update(k, v)
+	if ele != nil {
+		c.emit(Event{Type: EventUpdate, Key: ele.Key(), OldValue: ele.Value(), NewValue: v})
+	}
+	return ele
+}
+
+// Upsert inserts or updates an element by giving key and value.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (c *Container) Upsert(k container.Key, v container.Value) (container.Element, bool) {
+	ele, ok := c.ctr.Upsert(k, v)
+	if ok {
+		c.emit(Event{Type: EventInsert, Key: ele.Key(), NewValue: v})
+	} else {
+		c.emit(Event{Type: EventUpdate, Key: ele.Key(), OldValue: ele.Value(), NewValue: v})
+	}
+	return ele, ok
+}
+
+// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place.
+// Returns the element of key and its old value, returns nil if the key not be found.
+func (c *Container) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	ele, old := c.ctr.UpdateInPlace(k, v)
+	if ele != nil {
+		c.emit(Event{Type: EventUpdate, Key: ele.Key(), OldValue: old, NewValue: v})
+	}
+	return ele, old
+}
+
+// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (c *Container) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	// The old value can not be got from the element after it changed in place, searches it if there are Watchers.
+	var old container.Value
+	if c.watching() {
+		if ele := c.ctr.Search(k); ele != nil {
+			old = ele.Value()
+		}
+	}
+
+	ele, ok := c.ctr.UpsertInPlace(k, v)
+	if ok {
+		c.emit(Event{Type: EventInsert, Key: ele.Key(), NewValue: v})
+	} else {
+		c.emit(Event{Type: EventUpdate, Key: ele.Key(), OldValue: old, NewValue: v})
+	}
+	return ele, ok
+}
+
+// Search searches the element of a given key.
+// Returns nil if key not found.
+func (c *Container) Search(k container.Key) container.Element {
+	return c.ctr.Search(k)
+}
+
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f.
+func (c *Container) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) container.Element {
+	var ev *Event
+	ele := c.ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		v, keep := f(ele, exists)
+		switch {
+		case !exists && keep:
+			ev = &Event{Type: EventInsert, Key: k, NewValue: v}
+		case exists && keep:
+			ev = &Event{Type: EventUpdate, Key: ele.Key(), OldValue: ele.Value(), NewValue: v}
+		case exists && !keep:
+			ev = &Event{Type: EventDelete, Key: ele.Key(), OldValue: ele.Value()}
+		}
+		return v, keep
+	})
+	if ev != nil {
+		c.emit(*ev)
+	}
+	return ele
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+func (c *Container) GetOrInsert(k container.Key, f func() container.Value) (container.Element, bool) {
+	ele, ok := c.ctr.GetOrInsert(k, f)
+	if ok {
+		c.emit(Event{Type: EventInsert, Key: ele.Key(), NewValue: ele.Value()})
+	}
+	return ele, ok
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+//
+// An EventDelete is emitted for each removed element after all of them removed.
+func (c *Container) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	if !c.watching() {
+		return c.ctr.DeleteRange(start, boundary, f)
+	}
+
+	var events []Event
+	n := c.ctr.DeleteRange(start, boundary, func(ele container.Element) {
+		events = append(events, Event{Type: EventDelete, Key: ele.Key(), OldValue: ele.Value()})
+		if f != nil {
+			f(ele)
+		}
+	})
+	for _, ev := range events {
+		c.emit(ev)
+	}
+	return n
+}
+
+// Clear removes all elements in the Container.
+//
+// An EventDelete is emitted for each element, so it costs O(n) if there are Watchers.
+func (c *Container) Clear() {
+	if !c.watching() {
+		c.ctr.Clear()
+		return
+	}
+	c.DeleteRange(nil, nil, nil)
+}
+
+// Iter return an Iterator of the wrapped container.
+func (c *Container) Iter(start container.Key, boundary container.Key) container.Iterator {
+	return c.ctr.Iter(start, boundary)
+}
+
+// IterReverse return an reversed Iterator of the wrapped container.
+func (c *Container) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	return c.ctr.IterReverse(start, boundary)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (c *Container) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(c)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (c *Container) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(c)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (c *Container) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(c, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (c *Container) Keys() iter.Seq[container.Key] {
+	return container.Keys(c)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (c *Container) Values() iter.Seq[container.Value] {
+	return container.Values(c)
+}
+
+// Range calls f sequentially each element present in the Container.
+// If f returns false, range stops the iteration.
+func (c *Container) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	c.ctr.Range(start, boundary, f)
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+func (c *Container) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	c.ctr.Reverse(start, boundary, f)
+}
+
+// LastLT searches for the last element that less than the key.
+func (c *Container) LastLT(k container.Key) container.Element {
+	return c.ctr.LastLT(k)
+}
+
+// LastLE search for the last element that less than or equal to the key.
+func (c *Container) LastLE(k container.Key) container.Element {
+	return c.ctr.LastLE(k)
+}
+
+// FirstGT search for the first element that greater than to the key.
+func (c *Container) FirstGT(k container.Key) container.Element {
+	return c.ctr.FirstGT(k)
+}
+
+// FirstGE search for the first element that greater than or equal to the key.
+func (c *Container) FirstGE(k container.Key) container.Element {
+	return c.ctr.FirstGE(k)
+}
+
+// Registers a Watcher.
+func (c *Container) register(w *Watcher) {
+	c.mu.Lock()
+	c.watchers = append(c.watchers, w)
+	c.mu.Unlock()
+}
+
+// Unregisters a Watcher.
+func (c *Container) unregister(w *Watcher) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.watchers {
+		if c.watchers[i] == w {
+			// Copy on write, so that the emitting snapshot is not affected.
+			watchers := make([]*Watcher, 0, len(c.watchers)-1)
+			watchers = append(watchers, c.watchers[:i]...)
+			c.watchers = append(watchers, c.watchers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Represents whether there are Watchers registered.
+func (c *Container) watching() bool {
+	c.mu.RLock()
+	ok := len(c.watchers) != 0
+	c.mu.RUnlock()
+	return ok
+}
+
+// Delivers the event to the Watchers that match its key.
+func (c *Container) emit(ev Event) {
+	c.mu.RLock()
+	watchers := c.watchers
+	c.mu.RUnlock()
+
+	for _, w := range watchers {
+		if w.match(ev.Key) {
+			w.deliver(ev)
+		}
+	}
+}
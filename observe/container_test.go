@@ -0,0 +1,308 @@
+package observe
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/avl"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+var containers = map[string]func() container.Container{
+	"avltree": func() container.Container {
+		return avl.New()
+	},
+	"rbtree": func() container.Container {
+		return rb.New()
+	},
+	"skiplist": func() container.Container {
+		return skip.New()
+	},
+}
+
+func TestContainer_Events(t *testing.T) {
+	c := New(rb.New())
+
+	var events []Event
+	w := c.Listen(func(ev Event) {
+		events = append(events, ev)
+	})
+
+	c.Insert(container.Int(1), "a")
+	c.Insert(container.Int(1), "x") // No event for the existing key.
+	c.Update(container.Int(1), "b")
+	c.Update(container.Int(2), "x") // No event for the missing key.
+	c.Upsert(container.Int(2), "c")
+	c.UpsertInPlace(container.Int(2), "d")
+	c.UpdateInPlace(container.Int(1), "e")
+	c.Compute(container.Int(3), func(ele container.Element, exists bool) (container.Value, bool) {
+		return "f", true
+	})
+	c.GetOrInsert(container.Int(4), func() container.Value {
+		return "g"
+	})
+	c.Delete(container.Int(1))
+	c.DeleteRange(container.Int(2), container.Int(4), nil)
+	c.Clear()
+
+	require.Equal(t, events, []Event{
+		{Type: EventInsert, Key: container.Int(1), NewValue: "a"},
+		{Type: EventUpdate, Key: container.Int(1), OldValue: "a", NewValue: "b"},
+		{Type: EventInsert, Key: container.Int(2), NewValue: "c"},
+		{Type: EventUpdate, Key: container.Int(2), OldValue: "c", NewValue: "d"},
+		{Type: EventUpdate, Key: container.Int(1), OldValue: "b", NewValue: "e"},
+		{Type: EventInsert, Key: container.Int(3), NewValue: "f"},
+		{Type: EventInsert, Key: container.Int(4), NewValue: "g"},
+		{Type: EventDelete, Key: container.Int(1), OldValue: "e"},
+		{Type: EventDelete, Key: container.Int(2), OldValue: "d"},
+		{Type: EventDelete, Key: container.Int(3), OldValue: "f"},
+		{Type: EventDelete, Key: container.Int(4), OldValue: "g"},
+	})
+
+	// No events after closed.
+	w.Close()
+	w.Close()
+	c.Insert(container.Int(1), "a")
+	require.Equal(t, len(events), 11)
+}
+
+// The events can be applied to a mirror to keep it same as the Container.
+func TestContainer_Mirror(t *testing.T) {
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(time.Now().UnixNano()))
+			c := New(f())
+			mirror := rb.New()
+			// A mirror of range [32, 96).
+			partial := rb.New()
+
+			apply := func(m container.Container) func(ev Event) {
+				return func(ev Event) {
+					switch ev.Type {
+					case EventInsert:
+						_, ok := m.Insert(ev.Key, ev.NewValue)
+						require.True(t, ok)
+					case EventUpdate:
+						require.Equal(t, m.Update(ev.Key, ev.NewValue).Value(), ev.OldValue)
+					case EventDelete:
+						require.Equal(t, m.Delete(ev.Key).Value(), ev.OldValue)
+					}
+				}
+			}
+			c.Listen(apply(mirror))
+			c.Watch(container.Int(32), container.Int(96), apply(partial))
+
+			for i := 0; i < 4096; i++ {
+				k := container.Int(r.Intn(128))
+				switch r.Intn(8) {
+				case 0:
+					c.Insert(k, i)
+				case 1:
+					c.Delete(k)
+				case 2:
+					c.Update(k, i)
+				case 3:
+					c.Upsert(k, i)
+				case 4:
+					c.UpsertInPlace(k, i)
+				case 5:
+					c.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+						return i, r.Intn(2) == 0
+					})
+				case 6:
+					c.DeleteRange(k, k+container.Int(r.Intn(8)), nil)
+				case 7:
+					if r.Intn(64) == 0 {
+						c.Clear()
+					}
+				}
+			}
+
+			var expected, actual []container.Key
+			c.Range(nil, nil, func(ele container.Element) bool {
+				expected = append(expected, ele.Key())
+				return true
+			})
+			mirror.Range(nil, nil, func(ele container.Element) bool {
+				actual = append(actual, ele.Key())
+				require.Equal(t, c.Search(ele.Key()).Value(), ele.Value())
+				return true
+			})
+			require.Equal(t, actual, expected)
+
+			expected, actual = nil, nil
+			c.Range(container.Int(32), container.Int(96), func(ele container.Element) bool {
+				expected = append(expected, ele.Key())
+				return true
+			})
+			partial.Range(nil, nil, func(ele container.Element) bool {
+				actual = append(actual, ele.Key())
+				return true
+			})
+			require.Equal(t, actual, expected)
+		})
+	}
+}
+
+func TestContainer_WatchChan(t *testing.T) {
+	c := New(rb.New())
+
+	newest := c.WatchChan(nil, nil, 2, DropNewest)
+	oldest := c.WatchChan(nil, nil, 2, DropOldest)
+	ranged := c.WatchChan(container.Int(1), container.Int(2), 4, DropNewest)
+
+	for i := 0; i < 4; i++ {
+		c.Insert(container.Int(i), i)
+	}
+
+	require.Equal(t, newest.Dropped(), 2)
+	require.Equal(t, (<-newest.C()).Key, container.Int(0))
+	require.Equal(t, (<-newest.C()).Key, container.Int(1))
+
+	require.Equal(t, oldest.Dropped(), 2)
+	require.Equal(t, (<-oldest.C()).Key, container.Int(2))
+	require.Equal(t, (<-oldest.C()).Key, container.Int(3))
+
+	require.Equal(t, ranged.Dropped(), 0)
+	require.Equal(t, (<-ranged.C()).Key, container.Int(1))
+
+	for _, w := range []*Watcher{newest, oldest, ranged} {
+		w.Close()
+		_, ok := <-w.C()
+		require.False(t, ok)
+	}
+}
+
+// Run with `go test -race` to check the data race.
+func TestContainer_WatchChanBlock(t *testing.T) {
+	c := New(rb.New())
+	w := c.WatchChan(nil, nil, 1, Block)
+
+	const n = 1000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			c.Insert(container.Int(i), i)
+		}
+	}()
+
+	// Receives all events in order.
+	for i := 0; i < n/2; i++ {
+		ev := <-w.C()
+		require.Equal(t, ev.Key, container.Int(i))
+	}
+	// The blocked writer is released by Close.
+	w.Close()
+	wg.Wait()
+	require.Equal(t, c.Len(), n)
+}
+
+// upsertCounter counts the calls of UpsertInPlace, and fails the calls of UpdateInPlace and Insert.
+type upsertCounter struct {
+	container.Container
+	t *testing.T
+	n int
+}
+
+func (c *upsertCounter) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	c.n++
+	return c.Container.UpsertInPlace(k, v)
+}
+
+func (c *upsertCounter) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	c.t.Fatal("UpdateInPlace should not be called")
+	return nil, nil
+}
+
+func (c *upsertCounter) Insert(k container.Key, v container.Value) (container.Element, bool) {
+	c.t.Fatal("Insert should not be called")
+	return nil, false
+}
+
+func TestContainer_UpsertInPlace(t *testing.T) {
+	ctr := &upsertCounter{Container: rb.New(), t: t}
+	c := New(ctr)
+
+	// Without Watchers.
+	_, ok := c.UpsertInPlace(container.Int(1), "a")
+	require.True(t, ok)
+
+	var events []Event
+	c.Listen(func(ev Event) {
+		events = append(events, ev)
+	})
+	ele, ok := c.UpsertInPlace(container.Int(1), "b")
+	require.False(t, ok)
+	require.Equal(t, ele.Value(), "b")
+	ele, ok = c.UpsertInPlace(container.Int(2), "c")
+	require.True(t, ok)
+	require.Equal(t, ele.Value(), "c")
+
+	require.Equal(t, ctr.n, 3)
+	require.Equal(t, events, []Event{
+		{Type: EventUpdate, Key: container.Int(1), OldValue: "a", NewValue: "b"},
+		{Type: EventInsert, Key: container.Int(2), NewValue: "c"},
+	})
+}
+
+// Run with `go test -race` to check the data race.
+func TestContainer_CloseListener(t *testing.T) {
+	c := New(rb.New())
+
+	var mu sync.Mutex
+	closed := false
+	w := c.Listen(func(ev Event) {
+		// Widens the window between Close and the running delivery.
+		time.Sleep(time.Microsecond * 100)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.False(t, closed)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			c.Upsert(container.Int(i%16), i)
+		}
+	}()
+
+	time.Sleep(time.Millisecond)
+	w.Close()
+	mu.Lock()
+	closed = true
+	mu.Unlock()
+	<-done
+}
+
+func TestContainer_ListenerWrite(t *testing.T) {
+	c := New(rb.New())
+
+	// The f inserts the next key until 10, and deletes the key 0 once.
+	var events []Event
+	c.Listen(func(ev Event) {
+		events = append(events, ev)
+		k := ev.Key.(container.Int)
+		if ev.Type == EventInsert && k < 10 {
+			c.Insert(k+1, int(k+1))
+		}
+		if ev.Type == EventInsert && k == 10 {
+			c.Delete(container.Int(0))
+		}
+	})
+
+	c.Insert(container.Int(0), 0)
+	require.Equal(t, c.Len(), 10)
+	require.Equal(t, len(events), 12)
+	require.Equal(t, events[10], Event{Type: EventInsert, Key: container.Int(10), NewValue: 10})
+	require.Equal(t, events[11], Event{Type: EventDelete, Key: container.Int(0), OldValue: 0})
+}
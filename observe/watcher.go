@@ -0,0 +1,188 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package observe
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/yu31/structs-go/container"
+)
+
+// EventType is the type of Event.
+type EventType int
+
+const (
+	// EventInsert represents a new element is inserted.
+	EventInsert EventType = iota
+	// EventUpdate represents the value of an element is updated.
+	EventUpdate
+	// EventDelete represents an element is deleted.
+	EventDelete
+)
+
+// String returns the name of event type.
+func (t EventType) String() string {
+	switch t {
+	case EventInsert:
+		return "insert"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Event is a change of an element.
+// The OldValue is nil for EventInsert, and the NewValue is nil for EventDelete.
+type Event struct {
+	Type     EventType
+	Key      container.Key
+	OldValue container.Value
+	NewValue container.Value
+}
+
+// OverflowPolicy decides what to do when the buffered channel of Watcher is full.
+type OverflowPolicy int
+
+const (
+	// Block blocks the writer until the event is received or the Watcher is closed.
+	Block OverflowPolicy = iota
+	// DropNewest discards the new event.
+	DropNewest
+	// DropOldest discards the oldest event in the channel to make room for the new event.
+	DropOldest
+)
+
+// Watcher receives the events of keys in range start <= x < boundary.
+// The events are delivered either synchronously to a function or through a bounded buffered channel.
+type Watcher struct {
+	// The dropped accessed atomically, keep it at the beginning for 64-bit alignment.
+	dropped int64
+
+	owner    *Container
+	start    container.Key
+	boundary container.Key
+
+	f func(ev Event) // Not nil for synchronous delivery.
+
+	mu      sync.Mutex // Protects ch, closed and running, and is held during the delivery to channel.
+	idle    *sync.Cond // Signaled when the running deliveries to f finished.
+	running int        // The number of running deliveries to f, they are nested if f changes the Container.
+	ch      chan Event
+	done    chan struct{}
+	policy  OverflowPolicy
+	closed  bool
+	once    sync.Once
+}
+
+// C returns the channel of events, nil if the Watcher delivers synchronously.
+// The channel is closed after the Watcher closed.
+func (w *Watcher) C() <-chan Event {
+	return w.ch
+}
+
+// Dropped returns the number of events discarded due to overflow.
+func (w *Watcher) Dropped() int {
+	return int(atomic.LoadInt64(&w.dropped))
+}
+
+// Close unregisters the Watcher and closes its channel.
+// The Watcher never receives events after Close returns, it waits for the running delivery to finish.
+// Thus, Close must not be called by the f of a synchronous Watcher.
+func (w *Watcher) Close() {
+	w.once.Do(func() {
+		w.owner.unregister(w)
+		if w.ch != nil {
+			// Wakes up the blocked sender before acquiring the lock.
+			close(w.done)
+		}
+		w.mu.Lock()
+		w.closed = true
+		if w.ch != nil {
+			close(w.ch)
+		}
+		for w.running != 0 {
+			w.idle.Wait()
+		}
+		w.mu.Unlock()
+	})
+}
+
+// Represents whether the key is in the range of Watcher.
+func (w *Watcher) match(k container.Key) bool {
+	if w.start != nil && k.Compare(w.start) == -1 {
+		return false
+	}
+	if w.boundary != nil && k.Compare(w.boundary) != -1 {
+		return false
+	}
+	return true
+}
+
+// Delivers the event to Watcher.
+func (w *Watcher) deliver(ev Event) {
+	if w.f != nil {
+		w.call(ev)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+
+	switch w.policy {
+	case Block:
+		select {
+		case w.ch <- ev:
+		case <-w.done:
+		}
+	case DropNewest:
+		select {
+		case w.ch <- ev:
+		default:
+			atomic.AddInt64(&w.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case w.ch <- ev:
+				return
+			default:
+			}
+			select {
+			case <-w.ch:
+				atomic.AddInt64(&w.dropped, 1)
+			default:
+			}
+		}
+	}
+}
+
+// Calls f with the event without holding the lock, so that f can change the Container.
+// The Close waits for the running calls to finish.
+func (w *Watcher) call(ev Event) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.running++
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.running--
+		if w.running == 0 {
+			w.idle.Broadcast()
+		}
+		w.mu.Unlock()
+	}()
+	w.f(ev)
+}
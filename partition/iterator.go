@@ -0,0 +1,43 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package partition
+
+import (
+	"github.com/yu31/structs-go/container"
+)
+
+var _ container.Iterator = (*Iterator)(nil)
+
+// Iterator iterates over a snapshot of elements.
+type Iterator struct {
+	elements []container.Element
+	index    int
+}
+
+// creates an Iterator.
+func newIterator(elements []container.Element) *Iterator {
+	return &Iterator{
+		elements: elements,
+		index:    0,
+	}
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *Iterator) Valid() bool {
+	return iter.index < len(iter.elements)
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *Iterator) Next() container.Element {
+	if !iter.Valid() {
+		return nil
+	}
+	ele := iter.elements[iter.index]
+	iter.elements[iter.index] = nil // Prevent memory leaks.
+	iter.index++
+	return ele
+}
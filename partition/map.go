@@ -0,0 +1,592 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package partition
+
+import (
+	"iter"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yu31/structs-go/container"
+)
+
+// The default maximum number of elements in a shard.
+const defaultSplitSize = 4096
+
+var (
+	_ container.Container = (*Map)(nil)
+	_ container.Element   = (*element)(nil)
+)
+
+// element is a snapshot of an element of a shard.
+type element struct {
+	key   container.Key
+	value container.Value
+}
+
+// Key returns the key.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value.
+func (e *element) Value() container.Value {
+	return e.value
+}
+
+// shard holds the elements in range lower <= x < lower of next shard.
+type shard struct {
+	mu    sync.RWMutex
+	lower container.Key // The lower is nil for the first shard.
+	ctr   container.Container
+}
+
+// Map implements an ordered map that partitioned by key range, and it is safe for concurrent use by multiple goroutines.
+//
+// Each shard is a container.Container behind its own lock, so writers on different key ranges do not contend.
+// A shard is split into two halves when its size exceeds the split size, and is merged with its smaller
+// neighbor when its size falls below a quarter of the split size.
+//
+// The elements returned by Map are snapshots, the changes after returned are invisible to them.
+// Operations on a single key are atomic, but the operations across shards, such as Range and DeleteRange,
+// are only atomic in each shard.
+type Map struct {
+	// The len accessed atomically, keep it at the beginning for 64-bit alignment.
+	len int64
+
+	mu        sync.RWMutex // Protects the shards directory.
+	shards    []*shard
+	factory   func() container.Container
+	splitSize int
+	mergeSize int
+}
+
+// New creates a Map, the factory is used to create the container of shard, such as rb.New or skip.New.
+// A shard is split when its size exceeds splitSize. The default value 4096 is used if splitSize <= 0.
+func New(factory func() container.Container, splitSize int) *Map {
+	if splitSize <= 0 {
+		splitSize = defaultSplitSize
+	}
+	m := &Map{
+		len:       0,
+		factory:   factory,
+		splitSize: splitSize,
+		mergeSize: splitSize / 4,
+	}
+	m.shards = []*shard{{lower: nil, ctr: factory()}}
+	return m
+}
+
+// Len returns the number of elements.
+func (m *Map) Len() int {
+	return int(atomic.LoadInt64(&m.len))
+}
+
+// Insert inserts a new element if the key doesn't exist, or returns the existing element for the key if present.
+// The bool result is true if an element was inserted, false if searched.
+func (m *Map) Insert(k container.Key, v container.Value) (ele container.Element, ok bool) {
+	m.write(k, func(ctr container.Container) {
+		ele, ok = ctr.Insert(k, v)
+		ele = snapshot(ele)
+	})
+	return
+}
+
+// Delete removes and returns the element of a given key.
+// Returns nil if key not found.
+func (m *Map) Delete(k container.Key) (ele container.Element) {
+	m.write(k, func(ctr container.Container) {
+		ele = snapshot(ctr.Delete(k))
+	})
+	return
+}
+
+// Update updates an element with the given key and value, And returns the old element of key.
+// Returns nil if the key not be found.
+func (m *Map) Update(k container.Key, v container.Value) (ele container.Element) {
+	m.write(k, func(ctr container.Container) {
+		ele = snapshot(ctr.Update(k, v))
+	})
+	return
+}
+
+// Upsert inserts or updates an element by giving key and value.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (m *Map) Upsert(k container.Key, v container.Value) (ele container.Element, ok bool) {
+	m.write(k, func(ctr container.Container) {
+		ele, ok = ctr.Upsert(k, v)
+		ele = snapshot(ele)
+	})
+	return
+}
+
+// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place.
+// Returns the element of key and its old value, returns nil if the key not be found.
+func (m *Map) UpdateInPlace(k container.Key, v container.Value) (ele container.Element, old container.Value) {
+	m.write(k, func(ctr container.Container) {
+		ele, old = ctr.UpdateInPlace(k, v)
+		ele = snapshot(ele)
+	})
+	return
+}
+
+// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (m *Map) UpsertInPlace(k container.Key, v container.Value) (ele container.Element, ok bool) {
+	m.write(k, func(ctr container.Container) {
+		ele, ok = ctr.UpsertInPlace(k, v)
+		ele = snapshot(ele)
+	})
+	return
+}
+
+// Search searches the element of a given key.
+// Returns nil if key not found.
+func (m *Map) Search(k container.Key) container.Element {
+	m.mu.RLock()
+	s := m.shards[m.locate(k)]
+	s.mu.RLock()
+	ele := snapshot(s.ctr.Search(k))
+	s.mu.RUnlock()
+	m.mu.RUnlock()
+	return ele
+}
+
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f.
+//
+// The f is called with the lock of shard held, so it must not access the Map.
+func (m *Map) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) (ele container.Element) {
+	m.write(k, func(ctr container.Container) {
+		ele = snapshot(ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+			return f(snapshot(ele), exists)
+		}))
+	})
+	return
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+//
+// The f is called with the lock of shard held, so it must not access the Map.
+func (m *Map) GetOrInsert(k container.Key, f func() container.Value) (ele container.Element, ok bool) {
+	// Fast path, avoid to acquire the write lock if the key exists.
+	if ele = m.Search(k); ele != nil {
+		return ele, false
+	}
+	m.write(k, func(ctr container.Container) {
+		ele, ok = ctr.GetOrInsert(k, f)
+		ele = snapshot(ele)
+	})
+	return
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+//
+// The elements are removed shard by shard, and the f is called without lock held.
+func (m *Map) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return 0
+	}
+
+	total := 0
+	cursor := start
+	for {
+		var removed []container.Element
+		var g func(ele container.Element)
+		if f != nil {
+			g = func(ele container.Element) {
+				removed = append(removed, snapshot(ele))
+			}
+		}
+
+		m.mu.RLock()
+		i := m.locate(cursor)
+		s := m.shards[i]
+		upper := m.upper(i)
+		s.mu.Lock()
+		n := s.ctr.DeleteRange(cursor, minKey(upper, boundary), g)
+		size := s.ctr.Len()
+		s.mu.Unlock()
+		multi := len(m.shards) > 1
+		m.mu.RUnlock()
+
+		atomic.AddInt64(&m.len, int64(-n))
+		total += n
+		for _, ele := range removed {
+			f(ele)
+		}
+		if n > 0 && multi && size < m.mergeSize {
+			m.merge(cursor)
+		}
+
+		if upper == nil || (boundary != nil && upper.Compare(boundary) != -1) {
+			return total
+		}
+		cursor = upper
+	}
+}
+
+// Clear removes all elements in the Map.
+func (m *Map) Clear() {
+	m.mu.Lock()
+	m.shards = []*shard{{lower: nil, ctr: m.factory()}}
+	atomic.StoreInt64(&m.len, 0)
+	m.mu.Unlock()
+}
+
+// Range calls f sequentially each element present in the Map.
+// If f returns false, range stops the iteration.
+//
+// The elements are collected shard by shard, and the f is called without lock held.
+func (m *Map) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return
+	}
+
+	cursor := start
+	for {
+		elements, upper := m.collect(cursor, boundary)
+		for _, ele := range elements {
+			if !f(ele) {
+				return
+			}
+		}
+		if upper == nil || (boundary != nil && upper.Compare(boundary) != -1) {
+			return
+		}
+		cursor = upper
+	}
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+// If f returns false, range stops the iteration.
+func (m *Map) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return
+	}
+
+	cursor := boundary
+	for {
+		elements, lower := m.collectReverse(start, cursor)
+		for _, ele := range elements {
+			if !f(ele) {
+				return
+			}
+		}
+		if lower == nil || (start != nil && lower.Compare(start) != 1) {
+			return
+		}
+		cursor = lower
+	}
+}
+
+// Iter returns an Iterator over a snapshot of the elements in range.
+func (m *Map) Iter(start container.Key, boundary container.Key) container.Iterator {
+	var elements []container.Element
+	m.Range(start, boundary, func(ele container.Element) bool {
+		elements = append(elements, ele)
+		return true
+	})
+	return newIterator(elements)
+}
+
+// IterReverse is similar to the Iter method, but the elements are in reverse order.
+func (m *Map) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	var elements []container.Element
+	m.Reverse(start, boundary, func(ele container.Element) bool {
+		elements = append(elements, ele)
+		return true
+	})
+	return newIterator(elements)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (m *Map) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(m)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (m *Map) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(m)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (m *Map) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(m, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (m *Map) Keys() iter.Seq[container.Key] {
+	return container.Keys(m)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (m *Map) Values() iter.Seq[container.Value] {
+	return container.Values(m)
+}
+
+// LastLT searches for the last element that less than the key.
+func (m *Map) LastLT(k container.Key) container.Element {
+	return m.searchBackward(k, func(ctr container.Container) container.Element {
+		return ctr.LastLT(k)
+	})
+}
+
+// LastLE search for the last element that less than or equal to the key.
+func (m *Map) LastLE(k container.Key) container.Element {
+	return m.searchBackward(k, func(ctr container.Container) container.Element {
+		return ctr.LastLE(k)
+	})
+}
+
+// FirstGT search for the first element that greater than to the key.
+func (m *Map) FirstGT(k container.Key) container.Element {
+	return m.searchForward(k, func(ctr container.Container) container.Element {
+		return ctr.FirstGT(k)
+	})
+}
+
+// FirstGE search for the first element that greater than or equal to the key.
+func (m *Map) FirstGE(k container.Key) container.Element {
+	return m.searchForward(k, func(ctr container.Container) container.Element {
+		return ctr.FirstGE(k)
+	})
+}
+
+// Returns the index of shard that contains the key. Returns 0 if the key is nil.
+// The caller must hold the lock of directory.
+func (m *Map) locate(k container.Key) int {
+	if k == nil {
+		return 0
+	}
+	return sort.Search(len(m.shards), func(i int) bool {
+		return m.shards[i].lower != nil && m.shards[i].lower.Compare(k) == 1
+	}) - 1
+}
+
+// Returns the index of the last shard that has elements less than the key. Returns the last shard if the key is nil.
+// The caller must hold the lock of directory.
+func (m *Map) locateBefore(k container.Key) int {
+	if k == nil {
+		return len(m.shards) - 1
+	}
+	return sort.Search(len(m.shards), func(i int) bool {
+		return m.shards[i].lower != nil && m.shards[i].lower.Compare(k) != -1
+	}) - 1
+}
+
+// Returns the upper bound of shard i, it's nil for the last shard.
+// The caller must hold the lock of directory.
+func (m *Map) upper(i int) container.Key {
+	if i+1 < len(m.shards) {
+		return m.shards[i+1].lower
+	}
+	return nil
+}
+
+// Calls f with the container of shard that contains the key under the write lock, then split
+// or merge the shard if needed.
+func (m *Map) write(k container.Key, f func(ctr container.Container)) {
+	m.mu.RLock()
+	s := m.shards[m.locate(k)]
+	s.mu.Lock()
+	before := s.ctr.Len()
+	f(s.ctr)
+	after := s.ctr.Len()
+	s.mu.Unlock()
+	multi := len(m.shards) > 1
+	m.mu.RUnlock()
+
+	if after == before {
+		return
+	}
+	atomic.AddInt64(&m.len, int64(after-before))
+	if after > before && after > m.splitSize {
+		m.split(k)
+	} else if after < before && multi && after < m.mergeSize {
+		m.merge(k)
+	}
+}
+
+// Splits the shard that contains the key into two halves if its size exceeds the split size.
+func (m *Map) split(k container.Key) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.locate(k)
+	s := m.shards[i]
+	n := s.ctr.Len()
+	if n <= m.splitSize {
+		return
+	}
+
+	// Finds the median key.
+	var mid container.Key
+	j := 0
+	s.ctr.Range(nil, nil, func(ele container.Element) bool {
+		if j == n/2 {
+			mid = ele.Key()
+			return false
+		}
+		j++
+		return true
+	})
+
+	right := &shard{lower: mid, ctr: m.factory()}
+	s.ctr.DeleteRange(mid, nil, func(ele container.Element) {
+		right.ctr.Insert(ele.Key(), ele.Value())
+	})
+
+	m.shards = append(m.shards, nil)
+	copy(m.shards[i+2:], m.shards[i+1:])
+	m.shards[i+1] = right
+}
+
+// Merges the shard that contains the key with its smaller neighbor if its size falls below the merge size
+// and the merged size does not exceed the split size.
+func (m *Map) merge(k container.Key) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.shards) == 1 {
+		return
+	}
+	i := m.locate(k)
+	if m.shards[i].ctr.Len() >= m.mergeSize {
+		return
+	}
+
+	// Chooses the smaller neighbor.
+	j := i + 1
+	if j == len(m.shards) || (i > 0 && m.shards[i-1].ctr.Len() < m.shards[j].ctr.Len()) {
+		j = i - 1
+	}
+	if j < i {
+		i, j = j, i
+	}
+	left, right := m.shards[i], m.shards[j]
+	if left.ctr.Len()+right.ctr.Len() > m.splitSize {
+		return
+	}
+
+	// Moves the elements of the smaller one into the larger one.
+	dst, src := left, right
+	if right.ctr.Len() > left.ctr.Len() {
+		dst, src = right, left
+	}
+	src.ctr.Range(nil, nil, func(ele container.Element) bool {
+		dst.ctr.Insert(ele.Key(), ele.Value())
+		return true
+	})
+	dst.lower = left.lower
+
+	m.shards[i] = dst
+	copy(m.shards[j:], m.shards[j+1:])
+	m.shards[len(m.shards)-1] = nil
+	m.shards = m.shards[:len(m.shards)-1]
+}
+
+// Collects the elements in range start <= x < boundary from the shard that contains the start key,
+// and returns the upper bound of the shard.
+func (m *Map) collect(start container.Key, boundary container.Key) ([]container.Element, container.Key) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i := m.locate(start)
+	s := m.shards[i]
+	upper := m.upper(i)
+
+	var elements []container.Element
+	s.mu.RLock()
+	s.ctr.Range(start, minKey(upper, boundary), func(ele container.Element) bool {
+		elements = append(elements, snapshot(ele))
+		return true
+	})
+	s.mu.RUnlock()
+	return elements, upper
+}
+
+// Collects the elements in range start <= x < boundary in reverse from the last shard that has elements
+// less than the boundary, and returns the lower bound of the shard.
+func (m *Map) collectReverse(start container.Key, boundary container.Key) ([]container.Element, container.Key) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s := m.shards[m.locateBefore(boundary)]
+	from := start
+	if s.lower != nil && (from == nil || s.lower.Compare(from) == 1) {
+		from = s.lower
+	}
+
+	var elements []container.Element
+	s.mu.RLock()
+	s.ctr.Reverse(from, boundary, func(ele container.Element) bool {
+		elements = append(elements, snapshot(ele))
+		return true
+	})
+	s.mu.RUnlock()
+	return elements, s.lower
+}
+
+// Calls f with the shard that contains the key and its previous shards in turn until f returns non-nil.
+func (m *Map) searchBackward(k container.Key, f func(ctr container.Container) container.Element) container.Element {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := m.locate(k); i >= 0; i-- {
+		s := m.shards[i]
+		s.mu.RLock()
+		ele := snapshot(f(s.ctr))
+		s.mu.RUnlock()
+		if ele != nil {
+			return ele
+		}
+	}
+	return nil
+}
+
+// Calls f with the shard that contains the key and its next shards in turn until f returns non-nil.
+func (m *Map) searchForward(k container.Key, f func(ctr container.Container) container.Element) container.Element {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := m.locate(k); i < len(m.shards); i++ {
+		s := m.shards[i]
+		s.mu.RLock()
+		ele := snapshot(f(s.ctr))
+		s.mu.RUnlock()
+		if ele != nil {
+			return ele
+		}
+	}
+	return nil
+}
+
+// Returns the smaller one of two boundaries, the nil represents unbounded.
+func minKey(k1 container.Key, k2 container.Key) container.Key {
+	if k1 == nil {
+		return k2
+	}
+	if k2 == nil || k1.Compare(k2) == -1 {
+		return k1
+	}
+	return k2
+}
+
+// Creates a snapshot of the element. Returns nil if ele is nil.
+func snapshot(ele container.Element) container.Element {
+	if ele == nil {
+		return nil
+	}
+	return &element{
+		key:   ele.Key(),
+		value: ele.Value(),
+	}
+}
@@ -0,0 +1,250 @@
+package partition
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+var factories = map[string]func() container.Container{
+	"rbtree": func() container.Container {
+		return rb.New()
+	},
+	"skiplist": func() container.Container {
+		return skip.New()
+	},
+}
+
+func checkShards(t *testing.T, m *Map) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n := 0
+	require.Nil(t, m.shards[0].lower)
+	for i, s := range m.shards {
+		upper := m.upper(i)
+		if upper != nil {
+			require.Equal(t, s.lower == nil || s.lower.Compare(upper) == -1, true)
+		}
+		s.ctr.Range(nil, nil, func(ele container.Element) bool {
+			if s.lower != nil {
+				require.NotEqual(t, ele.Key().Compare(s.lower), -1)
+			}
+			if upper != nil {
+				require.Equal(t, ele.Key().Compare(upper), -1)
+			}
+			return true
+		})
+		require.LessOrEqual(t, s.ctr.Len(), m.splitSize)
+		n += s.ctr.Len()
+	}
+	require.Equal(t, n, m.Len())
+}
+
+func TestMap(t *testing.T) {
+	process := func(name string, m *Map) {
+		ref := rb.New()
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		maxKey := 512
+
+		for i := 0; i < 4096; i++ {
+			k := container.Int(r.Intn(maxKey))
+			switch r.Intn(5) {
+			case 0, 1:
+				_, ok1 := m.Insert(k, i)
+				_, ok2 := ref.Insert(k, i)
+				require.Equal(t, ok1, ok2)
+			case 2:
+				e1, ok1 := m.Upsert(k, i)
+				e2, ok2 := ref.Upsert(k, i)
+				require.Equal(t, ok1, ok2)
+				if !ok1 {
+					require.Equal(t, e1.Value(), e2.Value())
+				}
+			case 3:
+				e1 := m.Delete(k)
+				e2 := ref.Delete(k)
+				require.Equal(t, e1 == nil, e2 == nil)
+			case 4:
+				boundary := k + container.Int(r.Intn(16))
+				require.Equal(t, m.DeleteRange(k, boundary, nil), ref.DeleteRange(k, boundary, nil))
+			}
+			require.Equal(t, m.Len(), ref.Len())
+		}
+		checkShards(t, m)
+
+		// Searches across shard boundaries.
+		for i := -1; i <= maxKey+1; i++ {
+			k := container.Int(i)
+			for _, pair := range [][2]container.Element{
+				{m.Search(k), ref.Search(k)},
+				{m.LastLT(k), ref.LastLT(k)},
+				{m.LastLE(k), ref.LastLE(k)},
+				{m.FirstGT(k), ref.FirstGT(k)},
+				{m.FirstGE(k), ref.FirstGE(k)},
+			} {
+				require.Equal(t, pair[0] == nil, pair[1] == nil)
+				if pair[0] != nil {
+					require.Equal(t, pair[0].Key(), pair[1].Key())
+					require.Equal(t, pair[0].Value(), pair[1].Value())
+				}
+			}
+		}
+
+		// Range and Reverse stitch the shards together.
+		for x := 0; x < 64; x++ {
+			var start, boundary container.Key
+			if r.Intn(4) != 0 {
+				start = container.Int(r.Intn(maxKey))
+			}
+			if r.Intn(4) != 0 {
+				boundary = container.Int(r.Intn(maxKey))
+			}
+
+			var k1, k2 []container.Key
+			m.Range(start, boundary, func(ele container.Element) bool {
+				k1 = append(k1, ele.Key())
+				return true
+			})
+			ref.Range(start, boundary, func(ele container.Element) bool {
+				k2 = append(k2, ele.Key())
+				return true
+			})
+			require.Equal(t, k1, k2)
+
+			k1, k2 = nil, nil
+			m.Reverse(start, boundary, func(ele container.Element) bool {
+				k1 = append(k1, ele.Key())
+				return true
+			})
+			ref.Reverse(start, boundary, func(ele container.Element) bool {
+				k2 = append(k2, ele.Key())
+				return true
+			})
+			require.Equal(t, k1, k2)
+
+			k1 = nil
+			it := m.IterReverse(start, boundary)
+			for it.Valid() {
+				k1 = append(k1, it.Next().Key())
+			}
+			require.Equal(t, k1, k2)
+		}
+
+		m.Clear()
+		require.Equal(t, m.Len(), 0)
+		checkShards(t, m)
+	}
+
+	for name, f := range factories {
+		t.Run(name, func(t *testing.T) {
+			process(name, New(f, 8))
+		})
+	}
+}
+
+func TestMap_SplitAndMerge(t *testing.T) {
+	m := New(factories["rbtree"], 16)
+
+	length := 1024
+	for i := 0; i < length; i++ {
+		m.Insert(container.Int(i), i)
+	}
+	checkShards(t, m)
+	require.Greater(t, len(m.shards), length/16)
+
+	require.Equal(t, m.DeleteRange(container.Int(100), container.Int(900), nil), 800)
+	checkShards(t, m)
+
+	for i := 0; i < length; i++ {
+		m.Delete(container.Int(i))
+	}
+	checkShards(t, m)
+	require.Equal(t, len(m.shards), 1)
+	require.Equal(t, m.Len(), 0)
+}
+
+// Run with `go test -race` to check the data race.
+func TestMap_Concurrent(t *testing.T) {
+	process := func(m *Map) {
+		const (
+			writers = 4
+			readers = 4
+			loops   = 2000
+			maxKey  = 1024
+		)
+
+		var wg sync.WaitGroup
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(seed))
+				for i := 0; i < loops; i++ {
+					k := container.Int(r.Intn(maxKey))
+					switch r.Intn(5) {
+					case 0, 1:
+						m.Insert(k, int(k))
+					case 2:
+						m.Delete(k)
+					case 3:
+						m.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+							return int(k), true
+						})
+					case 4:
+						m.DeleteRange(k, k+32, nil)
+					}
+				}
+			}(time.Now().UnixNano() + int64(w))
+		}
+
+		for rd := 0; rd < readers; rd++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(seed))
+				for i := 0; i < loops; i++ {
+					k := container.Int(r.Intn(maxKey))
+					switch r.Intn(3) {
+					case 0:
+						if ele := m.Search(k); ele != nil {
+							require.Equal(t, ele.Value(), int(k))
+						}
+					case 1:
+						if ele := m.FirstGE(k); ele != nil {
+							require.NotEqual(t, ele.Key().Compare(k), -1)
+						}
+						if ele := m.LastLT(k); ele != nil {
+							require.Equal(t, ele.Key().Compare(k), -1)
+						}
+					case 2:
+						var last container.Key
+						m.Range(nil, nil, func(ele container.Element) bool {
+							if last != nil {
+								require.Equal(t, last.Compare(ele.Key()), -1)
+							}
+							last = ele.Key()
+							return true
+						})
+					}
+				}
+			}(time.Now().UnixNano() + int64(rd))
+		}
+
+		wg.Wait()
+		checkShards(t, m)
+	}
+
+	for name, f := range factories {
+		t.Run(name, func(t *testing.T) {
+			process(New(f, 16))
+		})
+	}
+}
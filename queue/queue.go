@@ -5,6 +5,10 @@
 
 package queue
 
+import (
+	"iter"
+)
+
 const (
 	defaultCapacity = 64
 )
@@ -76,6 +80,29 @@ func (q *Queue) Peek() interface{} {
 	return q.items[q.front]
 }
 
+// All returns an iterator over the elements in pop order (FIFO), the queue is not changed.
+// The index is 0 for the element at the head.
+func (q *Queue) All() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i := 0; i < q.Len(); i++ {
+			if !yield(i, q.items[(q.front+i)%q.cap]) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the elements in pop order (FIFO), the queue is not changed.
+func (q *Queue) Values() iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		for _, item := range q.All() {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
 func (q *Queue) autoGrow() {
 	if q.Len() == q.Cap() {
 		newCap := q.cap - 1
@@ -102,3 +102,30 @@ func TestQueue3(t *testing.T) {
 	require.Equal(t, q.Pop(), 5)
 	require.Equal(t, q.Pop(), 6)
 }
+
+func TestQueue_All(t *testing.T) {
+	q := New(2)
+	for i := 0; i < 5; i++ {
+		q.Push(i)
+	}
+	// Wraps around the array.
+	q.Pop()
+	q.Push(5)
+
+	var items []interface{}
+	for i, item := range q.All() {
+		require.Equal(t, i, len(items))
+		items = append(items, item)
+	}
+	require.Equal(t, items, []interface{}{1, 2, 3, 4, 5})
+	require.Equal(t, q.Len(), 5)
+
+	items = nil
+	for item := range q.Values() {
+		if item == 3 {
+			break
+		}
+		items = append(items, item)
+	}
+	require.Equal(t, items, []interface{}{1, 2})
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package rb
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	_ json.Marshaler   = (*Tree)(nil)
+	_ json.Unmarshaler = (*Tree)(nil)
+)
+
+// MarshalJSON encodes all elements as a JSON array in ascending order by key.
+// See container.JSONEncoder for the format of elements.
+func (tr *Tree) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := container.WriteJSON(&buf, tr, nil, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON replaces all elements with the elements decoded from a JSON array.
+// The elements with duplicate key are ignored except the first unless the tree allows duplicate keys.
+func (tr *Tree) UnmarshalJSON(data []byte) error {
+	tr.Clear()
+	if _, err := container.ReadJSON(bytes.NewReader(data), tr); err != nil {
+		tr.Clear()
+		return err
+	}
+	return nil
+}
@@ -6,15 +6,19 @@
 package rb
 
 import (
+	"iter"
+
 	"github.com/yu31/structs-go/container"
 	"github.com/yu31/structs-go/internal/tree"
 )
 
 var (
-	_ container.Container = (*Tree)(nil)
-	_ container.Element   = (*treeNode)(nil)
-	_ container.Tree      = (*Tree)(nil)
-	_ container.TreeNode  = (*treeNode)(nil)
+	_ container.Container      = (*Tree)(nil)
+	_ container.MultiContainer = (*Tree)(nil)
+	_ container.Element        = (*treeNode)(nil)
+	_ container.Tree           = (*Tree)(nil)
+	_ container.TreeNode       = (*treeNode)(nil)
+	_ container.MutableElement = (*treeNode)(nil)
 )
 
 const (
@@ -42,6 +46,11 @@ func (n *treeNode) Value() container.Value {
 	return n.value
 }
 
+// SetValue sets the value in place.
+func (n *treeNode) SetValue(v container.Value) {
+	n.value = v
+}
+
 // Left returns the left child of the TreeNode.
 func (n *treeNode) Left() container.TreeNode {
 	if n.left == nil {
@@ -60,8 +69,9 @@ func (n *treeNode) Right() container.TreeNode {
 
 // Tree implements the Red-Black Tree.
 type Tree struct {
-	root *treeNode
-	len  int
+	root  *treeNode
+	len   int
+	multi bool
 }
 
 // New creates a Red-Black Tree.
@@ -72,6 +82,39 @@ func New() *Tree {
 	}
 }
 
+// NewMulti creates a Red-Black Tree that allows duplicate keys, the elements
+// with the same key are kept in insertion order.
+func NewMulti() *Tree {
+	return &Tree{
+		root:  nil,
+		len:   0,
+		multi: true,
+	}
+}
+
+// NewFromSorted creates a Tree with n elements returned by next in ascending order by key.
+// It builds a balanced tree in O(n) without comparison, so the keys must be strictly ascending.
+//
+// All nodes are black except the nodes in the deepest level if it is not full, so the black height is same.
+func NewFromSorted(n int, next func() (container.Key, container.Value)) *Tree {
+	tr := New()
+
+	// The depth of deepest level, the root is at depth 0.
+	depth := -1
+	for x := n; x > 0; x >>= 1 {
+		depth++
+	}
+	// The deepest level is full if n == 2^(depth+1) - 1.
+	redDepth := depth
+	if n == 1<<uint(depth+1)-1 {
+		redDepth = -1
+	}
+
+	tr.root = tr.buildSorted(n, 0, redDepth, nil, next)
+	tr.len = n
+	return tr
+}
+
 // Root returns the root node of the tree.
 func (tr *Tree) Root() container.TreeNode {
 	if tr.root == nil {
@@ -87,6 +130,8 @@ func (tr *Tree) Len() int {
 
 // Insert inserts a new element if the key doesn't exist, or returns the existing element for the key if present.
 // The bool result is true if an element was inserted, false if searched.
+//
+// If the tree allows duplicate keys, it always inserts a new element behind the elements with the same key.
 func (tr *Tree) Insert(k container.Key, v container.Value) (container.Element, bool) {
 	node, ok := tr.insertOrSearch(k, v)
 	return node, ok
@@ -116,6 +161,12 @@ func (tr *Tree) Update(k container.Key, v container.Value) container.Element {
 // Upsert inserts or updates an element by giving key and value.
 // The bool result is true if an element was inserted, false if an element was updated.
 func (tr *Tree) Upsert(k container.Key, v container.Value) (container.Element, bool) {
+	if tr.multi {
+		if node := tr.searchNode(k); node != nil {
+			tr.replaceNode(node, tr.createNode(k, v, nil))
+			return node, false
+		}
+	}
 	node, ok := tr.insertOrSearch(k, v)
 	if !ok {
 		tr.replaceNode(node, tr.createNode(k, v, nil))
@@ -123,6 +174,29 @@ func (tr *Tree) Upsert(k container.Key, v container.Value) (container.Element, b
 	return node, ok
 }
 
+// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place.
+// Returns the element of key and its old value, returns nil if the key not be found.
+func (tr *Tree) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	node := tr.searchNode(k)
+	if node == nil {
+		return nil, nil
+	}
+	old := node.value
+	node.value = v
+	return node, old
+}
+
+// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (tr *Tree) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	var ok bool
+	ele := tr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		ok = !exists
+		return v, true
+	})
+	return ele, ok
+}
+
 // Search searches the element of a given key.
 // Returns nil if key not found.
 func (tr *Tree) Search(k container.Key) container.Element {
@@ -133,6 +207,155 @@ func (tr *Tree) Search(k container.Key) container.Element {
 	return node
 }
 
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f. All of these are done with a single search.
+//
+// Returns the element of key after computed, nil if no element for the key.
+func (tr *Tree) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) container.Element {
+	var node, parent *treeNode
+	var cmp int
+
+	p := tr.root
+	for p != nil {
+		c := k.Compare(p.key)
+		if c == 0 {
+			node = p
+			if !tr.multi {
+				break
+			}
+			// Continue to search the first one of duplicate keys.
+			p = p.left
+			continue
+		}
+		parent, cmp = p, c
+		if c == -1 {
+			p = p.left
+		} else {
+			p = p.right
+		}
+	}
+
+	if node != nil {
+		v, keep := f(node, true)
+		if !keep {
+			tr.deleteNode(node)
+			return nil
+		}
+		node.value = v
+		return node
+	}
+
+	v, keep := f(nil, false)
+	if !keep {
+		return nil
+	}
+
+	node = tr.createNode(k, v, parent)
+	if parent != nil {
+		if cmp == -1 {
+			parent.left = node
+		} else {
+			parent.right = node
+		}
+	}
+	tr.insertReBalance(node)
+	tr.len++
+	return node
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+func (tr *Tree) GetOrInsert(k container.Key, f func() container.Value) (container.Element, bool) {
+	var ok bool
+	ele := tr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		if exists {
+			return ele.Value(), true
+		}
+		ok = true
+		return f(), true
+	})
+	return ele, ok
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+//
+// The tree is split at start and boundary, and the two remaining parts are joined back together by black height,
+// Thus the cost is O(log n) plus the number of removed elements.
+func (tr *Tree) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return 0
+	}
+
+	var left, middle, right *treeNode
+	var lh, mh, rh int
+
+	middle, mh = tr.root, tr.blackHeight(tr.root)
+	if start != nil {
+		left, lh, middle, mh = tr.split(middle, mh, start)
+	}
+	if boundary != nil {
+		middle, mh, right, rh = tr.split(middle, mh, boundary)
+	}
+	tr.root = tr.join2(left, lh, right, rh)
+
+	n := tr.releaseNodes(middle, f)
+	tr.len -= n
+	return n
+}
+
+// Clear removes all elements in the tree.
+func (tr *Tree) Clear() {
+	tr.root = nil
+	tr.len = 0
+}
+
+// SearchAll searches all elements of a given key, and returns them in insertion order.
+// Returns nil if key not found.
+func (tr *Tree) SearchAll(k container.Key) []container.Element {
+	var result []container.Element
+	for node := tr.searchNode(k); node != nil && node.key.Compare(k) == 0; node = tr.successor(node) {
+		result = append(result, node)
+	}
+	return result
+}
+
+// DeleteOne removes and returns the first element of a given key that f returns true.
+// Returns nil if no element matched.
+func (tr *Tree) DeleteOne(k container.Key, f func(ele container.Element) bool) container.Element {
+	for node := tr.searchNode(k); node != nil && node.key.Compare(k) == 0; node = tr.successor(node) {
+		if f(node) {
+			tr.deleteNode(node)
+			return node
+		}
+	}
+	return nil
+}
+
+// DeleteAll removes all elements of a given key, and returns the number of removed elements.
+func (tr *Tree) DeleteAll(k container.Key) int {
+	n := 0
+	node := tr.searchNode(k)
+	for node != nil && node.key.Compare(k) == 0 {
+		// The deleteNode only relinks nodes, so the successor is still valid after deletion.
+		next := tr.successor(node)
+		tr.deleteNode(node)
+		node = next
+		n++
+	}
+	return n
+}
+
+// Count returns the number of elements of a given key.
+func (tr *Tree) Count(k container.Key) int {
+	n := 0
+	for node := tr.searchNode(k); node != nil && node.key.Compare(k) == 0; node = tr.successor(node) {
+		n++
+	}
+	return n
+}
+
 // Iter return an Iterator, it's a wrap for tree.Iterator.
 func (tr *Tree) Iter(start container.Key, boundary container.Key) container.Iterator {
 	return tree.NewIterator(tr.root, start, boundary)
@@ -143,6 +366,31 @@ func (tr *Tree) IterReverse(start container.Key, boundary container.Key) contain
 	return tree.NewIteratorReverse(tr.root, start, boundary)
 }
 
+// All returns an iterator over all elements in ascending order.
+func (tr *Tree) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(tr)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (tr *Tree) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(tr)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (tr *Tree) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(tr, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (tr *Tree) Keys() iter.Seq[container.Key] {
+	return container.Keys(tr)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (tr *Tree) Values() iter.Seq[container.Value] {
+	return container.Values(tr)
+}
+
 // Range calls f sequentially each TreeNode present in the Tree.
 // If f returns false, range stops the iteration.
 func (tr *Tree) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
@@ -187,8 +435,12 @@ func (tr *Tree) insertOrSearch(k container.Key, v container.Value) (node *treeNo
 	for node != nil {
 		cmp := k.Compare(node.key)
 		if cmp == 0 {
-			// The key already exists, returns it.
-			return
+			if !tr.multi {
+				// The key already exists, returns it.
+				return
+			}
+			// Inserts the duplicate key behind the exists.
+			cmp = 1
 		}
 
 		if cmp == -1 {
@@ -225,6 +477,197 @@ func (tr *Tree) deleteAndSearch(k container.Key) *treeNode {
 	return node
 }
 
+// Returns the number of black nodes in each path from the root node r0 to nil.
+func (tr *Tree) blackHeight(r0 *treeNode) int {
+	h := 0
+	for n := r0; n != nil; n = n.left {
+		if n.color == black {
+			h++
+		}
+	}
+	return h
+}
+
+// Splits the tree r0 with black height h into two trees, one with the keys that less than k,
+// and another with the keys that greater than or equal to k. Returns the trees and their black height.
+func (tr *Tree) split(r0 *treeNode, h int, k container.Key) (left *treeNode, lh int, right *treeNode, rh int) {
+	if r0 == nil {
+		return
+	}
+	// The black height of children.
+	ch := h
+	if r0.color == black {
+		ch--
+	}
+	l, r := r0.left, r0.right
+	if r0.key.Compare(k) == -1 {
+		left, lh, right, rh = tr.split(r, ch, k)
+		left, lh = tr.join(l, ch, r0, left, lh)
+	} else {
+		left, lh, right, rh = tr.split(l, ch, k)
+		right, rh = tr.join(right, rh, r0, r, ch)
+	}
+	return
+}
+
+// Joins the tree left with black height lh, the node and the tree right with black height rh
+// into a Red-Black Tree, returns the root node and its black height.
+// All keys in left must less than or equal to node's key, and all keys in right must greater than or equal to it.
+//
+// The node is linked in the spine of the higher tree where the black height is same as the lower one,
+// and then re-balanced as a newly inserted node. Thus, the cost is O(|lh - rh|).
+func (tr *Tree) join(left *treeNode, lh int, node *treeNode, right *treeNode, rh int) (*treeNode, int) {
+	// The roots are always black, so that the node can be linked as a red node under them.
+	if left != nil {
+		left.parent = nil
+		if left.color == red {
+			left.color = black
+			lh++
+		}
+	}
+	if right != nil {
+		right.parent = nil
+		if right.color == red {
+			right.color = black
+			rh++
+		}
+	}
+
+	if lh == rh {
+		tr.linkChildren(node, left, right)
+		node.parent = nil
+		node.color = black
+		return node, lh + 1
+	}
+
+	var p, root *treeNode
+	if lh > rh {
+		// Searches the first black node in the right spine of left that black height same as right.
+		c, h := left, lh
+		for h > rh || (c != nil && c.color == red) {
+			if c.color == black {
+				h--
+			}
+			p, c = c, c.right
+		}
+		tr.linkChildren(node, c, right)
+		p.right = node
+		root = left
+	} else {
+		// Searches the first black node in the left spine of right that black height same as left.
+		c, h := right, rh
+		for h > lh || (c != nil && c.color == red) {
+			if c.color == black {
+				h--
+			}
+			p, c = c, c.left
+		}
+		tr.linkChildren(node, left, c)
+		p.left = node
+		root = right
+	}
+	node.parent = p
+	node.color = red
+
+	// The node will not be re-balanced out of the tree root.
+	tr.root = root
+	tr.insertReBalance(node)
+	root = tr.root
+
+	// The children of node still have the black height of the lower tree after re-balanced.
+	h := lh
+	if rh < lh {
+		h = rh
+	}
+	for n := node; n != nil; n = n.parent {
+		if n.color == black {
+			h++
+		}
+	}
+	return root, h
+}
+
+// Joins the tree left with black height lh and the tree right with black height rh into a Red-Black Tree,
+// returns the root node. All keys in left must less than or equal to the keys in right.
+func (tr *Tree) join2(left *treeNode, lh int, right *treeNode, rh int) *treeNode {
+	if left == nil || right == nil {
+		root := left
+		if root == nil {
+			root = right
+		}
+		if root != nil {
+			root.parent = nil
+			root.color = black
+		}
+		return root
+	}
+
+	// Removes the node with minimum key from right, and uses it to join the two trees.
+	right.parent = nil
+	tr.root = right
+	x := tr.minimum(right)
+	tr.deleteNode(x)
+	tr.len++
+
+	root, _ := tr.join(left, lh, x, tr.root, tr.blackHeight(tr.root))
+	return root
+}
+
+// Sets the children of node, and sets the parent of them to node.
+func (tr *Tree) linkChildren(node *treeNode, left *treeNode, right *treeNode) {
+	node.left = left
+	node.right = right
+	if left != nil {
+		left.parent = node
+	}
+	if right != nil {
+		right.parent = node
+	}
+}
+
+// Resets all nodes of the detached tree r0 by in-order traversal, returns the number of nodes.
+// f will be called with each node if it is not nil.
+func (tr *Tree) releaseNodes(r0 *treeNode, f func(ele container.Element)) int {
+	if r0 == nil {
+		return 0
+	}
+	n := 0
+	tree.LDR(r0, func(node container.TreeNode) bool {
+		d := node.(*treeNode)
+		// reset the unused field. LDR has already read both children before calls f.
+		d.left = nil
+		d.right = nil
+		d.parent = nil
+		d.color = -1
+		if f != nil {
+			f(d)
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+// Builds a balanced subtree with n elements returned by next, the root of subtree is at depth.
+// The nodes at redDepth are red, and others are black.
+func (tr *Tree) buildSorted(n int, depth int, redDepth int, p *treeNode, next func() (container.Key, container.Value)) *treeNode {
+	if n <= 0 {
+		return nil
+	}
+	left := tr.buildSorted(n/2, depth+1, redDepth, nil, next)
+	k, v := next()
+	node := tr.createNode(k, v, p)
+	if depth != redDepth {
+		node.color = black
+	}
+	node.left = left
+	if left != nil {
+		left.parent = node
+	}
+	node.right = tr.buildSorted(n-n/2-1, depth+1, redDepth, node, next)
+	return node
+}
+
 // Creates a new node with the giving key and value.
 func (tr *Tree) createNode(k container.Key, v container.Value, p *treeNode) *treeNode {
 	return &treeNode{
@@ -316,21 +759,51 @@ func (tr *Tree) replaceNode(old, n0 *treeNode) {
 }
 
 // Search the node of a given key.
+// Returns the first one in insertion order if the tree allows duplicate keys.
 func (tr *Tree) searchNode(k container.Key) (node *treeNode) {
-	node = tr.root
-	for node != nil {
-		cmp := k.Compare(node.key)
+	p := tr.root
+	for p != nil {
+		cmp := k.Compare(p.key)
 		if cmp == -1 {
-			node = node.left
+			p = p.left
 		} else if cmp == 1 {
-			node = node.right
+			p = p.right
 		} else {
-			return
+			node = p
+			if !tr.multi {
+				return
+			}
+			// Continue to search the first one of duplicate keys.
+			p = p.left
 		}
 	}
 	return
 }
 
+// Returns the node with minimum key in the subtree n.
+func (tr *Tree) minimum(n *treeNode) *treeNode {
+	if n == nil {
+		return nil
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+// Returns the next node of n by in-order traversal.
+func (tr *Tree) successor(n *treeNode) *treeNode {
+	if n.right != nil {
+		return tr.minimum(n.right)
+	}
+	p := n.parent
+	for p != nil && p.right == n {
+		n = p
+		p = p.parent
+	}
+	return p
+}
+
 // Re-Balance after inserts a new node.
 // n is the newly inserted node.
 func (tr *Tree) insertReBalance(n *treeNode) {
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/internal/tree"
 )
 
 func recurseCalculateNodeHeight(n *treeNode) int {
@@ -154,3 +155,139 @@ func TestTree(t *testing.T) {
 		require.Equal(t, tr.Len(), 0)
 	}
 }
+
+func TestTree_DeleteRange(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for x := 0; x < 256; x++ {
+		tr := New()
+		length := r.Intn(512) + 1
+		for _, i := range r.Perm(length) {
+			tr.Insert(container.Int(i), i)
+		}
+
+		start := r.Intn(length)
+		boundary := start + r.Intn(length-start+1)
+
+		var startKey, boundaryKey container.Key = container.Int(start), container.Int(boundary)
+		switch x % 4 {
+		case 1:
+			startKey, start = nil, 0
+		case 2:
+			boundaryKey, boundary = nil, length
+		}
+
+		next := start
+		n := tr.DeleteRange(startKey, boundaryKey, func(ele container.Element) {
+			require.Equal(t, ele.Key(), container.Int(next))
+			next++
+		})
+		require.Equal(t, n, boundary-start)
+		require.Equal(t, next, boundary)
+		require.Equal(t, tr.Len(), length-n)
+		if tr.root != nil {
+			require.Nil(t, tr.root.parent)
+			require.Equal(t, tr.root.color, black)
+		}
+		checkBalance(t, tr.root)
+		checkBlackHeight(t, tr.root)
+
+		for i := 0; i < length; i++ {
+			if i >= start && i < boundary {
+				require.Nil(t, tr.Search(container.Int(i)))
+			} else {
+				require.NotNil(t, tr.Search(container.Int(i)))
+			}
+		}
+
+		// The tree is usable after deleted.
+		_, ok := tr.Insert(container.Int(length), length)
+		require.True(t, ok)
+		checkBalance(t, tr.root)
+		checkBlackHeight(t, tr.root)
+	}
+}
+
+// Returns the number of black nodes in each path from n to nil, it should be same.
+func checkBlackHeight(t *testing.T, n *treeNode) int {
+	if n == nil {
+		return 1
+	}
+	if n.left != nil {
+		require.True(t, n.left.parent == n)
+	}
+	if n.right != nil {
+		require.True(t, n.right.parent == n)
+	}
+	lh := checkBlackHeight(t, n.left)
+	rh := checkBlackHeight(t, n.right)
+	require.Equal(t, lh, rh)
+	if n.color == black {
+		return lh + 1
+	}
+	return lh
+}
+
+func TestTree_Pivots(t *testing.T) {
+	tr := New()
+	for i := 0; i < 200000; i++ {
+		tr.Insert(container.Int(i), i)
+	}
+
+	for _, r := range [][2]container.Key{
+		{nil, nil},
+		{container.Int(100000), container.Int(102000)},
+		{container.Int(3), container.Int(2003)},
+		{container.Int(197999), nil},
+		{nil, container.Int(2000)},
+	} {
+		// The pivots of a small range are as many as the whole tree.
+		pivots := tree.Pivots(tr.root, r[0], r[1], 6)
+		require.GreaterOrEqual(t, len(pivots), 1<<5-1)
+		require.LessOrEqual(t, len(pivots), 1<<6-1)
+		for i, p := range pivots {
+			if r[0] != nil {
+				require.Equal(t, p.Compare(r[0]), 1)
+			}
+			if r[1] != nil {
+				require.Equal(t, p.Compare(r[1]), -1)
+			}
+			if i > 0 {
+				require.Equal(t, pivots[i-1].Compare(p), -1)
+			}
+		}
+	}
+	require.Nil(t, tree.Pivots(tr.root, container.Int(5), container.Int(6), 6))
+}
+
+func TestNewFromSorted(t *testing.T) {
+	for n := 0; n < 300; n++ {
+		i := 0
+		tr := NewFromSorted(n, func() (container.Key, container.Value) {
+			i++
+			return container.Int(i), i
+		})
+		require.Equal(t, tr.Len(), n)
+		if tr.root != nil {
+			require.Nil(t, tr.root.parent)
+			require.Equal(t, tr.root.color, black)
+		}
+		checkBalance(t, tr.root)
+		checkBlackHeight(t, tr.root)
+
+		j := 0
+		tr.Range(nil, nil, func(ele container.Element) bool {
+			j++
+			require.Equal(t, ele.Key(), container.Int(j))
+			return true
+		})
+		require.Equal(t, j, n)
+
+		// The tree is usable after built.
+		_, ok := tr.Insert(container.Int(n+1), n+1)
+		require.True(t, ok)
+		require.NotNil(t, tr.Delete(container.Int(n/2+1)))
+		checkBalance(t, tr.root)
+		checkBlackHeight(t, tr.root)
+	}
+}
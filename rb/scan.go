@@ -0,0 +1,39 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package rb
+
+import (
+	"context"
+	"math/bits"
+	"runtime"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/internal/scan"
+	"github.com/yu31/structs-go/internal/tree"
+)
+
+// RangeContext is similar to the Range method, but it checks the ctx periodically and stops
+// the iteration if ctx is done. Returns the error of ctx in that case.
+func (tr *Tree) RangeContext(ctx context.Context, start container.Key, boundary container.Key, f func(ele container.Element) bool) error {
+	return scan.Context(ctx, tr.Range, start, boundary, f)
+}
+
+// ParallelRange calls f with each element in the range start <= x < boundary concurrently in at most
+// workers goroutines, the workers is runtime.GOMAXPROCS(0) if it <= 0. The range is partitioned into
+// the balanced sub-ranges by the keys in the top levels of tree that counted from the topmost node in
+// the range, and the elements in each sub-range are visited in ascending order.
+//
+// If f returns an error, the iteration stops and the first error is returned.
+// The tree must not be changed during the iteration.
+func (tr *Tree) ParallelRange(start container.Key, boundary container.Key, workers int, f func(ele container.Element) error) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	// About 4 sub-ranges per worker, so the workers finished early can take more.
+	depth := bits.Len(uint(workers * 4))
+	pivots := tree.Pivots(tr.root, start, boundary, depth)
+	return scan.Parallel(tr.Range, start, boundary, pivots, workers, f)
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package safe
+
+import (
+	"iter"
+	"sync"
+
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	_ container.Container = (*Container)(nil)
+	_ container.Element   = (*element)(nil)
+)
+
+// element is a snapshot of an element of the wrapped container.
+type element struct {
+	key   container.Key
+	value container.Value
+}
+
+// Key returns the key.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value.
+func (e *element) Value() container.Value {
+	return e.value
+}
+
+// Container wraps a container.Container to make it safe for concurrent use by multiple goroutines.
+// The read operations are protected by a read lock, and the write operations are protected by a write lock.
+//
+// The elements returned by Container are snapshots of the elements in the wrapped container,
+// Thus they can be accessed safely without lock, but the changes after returned are invisible to them.
+//
+// The wrapped container should not be accessed directly after wrapped.
+type Container struct {
+	mu  sync.RWMutex
+	ctr container.Container
+}
+
+// New creates a Container that wraps the given container.
+func New(ctr container.Container) *Container {
+	return &Container{
+		ctr: ctr,
+	}
+}
+
+// Len returns the number of elements.
+func (c *Container) Len() int {
+	c.mu.RLock()
+	n := c.ctr.Len()
+	c.mu.RUnlock()
+	return n
+}
+
+// Insert inserts a new element if the key doesn't exist, or returns the existing element for the key if present.
+// The bool result is true if an element was inserted, false if searched.
+func (c *Container) Insert(k container.Key, v container.Value) (container.Element, bool) {
+	c.mu.Lock()
+	ele, ok := c.ctr.Insert(k, v)
+	ele = snapshot(ele)
+	c.mu.Unlock()
+	return ele, ok
+}
+
+// Delete removes and returns the element of a given key.
+// Returns nil if key not found.
+func (c *Container) Delete(k container.Key) container.Element {
+	c.mu.Lock()
+	ele := snapshot(c.ctr.Delete(k))
+	c.mu.Unlock()
+	return ele
+}
+
+// Update updates an element with the given key and value, And returns the old element of key.
+// Returns nil if the key not be found.
+func (c *Container) Update(k container.Key, v container.Value) container.Element {
+	c.mu.Lock()
+	ele := snapshot(c.ctr.Update(k, v))
+	c.mu.Unlock()
+	return ele
+}
+
+// Upsert inserts or updates an element by giving key and value.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (c *Container) Upsert(k container.Key, v container.Value) (container.Element, bool) {
+	c.mu.Lock()
+	ele, ok := c.ctr.Upsert(k, v)
+	ele = snapshot(ele)
+	c.mu.Unlock()
+	return ele, ok
+}
+
+// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place.
+// Returns the element of key and its old value, returns nil if the key not be found.
+func (c *Container) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	c.mu.Lock()
+	ele, old := c.ctr.UpdateInPlace(k, v)
+	ele = snapshot(ele)
+	c.mu.Unlock()
+	return ele, old
+}
+
+// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (c *Container) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	c.mu.Lock()
+	ele, ok := c.ctr.UpsertInPlace(k, v)
+	ele = snapshot(ele)
+	c.mu.Unlock()
+	return ele, ok
+}
+
+// Search searches the element of a given key.
+// Returns nil if key not found.
+func (c *Container) Search(k container.Key) container.Element {
+	c.mu.RLock()
+	ele := snapshot(c.ctr.Search(k))
+	c.mu.RUnlock()
+	return ele
+}
+
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f.
+//
+// The f is called with the write lock held, so it must not access the Container.
+func (c *Container) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) container.Element {
+	c.mu.Lock()
+	ele := snapshot(c.ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		return f(snapshot(ele), exists)
+	}))
+	c.mu.Unlock()
+	return ele
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+//
+// The f is called with the write lock held, so it must not access the Container.
+func (c *Container) GetOrInsert(k container.Key, f func() container.Value) (container.Element, bool) {
+	// Fast path, avoid to acquire the write lock if the key exists.
+	if ele := c.Search(k); ele != nil {
+		return ele, false
+	}
+
+	c.mu.Lock()
+	ele, ok := c.ctr.GetOrInsert(k, f)
+	ele = snapshot(ele)
+	c.mu.Unlock()
+	return ele, ok
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+//
+// The f is called with the write lock held, so it must not access the Container.
+func (c *Container) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	var g func(ele container.Element)
+	if f != nil {
+		g = func(ele container.Element) {
+			f(snapshot(ele))
+		}
+	}
+
+	c.mu.Lock()
+	n := c.ctr.DeleteRange(start, boundary, g)
+	c.mu.Unlock()
+	return n
+}
+
+// Clear removes all elements in the Container.
+func (c *Container) Clear() {
+	c.mu.Lock()
+	c.ctr.Clear()
+	c.mu.Unlock()
+}
+
+// Range calls f sequentially each element present in the Container.
+// If f returns false, range stops the iteration.
+//
+// It iterates over the elements in batches, each batch is a consistent snapshot that taken with the
+// read lock held, and the lock is released between batches. Thus f is called without lock, so f can
+// call any methods of the Container, and the changes between batches are visible to the iteration.
+func (c *Container) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	iter := newIterator(c, start, boundary, false)
+	for iter.Valid() {
+		if !f(iter.Next()) {
+			return
+		}
+	}
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+// If f returns false, range stops the iteration.
+//
+// It iterates over the elements in batches as the Range method.
+func (c *Container) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	iter := newIterator(c, start, boundary, true)
+	for iter.Valid() {
+		if !f(iter.Next()) {
+			return
+		}
+	}
+}
+
+// Iter returns an Iterator over the elements in range. The elements are loaded in batches as the
+// Range method, so the Iterator can be used without lock, and each batch is not affected by the
+// subsequent writes.
+func (c *Container) Iter(start container.Key, boundary container.Key) container.Iterator {
+	return newIterator(c, start, boundary, false)
+}
+
+// IterReverse is similar to the Iter method, but the elements are in reverse order.
+func (c *Container) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	return newIterator(c, start, boundary, true)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (c *Container) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(c)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (c *Container) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(c)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (c *Container) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(c, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (c *Container) Keys() iter.Seq[container.Key] {
+	return container.Keys(c)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (c *Container) Values() iter.Seq[container.Value] {
+	return container.Values(c)
+}
+
+// LastLT searches for the last element that less than the key.
+func (c *Container) LastLT(k container.Key) container.Element {
+	c.mu.RLock()
+	ele := snapshot(c.ctr.LastLT(k))
+	c.mu.RUnlock()
+	return ele
+}
+
+// LastLE search for the last element that less than or equal to the key.
+func (c *Container) LastLE(k container.Key) container.Element {
+	c.mu.RLock()
+	ele := snapshot(c.ctr.LastLE(k))
+	c.mu.RUnlock()
+	return ele
+}
+
+// FirstGT search for the first element that greater than to the key.
+func (c *Container) FirstGT(k container.Key) container.Element {
+	c.mu.RLock()
+	ele := snapshot(c.ctr.FirstGT(k))
+	c.mu.RUnlock()
+	return ele
+}
+
+// FirstGE search for the first element that greater than or equal to the key.
+func (c *Container) FirstGE(k container.Key) container.Element {
+	c.mu.RLock()
+	ele := snapshot(c.ctr.FirstGE(k))
+	c.mu.RUnlock()
+	return ele
+}
+
+// Creates a snapshot of the element. Returns nil if ele is nil.
+func snapshot(ele container.Element) container.Element {
+	if ele == nil {
+		return nil
+	}
+	return &element{
+		key:   ele.Key(),
+		value: ele.Value(),
+	}
+}
@@ -0,0 +1,279 @@
+package safe
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/avl"
+	"github.com/yu31/structs-go/bs"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+var containers = map[string]func() container.Container{
+	"bstree": func() container.Container {
+		return bs.New()
+	},
+	"avltree": func() container.Container {
+		return avl.New()
+	},
+	"rbtree": func() container.Container {
+		return rb.New()
+	},
+	"skiplist": func() container.Container {
+		return skip.New()
+	},
+}
+
+func TestContainer(t *testing.T) {
+	process := func(c *Container) {
+		for i := 0; i < 100; i++ {
+			ele, ok := c.Insert(container.Int(i), i)
+			require.True(t, ok)
+			require.Equal(t, ele.Key(), container.Int(i))
+			require.Equal(t, ele.Value(), i)
+		}
+		require.Equal(t, c.Len(), 100)
+
+		// The returned element is a snapshot.
+		ele := c.Search(container.Int(10))
+		c.UpdateInPlace(container.Int(10), 1024)
+		require.Equal(t, ele.Value(), 10)
+		require.Equal(t, c.Search(container.Int(10)).Value(), 1024)
+
+		require.Equal(t, c.Update(container.Int(10), 10).Value(), 1024)
+		_, ok := c.Upsert(container.Int(100), 100)
+		require.True(t, ok)
+		_, ok = c.UpsertInPlace(container.Int(100), 100)
+		require.False(t, ok)
+
+		ele, ok = c.GetOrInsert(container.Int(50), func() container.Value {
+			require.FailNow(t, "the f should not be called if the key exists")
+			return nil
+		})
+		require.False(t, ok)
+		require.Equal(t, ele.Value(), 50)
+
+		require.Nil(t, c.Compute(container.Int(100), func(ele container.Element, exists bool) (container.Value, bool) {
+			require.True(t, exists)
+			return nil, false
+		}))
+		require.Nil(t, c.Search(container.Int(100)))
+
+		require.Equal(t, c.LastLT(container.Int(10)).Key(), container.Int(9))
+		require.Equal(t, c.LastLE(container.Int(10)).Key(), container.Int(10))
+		require.Equal(t, c.FirstGT(container.Int(10)).Key(), container.Int(11))
+		require.Equal(t, c.FirstGE(container.Int(10)).Key(), container.Int(10))
+		require.True(t, c.FirstGT(container.Int(99)) == nil)
+
+		var keys []container.Key
+		it := c.Iter(container.Int(10), container.Int(15))
+		for it.Valid() {
+			keys = append(keys, it.Next().Key())
+		}
+		require.Equal(t, keys, []container.Key{container.Int(10), container.Int(11), container.Int(12), container.Int(13), container.Int(14)})
+		require.Nil(t, it.Next())
+
+		require.Equal(t, c.Delete(container.Int(10)).Value(), 10)
+		require.Nil(t, c.Delete(container.Int(10)))
+
+		require.Equal(t, c.DeleteRange(container.Int(20), container.Int(30), nil), 10)
+		require.Equal(t, c.Len(), 89)
+
+		c.Clear()
+		require.Equal(t, c.Len(), 0)
+	}
+
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			process(New(f()))
+		})
+	}
+}
+
+// Run with `go test -race` to check the data race.
+func TestContainer_Concurrent(t *testing.T) {
+	process := func(c *Container) {
+		const (
+			writers = 4
+			readers = 4
+			loops   = 1000
+			maxKey  = 256
+		)
+
+		var wg sync.WaitGroup
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(seed))
+				for i := 0; i < loops; i++ {
+					k := container.Int(r.Intn(maxKey))
+					switch r.Intn(6) {
+					case 0:
+						c.Insert(k, int(k))
+					case 1:
+						c.Delete(k)
+					case 2:
+						c.Upsert(k, int(k))
+					case 3:
+						c.UpdateInPlace(k, int(k))
+					case 4:
+						c.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+							return int(k), true
+						})
+					case 5:
+						c.DeleteRange(k, k+8, nil)
+					}
+				}
+			}(time.Now().UnixNano() + int64(w))
+		}
+
+		for rd := 0; rd < readers; rd++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(seed))
+				for i := 0; i < loops; i++ {
+					k := container.Int(r.Intn(maxKey))
+					switch r.Intn(4) {
+					case 0:
+						if ele := c.Search(k); ele != nil {
+							assert.Equal(t, ele.Value(), int(k))
+						}
+					case 1:
+						c.FirstGE(k)
+						c.LastLT(k)
+					case 2:
+						// The snapshot of Iter is always in order.
+						var last container.Key
+						it := c.Iter(nil, nil)
+						for it.Valid() {
+							ele := it.Next()
+							if last != nil {
+								assert.Equal(t, last.Compare(ele.Key()), -1)
+							}
+							assert.Equal(t, ele.Value(), int(ele.Key().(container.Int)))
+							last = ele.Key()
+						}
+					case 3:
+						c.Range(k, nil, func(ele container.Element) bool {
+							return ele.Key().Compare(k+16) == -1
+						})
+					}
+				}
+			}(time.Now().UnixNano() + int64(rd))
+		}
+
+		wg.Wait()
+
+		n := 0
+		c.Range(nil, nil, func(ele container.Element) bool {
+			n++
+			return true
+		})
+		require.Equal(t, c.Len(), n)
+	}
+
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			process(New(f()))
+		})
+	}
+}
+
+func TestContainer_RangeReentrant(t *testing.T) {
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			c := New(f())
+			for i := 0; i < 64; i++ {
+				c.Insert(container.Int(i), i)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				// The f accesses the Container while a writer is waiting for the lock.
+				var wg sync.WaitGroup
+				c.Range(nil, nil, func(ele container.Element) bool {
+					k := ele.Key().(container.Int)
+					if k < 64 {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							c.Insert(k+64, 0)
+						}()
+					}
+					assert.NotNil(t, c.Search(ele.Key()))
+					c.Update(ele.Key(), -1)
+					return true
+				})
+				wg.Wait()
+
+				c.Reverse(nil, nil, func(ele container.Element) bool {
+					assert.Equal(t, c.Len(), int(ele.Key().(container.Int))+1)
+					assert.NotNil(t, c.Delete(ele.Key()))
+					return ele.Key().Compare(container.Int(64)) != 0
+				})
+				assert.Equal(t, c.Len(), 64)
+				assert.Equal(t, c.Search(container.Int(0)).Value(), -1)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second * 10):
+				t.Fatal("deadlock in Range or Reverse")
+			}
+		})
+	}
+}
+
+func TestContainer_Batches(t *testing.T) {
+	// The elements with the same key are in one batch.
+	c := New(rb.NewMulti())
+	for i := 0; i < batchSize*3; i++ {
+		for j := 0; j < 3; j++ {
+			c.Insert(container.Int(i), j)
+		}
+	}
+	var expected []container.Element
+	c.ctr.Range(nil, nil, func(ele container.Element) bool {
+		expected = append(expected, snapshot(ele))
+		return true
+	})
+
+	var elements []container.Element
+	c.Range(nil, nil, func(ele container.Element) bool {
+		elements = append(elements, ele)
+		return true
+	})
+	require.Equal(t, elements, expected)
+
+	elements = nil
+	it := c.IterReverse(container.Int(1), nil)
+	for it.Valid() {
+		elements = append(elements, it.Next())
+	}
+	for i, j := 0, len(elements)-1; i < j; i, j = i+1, j-1 {
+		elements[i], elements[j] = elements[j], elements[i]
+	}
+	require.Equal(t, elements, expected[3:])
+
+	// The changes between batches are visible.
+	n := 0
+	c.Range(nil, nil, func(ele container.Element) bool {
+		if n == 0 {
+			c.Insert(container.Int(batchSize*3), 0)
+			c.DeleteRange(container.Int(batchSize*2), container.Int(batchSize*2+1), nil)
+		}
+		n++
+		return true
+	})
+	require.Equal(t, n, len(expected)-3+1)
+}
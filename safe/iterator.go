@@ -0,0 +1,102 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package safe
+
+import (
+	"github.com/yu31/structs-go/container"
+)
+
+var _ container.Iterator = (*Iterator)(nil)
+
+// The number of elements that loaded into a batch with the read lock held.
+const batchSize = 64
+
+// Iterator iterates over the elements in range batch by batch. Each batch is a consistent snapshot
+// that loaded with the read lock held, and the lock is released between batches.
+type Iterator struct {
+	c        *Container
+	start    container.Key
+	boundary container.Key
+	reverse  bool
+
+	last     container.Key // The key of the last element in the loaded batches.
+	done     bool          // Represents whether all batches have been loaded.
+	elements []container.Element
+	index    int
+}
+
+// creates an Iterator.
+func newIterator(c *Container, start container.Key, boundary container.Key, reverse bool) *Iterator {
+	return &Iterator{
+		c:        c,
+		start:    start,
+		boundary: boundary,
+		reverse:  reverse,
+	}
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *Iterator) Valid() bool {
+	if iter.index == len(iter.elements) && !iter.done {
+		iter.load()
+	}
+	return iter.index < len(iter.elements)
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *Iterator) Next() container.Element {
+	if !iter.Valid() {
+		return nil
+	}
+	ele := iter.elements[iter.index]
+	iter.elements[iter.index] = nil // Prevent memory leaks.
+	iter.index++
+	return ele
+}
+
+// Loads the next batch that behind the last key. The elements with the same key are
+// never split into two batches, so that the next batch can start after the last key.
+func (iter *Iterator) load() {
+	start, boundary := iter.start, iter.boundary
+	if iter.last != nil {
+		if iter.reverse {
+			boundary = iter.last
+		} else {
+			start = iter.last
+		}
+	}
+
+	elements := iter.elements[:0]
+	f := func(ele container.Element) bool {
+		k := ele.Key()
+		if !iter.reverse && iter.last != nil && k.Compare(iter.last) == 0 {
+			// The start is inclusive, skips the elements of last key.
+			return true
+		}
+		if len(elements) >= batchSize && k.Compare(elements[len(elements)-1].Key()) != 0 {
+			return false
+		}
+		elements = append(elements, snapshot(ele))
+		return true
+	}
+
+	iter.c.mu.RLock()
+	if iter.reverse {
+		iter.c.ctr.Reverse(start, boundary, f)
+	} else {
+		iter.c.ctr.Range(start, boundary, f)
+	}
+	iter.c.mu.RUnlock()
+
+	iter.elements = elements
+	iter.index = 0
+	if len(elements) < batchSize {
+		iter.done = true
+	} else {
+		iter.last = elements[len(elements)-1].Key()
+	}
+}
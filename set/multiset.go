@@ -0,0 +1,192 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package set
+
+import (
+	"github.com/yu31/structs-go/container"
+)
+
+// OrderedMultiset implements an ordered set that allows duplicate keys, it is backed by any container.Container.
+// The backing container stores each distinct key once, with the number of its occurrences as value.
+type OrderedMultiset struct {
+	ctr container.Container
+	len int
+}
+
+// NewMultiset creates an OrderedMultiset with the given container.
+// The container should be empty and should not be modified by others.
+func NewMultiset(ctr container.Container) *OrderedMultiset {
+	return &OrderedMultiset{
+		ctr: ctr,
+		len: 0,
+	}
+}
+
+// Len returns the number of keys in the multiset, the duplicate keys are counted.
+func (s *OrderedMultiset) Len() int {
+	return s.len
+}
+
+// Distinct returns the number of distinct keys in the multiset.
+func (s *OrderedMultiset) Distinct() int {
+	return s.ctr.Len()
+}
+
+// Add adds a key to the multiset, and returns the number of occurrences of the key after added.
+func (s *OrderedMultiset) Add(k Key) int {
+	return s.AddN(k, 1)
+}
+
+// AddN adds n occurrences of a key to the multiset, and returns the number of occurrences of the key after added.
+// Nothing happens if n <= 0.
+func (s *OrderedMultiset) AddN(k Key, n int) int {
+	if n <= 0 {
+		return s.Count(k)
+	}
+	ele := s.ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		if !exists {
+			return n, true
+		}
+		return ele.Value().(int) + n, true
+	})
+	s.len += n
+	return ele.Value().(int)
+}
+
+// Remove removes one occurrence of a key from the multiset.
+// Returns true if the key was removed, false if the key not exists.
+func (s *OrderedMultiset) Remove(k Key) bool {
+	return s.RemoveN(k, 1) == 1
+}
+
+// RemoveN removes at most n occurrences of a key from the multiset, and returns the number of removed occurrences.
+func (s *OrderedMultiset) RemoveN(k Key, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	removed := 0
+	s.ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		if !exists {
+			return nil, false
+		}
+		c := ele.Value().(int)
+		if c <= n {
+			removed = c
+			return nil, false
+		}
+		removed = n
+		return c - n, true
+	})
+	s.len -= removed
+	return removed
+}
+
+// RemoveAll removes all occurrences of a key from the multiset, and returns the number of removed occurrences.
+func (s *OrderedMultiset) RemoveAll(k Key) int {
+	ele := s.ctr.Delete(k)
+	if ele == nil {
+		return 0
+	}
+	n := ele.Value().(int)
+	s.len -= n
+	return n
+}
+
+// Count returns the number of occurrences of a key.
+func (s *OrderedMultiset) Count(k Key) int {
+	ele := s.ctr.Search(k)
+	if ele == nil {
+		return 0
+	}
+	return ele.Value().(int)
+}
+
+// Contains represents whether the key exists in the multiset.
+func (s *OrderedMultiset) Contains(k Key) bool {
+	return s.ctr.Search(k) != nil
+}
+
+// Clear removes all keys in the multiset.
+func (s *OrderedMultiset) Clear() {
+	s.ctr.Clear()
+	s.len = 0
+}
+
+// Floor returns the greatest key that less than or equal to the given key.
+// Returns nil if not found.
+func (s *OrderedMultiset) Floor(k Key) Key {
+	return elementKey(s.ctr.LastLE(k))
+}
+
+// Ceiling returns the least key that greater than or equal to the given key.
+// Returns nil if not found.
+func (s *OrderedMultiset) Ceiling(k Key) Key {
+	return elementKey(s.ctr.FirstGE(k))
+}
+
+// Lower returns the greatest key that less than the given key.
+// Returns nil if not found.
+func (s *OrderedMultiset) Lower(k Key) Key {
+	return elementKey(s.ctr.LastLT(k))
+}
+
+// Higher returns the least key that greater than the given key.
+// Returns nil if not found.
+func (s *OrderedMultiset) Higher(k Key) Key {
+	return elementKey(s.ctr.FirstGT(k))
+}
+
+// Range calls f sequentially each distinct key and its number of occurrences in ascending order.
+// If f returns false, Range stops the iteration.
+//
+// The range is start <= x < boundary.
+// The keys will return from the beginning if start is nil,
+// And return until the end if the boundary is nil.
+func (s *OrderedMultiset) Range(start Key, boundary Key, f func(k Key, count int) bool) {
+	s.ctr.Range(start, boundary, func(ele container.Element) bool {
+		return f(ele.Key(), ele.Value().(int))
+	})
+}
+
+// IsSubset represents whether the number of occurrences of every key in the multiset
+// is less than or equal to that in the other.
+func (s *OrderedMultiset) IsSubset(other *OrderedMultiset) bool {
+	if s.Len() > other.Len() || s.Distinct() > other.Distinct() {
+		return false
+	}
+	it1 := s.ctr.Iter(nil, nil)
+	it2 := other.ctr.Iter(nil, nil)
+	for it1.Valid() {
+		e1 := it1.Next()
+		// Skips the keys that only in other.
+		found := false
+		for it2.Valid() {
+			e2 := it2.Next()
+			cmp := e2.Key().Compare(e1.Key())
+			if cmp == 0 {
+				found = e1.Value().(int) <= e2.Value().(int)
+				break
+			}
+			if cmp == 1 {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSuperset represents whether the other is a subset of the multiset.
+func (s *OrderedMultiset) IsSuperset(other *OrderedMultiset) bool {
+	return other.IsSubset(s)
+}
+
+// Equal represents whether the multiset and the other contain the same keys with the same number of occurrences.
+func (s *OrderedMultiset) Equal(other *OrderedMultiset) bool {
+	return s.Len() == other.Len() && s.Distinct() == other.Distinct() && s.IsSubset(other)
+}
@@ -0,0 +1,101 @@
+package set
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+)
+
+func TestOrderedMultiset(t *testing.T) {
+	process := func(f func() container.Container) {
+		s := NewMultiset(f())
+		require.Equal(t, s.Len(), 0)
+
+		require.Equal(t, s.Add(container.Int(3)), 1)
+		require.Equal(t, s.Add(container.Int(3)), 2)
+		require.Equal(t, s.AddN(container.Int(3), 3), 5)
+		require.Equal(t, s.AddN(container.Int(3), 0), 5)
+		require.Equal(t, s.Add(container.Int(1)), 1)
+		require.Equal(t, s.AddN(container.Int(7), 2), 2)
+
+		require.Equal(t, s.Len(), 8)
+		require.Equal(t, s.Distinct(), 3)
+		require.Equal(t, s.Count(container.Int(3)), 5)
+		require.Equal(t, s.Count(container.Int(5)), 0)
+		require.True(t, s.Contains(container.Int(7)))
+		require.False(t, s.Contains(container.Int(5)))
+
+		require.Equal(t, s.Floor(container.Int(5)), container.Int(3))
+		require.Equal(t, s.Ceiling(container.Int(5)), container.Int(7))
+		require.Equal(t, s.Lower(container.Int(3)), container.Int(1))
+		require.Equal(t, s.Higher(container.Int(3)), container.Int(7))
+
+		var counts []int
+		s.Range(nil, nil, func(k Key, count int) bool {
+			counts = append(counts, count)
+			return true
+		})
+		require.Equal(t, counts, []int{1, 5, 2})
+
+		require.True(t, s.Remove(container.Int(3)))
+		require.Equal(t, s.Count(container.Int(3)), 4)
+		require.Equal(t, s.RemoveN(container.Int(3), 3), 3)
+		require.Equal(t, s.Count(container.Int(3)), 1)
+		require.Equal(t, s.RemoveN(container.Int(3), 3), 1)
+		require.False(t, s.Contains(container.Int(3)))
+		require.False(t, s.Remove(container.Int(3)))
+		require.Equal(t, s.Len(), 3)
+		require.Equal(t, s.Distinct(), 2)
+
+		require.Equal(t, s.RemoveAll(container.Int(7)), 2)
+		require.Equal(t, s.RemoveAll(container.Int(7)), 0)
+		require.Equal(t, s.Len(), 1)
+
+		s.Clear()
+		require.Equal(t, s.Len(), 0)
+		require.Equal(t, s.Distinct(), 0)
+	}
+
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			process(f)
+		})
+	}
+}
+
+func TestOrderedMultiset_Compare(t *testing.T) {
+	process := func(f func() container.Container) {
+		s1 := NewMultiset(f())
+		s2 := NewMultiset(f())
+		require.True(t, s1.IsSubset(s2))
+		require.True(t, s1.Equal(s2))
+
+		s1.AddN(container.Int(1), 2)
+		s1.AddN(container.Int(3), 1)
+		s2.AddN(container.Int(1), 3)
+		s2.AddN(container.Int(2), 1)
+		s2.AddN(container.Int(3), 1)
+
+		require.True(t, s1.IsSubset(s2))
+		require.True(t, s2.IsSuperset(s1))
+		require.False(t, s2.IsSubset(s1))
+		require.False(t, s1.Equal(s2))
+
+		// The number of occurrences exceeds.
+		s1.AddN(container.Int(3), 1)
+		require.False(t, s1.IsSubset(s2))
+
+		s1.Remove(container.Int(3))
+		s1.Add(container.Int(1))
+		s1.Add(container.Int(2))
+		require.True(t, s1.Equal(s2))
+	}
+
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			process(f)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package set
+
+import (
+	"github.com/yu31/structs-go/container"
+)
+
+// Type aliases for simplifying use in this package.
+
+type Key = container.Key
+
+// OrderedSet implements an ordered set of keys, it is backed by any container.Container.
+//
+// There is no key-only node: each key is stored as an element with a nil value in the backing container,
+// so a key costs as much memory as an element of the container, including the unused value field.
+type OrderedSet struct {
+	ctr container.Container
+}
+
+// New creates an OrderedSet with the given container.
+// The container should be empty and should not be modified by others.
+func New(ctr container.Container) *OrderedSet {
+	return &OrderedSet{
+		ctr: ctr,
+	}
+}
+
+// Len returns the number of keys in the set.
+func (s *OrderedSet) Len() int {
+	return s.ctr.Len()
+}
+
+// Add adds a key to the set. Returns true if the key was added, false if the key already exists.
+func (s *OrderedSet) Add(k Key) bool {
+	_, ok := s.ctr.Insert(k, nil)
+	return ok
+}
+
+// Remove removes a key from the set. Returns true if the key was removed, false if the key not exists.
+func (s *OrderedSet) Remove(k Key) bool {
+	return s.ctr.Delete(k) != nil
+}
+
+// Contains represents whether the key exists in the set.
+func (s *OrderedSet) Contains(k Key) bool {
+	return s.ctr.Search(k) != nil
+}
+
+// Clear removes all keys in the set.
+func (s *OrderedSet) Clear() {
+	s.ctr.Clear()
+}
+
+// Floor returns the greatest key that less than or equal to the given key.
+// Returns nil if not found.
+func (s *OrderedSet) Floor(k Key) Key {
+	return elementKey(s.ctr.LastLE(k))
+}
+
+// Ceiling returns the least key that greater than or equal to the given key.
+// Returns nil if not found.
+func (s *OrderedSet) Ceiling(k Key) Key {
+	return elementKey(s.ctr.FirstGE(k))
+}
+
+// Lower returns the greatest key that less than the given key.
+// Returns nil if not found.
+func (s *OrderedSet) Lower(k Key) Key {
+	return elementKey(s.ctr.LastLT(k))
+}
+
+// Higher returns the least key that greater than the given key.
+// Returns nil if not found.
+func (s *OrderedSet) Higher(k Key) Key {
+	return elementKey(s.ctr.FirstGT(k))
+}
+
+// Range calls f sequentially each key present in the set in ascending order.
+// If f returns false, Range stops the iteration.
+//
+// The range is start <= x < boundary.
+// The keys will return from the beginning if start is nil,
+// And return until the end if the boundary is nil.
+func (s *OrderedSet) Range(start Key, boundary Key, f func(k Key) bool) {
+	s.ctr.Range(start, boundary, func(ele container.Element) bool {
+		return f(ele.Key())
+	})
+}
+
+// Keys returns all keys in the set in ascending order.
+func (s *OrderedSet) Keys() []Key {
+	keys := make([]Key, 0, s.ctr.Len())
+	s.ctr.Range(nil, nil, func(ele container.Element) bool {
+		keys = append(keys, ele.Key())
+		return true
+	})
+	return keys
+}
+
+// IsSubset represents whether every key of the set is also in the other.
+func (s *OrderedSet) IsSubset(other *OrderedSet) bool {
+	if s.Len() > other.Len() {
+		return false
+	}
+	it1 := s.ctr.Iter(nil, nil)
+	it2 := other.ctr.Iter(nil, nil)
+	for it1.Valid() {
+		k := it1.Next().Key()
+		// Skips the keys that only in other.
+		found := false
+		for it2.Valid() {
+			cmp := it2.Next().Key().Compare(k)
+			if cmp == 0 {
+				found = true
+				break
+			}
+			if cmp == 1 {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSuperset represents whether every key of the other is also in the set.
+func (s *OrderedSet) IsSuperset(other *OrderedSet) bool {
+	return other.IsSubset(s)
+}
+
+// Equal represents whether the set and the other contain the same keys.
+func (s *OrderedSet) Equal(other *OrderedSet) bool {
+	return s.Len() == other.Len() && s.IsSubset(other)
+}
+
+func elementKey(ele container.Element) Key {
+	if ele == nil {
+		return nil
+	}
+	return ele.Key()
+}
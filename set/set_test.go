@@ -0,0 +1,120 @@
+package set
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/avl"
+	"github.com/yu31/structs-go/bs"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+var containers = map[string]func() container.Container{
+	"bstree": func() container.Container {
+		return bs.New()
+	},
+	"avltree": func() container.Container {
+		return avl.New()
+	},
+	"rbtree": func() container.Container {
+		return rb.New()
+	},
+	"skiplist": func() container.Container {
+		return skip.New()
+	},
+}
+
+func TestOrderedSet(t *testing.T) {
+	process := func(f func() container.Container) {
+		s := New(f())
+		require.Equal(t, s.Len(), 0)
+
+		for _, k := range []container.Int{5, 1, 9, 3, 7} {
+			require.True(t, s.Add(k))
+			require.False(t, s.Add(k))
+			require.True(t, s.Contains(k))
+		}
+		require.Equal(t, s.Len(), 5)
+		require.Equal(t, s.Keys(), []Key{container.Int(1), container.Int(3), container.Int(5), container.Int(7), container.Int(9)})
+
+		require.Equal(t, s.Floor(container.Int(4)), container.Int(3))
+		require.Equal(t, s.Floor(container.Int(5)), container.Int(5))
+		require.Nil(t, s.Floor(container.Int(0)))
+		require.Equal(t, s.Ceiling(container.Int(4)), container.Int(5))
+		require.Equal(t, s.Ceiling(container.Int(5)), container.Int(5))
+		require.Nil(t, s.Ceiling(container.Int(10)))
+		require.Equal(t, s.Lower(container.Int(5)), container.Int(3))
+		require.Nil(t, s.Lower(container.Int(1)))
+		require.Equal(t, s.Higher(container.Int(5)), container.Int(7))
+		require.Nil(t, s.Higher(container.Int(9)))
+
+		var keys []Key
+		s.Range(container.Int(3), container.Int(9), func(k Key) bool {
+			keys = append(keys, k)
+			return true
+		})
+		require.Equal(t, keys, []Key{container.Int(3), container.Int(5), container.Int(7)})
+
+		require.True(t, s.Remove(container.Int(5)))
+		require.False(t, s.Remove(container.Int(5)))
+		require.False(t, s.Contains(container.Int(5)))
+		require.Equal(t, s.Len(), 4)
+
+		s.Clear()
+		require.Equal(t, s.Len(), 0)
+		require.False(t, s.Contains(container.Int(1)))
+	}
+
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			process(f)
+		})
+	}
+}
+
+func TestOrderedSet_Compare(t *testing.T) {
+	process := func(f func() container.Container) {
+		s1 := New(f())
+		s2 := New(f())
+
+		// Both empty.
+		require.True(t, s1.IsSubset(s2))
+		require.True(t, s1.Equal(s2))
+
+		for _, k := range []container.Int{2, 4, 6} {
+			s1.Add(k)
+		}
+		for _, k := range []container.Int{1, 2, 3, 4, 5, 6} {
+			s2.Add(k)
+		}
+
+		require.True(t, s1.IsSubset(s2))
+		require.False(t, s2.IsSubset(s1))
+		require.True(t, s2.IsSuperset(s1))
+		require.False(t, s1.Equal(s2))
+
+		s1.Add(container.Int(7))
+		require.False(t, s1.IsSubset(s2))
+
+		s1.Remove(container.Int(7))
+		for _, k := range []container.Int{1, 3, 5} {
+			s1.Add(k)
+		}
+		require.True(t, s1.Equal(s2))
+		require.True(t, s2.Equal(s1))
+
+		s1.Remove(container.Int(6))
+		s1.Add(container.Int(8))
+		require.False(t, s1.Equal(s2))
+		require.False(t, s1.IsSubset(s2))
+	}
+
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			process(f)
+		})
+	}
+}
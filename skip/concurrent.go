@@ -0,0 +1,675 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package skip
+
+import (
+	"iter"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	_ container.Container      = (*ConcurrentList)(nil)
+	_ container.Element        = (*concurrentNode)(nil)
+	_ container.MutableElement = (*concurrentNode)(nil)
+	_ container.Element        = (*element)(nil)
+)
+
+// markableRef is an immutable reference to the next node with a mark bit.
+// The mark bit represents whether the node that owns the reference has been removed from the level.
+// A new markableRef is created for each change, so that the reference and mark bit can be updated atomically with a CAS.
+type markableRef struct {
+	node   *concurrentNode
+	marked bool
+}
+
+// valueBox is an immutable holder of value.
+// The deleted represents whether the node has been logically deleted.
+type valueBox struct {
+	value   container.Value
+	deleted bool
+}
+
+// concurrentNode is used for ConcurrentList.
+type concurrentNode struct {
+	key   container.Key
+	value unsafe.Pointer   // *valueBox
+	next  []unsafe.Pointer // []*markableRef
+}
+
+// Key returns the key.
+func (n *concurrentNode) Key() container.Key {
+	return n.key
+}
+
+// Value returns the value.
+func (n *concurrentNode) Value() container.Value {
+	return n.loadValue().value
+}
+
+// SetValue sets the value in place. Nothing happens if the node has been deleted.
+func (n *concurrentNode) SetValue(v container.Value) {
+	for {
+		b := n.loadValue()
+		if b.deleted || n.casValue(b, &valueBox{value: v}) {
+			return
+		}
+	}
+}
+
+func (n *concurrentNode) loadValue() *valueBox {
+	return (*valueBox)(atomic.LoadPointer(&n.value))
+}
+
+func (n *concurrentNode) casValue(old, b *valueBox) bool {
+	return atomic.CompareAndSwapPointer(&n.value, unsafe.Pointer(old), unsafe.Pointer(b))
+}
+
+func (n *concurrentNode) loadNext(level int) *markableRef {
+	return (*markableRef)(atomic.LoadPointer(&n.next[level]))
+}
+
+func (n *concurrentNode) casNext(level int, old, ref *markableRef) bool {
+	return atomic.CompareAndSwapPointer(&n.next[level], unsafe.Pointer(old), unsafe.Pointer(ref))
+}
+
+// element is a snapshot of key and value, used to return the old value of an element.
+type element struct {
+	key   container.Key
+	value container.Value
+}
+
+// Key returns the key.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value.
+func (e *element) Value() container.Value {
+	return e.value
+}
+
+// ConcurrentList implements a lock-free Skip List that is safe for concurrent use by multiple goroutines.
+//
+// The Insert, Delete, Update, Upsert and Search are linearizable. A node is inserted by CAS
+// on the bottom level and then linked to the upper levels. A node is deleted logically by CAS on its value,
+// and then removed physically by marking its references and unlinking it from each level.
+//
+// The Range, Iter and Searcher methods are weakly consistent, they never return an element more than once,
+// and reflect some state of the list at or since the call, but may not reflect the concurrent changes.
+type ConcurrentList struct {
+	// The fields seed and len accessed atomically, keep them at the beginning for 64-bit alignment.
+	seed uint64
+	len  int64
+	head *concurrentNode
+}
+
+// NewConcurrent creates a lock-free Skip List.
+func NewConcurrent() *ConcurrentList {
+	sl := &ConcurrentList{
+		seed: uint64(time.Now().UnixNano()),
+		len:  0,
+	}
+	sl.head = sl.createNode(nil, nil, maxLevel)
+	return sl
+}
+
+// Len returns the number of elements.
+func (sl *ConcurrentList) Len() int {
+	return int(atomic.LoadInt64(&sl.len))
+}
+
+// Insert inserts a new element if the key doesn't exist, or returns the existing element for the key if present.
+// The bool result is true if an element was inserted, false if searched.
+func (sl *ConcurrentList) Insert(k container.Key, v container.Value) (container.Element, bool) {
+	var preds, succs [maxLevel + 1]*concurrentNode
+	var refs [maxLevel + 1]*markableRef
+
+	for {
+		if sl.find(k, preds[:], succs[:], refs[:]) {
+			node := succs[0]
+			if !node.loadValue().deleted {
+				return node, false
+			}
+			// The node is being deleted, helps to remove it and retry.
+			sl.markNode(node)
+			continue
+		}
+		if node := sl.linkNode(k, v, preds[:], succs[:], refs[:]); node != nil {
+			return node, true
+		}
+	}
+}
+
+// Delete removes and returns the element of a given key.
+// Returns nil if key not found.
+func (sl *ConcurrentList) Delete(k container.Key) container.Element {
+	var preds, succs [maxLevel + 1]*concurrentNode
+	var refs [maxLevel + 1]*markableRef
+
+	if !sl.find(k, preds[:], succs[:], refs[:]) {
+		return nil
+	}
+	node := succs[0]
+	if !sl.deleteNode(node) {
+		return nil
+	}
+	return node
+}
+
+// Update updates an element with the given key and value, And returns the old element of key.
+// Returns nil if the key not be found.
+//
+// The value is updated in place, and the returned element is a snapshot of the old key and value.
+func (sl *ConcurrentList) Update(k container.Key, v container.Value) container.Element {
+	node := sl.searchNode(k)
+	if node == nil {
+		return nil
+	}
+	old, ok := sl.swapValue(node, v)
+	if !ok {
+		return nil
+	}
+	return &element{key: node.key, value: old}
+}
+
+// Upsert inserts or updates an element by giving key and value.
+// The bool result is true if an element was inserted, false if an element was updated.
+//
+// The value is updated in place, and the returned element is a snapshot of the old key and value if updated.
+func (sl *ConcurrentList) Upsert(k container.Key, v container.Value) (container.Element, bool) {
+	var old container.Value
+	node, ok := sl.upsert(k, v, &old)
+	if ok {
+		return node, true
+	}
+	return &element{key: node.key, value: old}, false
+}
+
+// UpdateInPlace is similar to the Update method, but it returns the element of key and its old value.
+// Returns nil if the key not be found.
+func (sl *ConcurrentList) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	node := sl.searchNode(k)
+	if node == nil {
+		return nil, nil
+	}
+	old, ok := sl.swapValue(node, v)
+	if !ok {
+		return nil, nil
+	}
+	return node, old
+}
+
+// UpsertInPlace is similar to the Upsert method, but it returns the element of key.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (sl *ConcurrentList) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	var old container.Value
+	return sl.upsert(k, v, &old)
+}
+
+// Search searches the element of a given key.
+// Returns nil if key not found.
+func (sl *ConcurrentList) Search(k container.Key) container.Element {
+	node := sl.searchNode(k)
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f atomically.
+//
+// The f may be called more than once if there are concurrent changes of the key, so it should have no side effects.
+// Returns the element of key after computed, nil if no element for the key.
+func (sl *ConcurrentList) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) container.Element {
+	var preds, succs [maxLevel + 1]*concurrentNode
+	var refs [maxLevel + 1]*markableRef
+
+	for {
+		if sl.find(k, preds[:], succs[:], refs[:]) {
+			node := succs[0]
+			b := node.loadValue()
+			if b.deleted {
+				sl.markNode(node)
+				continue
+			}
+
+			v, keep := f(&element{key: node.key, value: b.value}, true)
+			if keep {
+				if node.casValue(b, &valueBox{value: v}) {
+					return node
+				}
+				continue
+			}
+			if node.casValue(b, &valueBox{value: b.value, deleted: true}) {
+				atomic.AddInt64(&sl.len, -1)
+				sl.markNode(node)
+				return nil
+			}
+			continue
+		}
+
+		v, keep := f(nil, false)
+		if !keep {
+			return nil
+		}
+		if node := sl.linkNode(k, v, preds[:], succs[:], refs[:]); node != nil {
+			return node
+		}
+	}
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+//
+// The f is called at most once, but it may be called even though the key is inserted by others concurrently.
+func (sl *ConcurrentList) GetOrInsert(k container.Key, f func() container.Value) (container.Element, bool) {
+	var preds, succs [maxLevel + 1]*concurrentNode
+	var refs [maxLevel + 1]*markableRef
+
+	var v container.Value
+	var built bool
+
+	for {
+		if sl.find(k, preds[:], succs[:], refs[:]) {
+			node := succs[0]
+			if !node.loadValue().deleted {
+				return node, false
+			}
+			sl.markNode(node)
+			continue
+		}
+		if !built {
+			v = f()
+			built = true
+		}
+		if node := sl.linkNode(k, v, preds[:], succs[:], refs[:]); node != nil {
+			return node, true
+		}
+	}
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+//
+// Each element is removed atomically, but the DeleteRange as a whole is not atomic.
+func (sl *ConcurrentList) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return 0
+	}
+
+	n := 0
+	var node *concurrentNode
+	if start == nil {
+		node = sl.head.loadNext(0).node
+	} else {
+		_, node = sl.searchPredecessor(start, false)
+	}
+	for ; node != nil && (boundary == nil || node.key.Compare(boundary) == -1); node = node.loadNext(0).node {
+		if sl.deleteNode(node) {
+			if f != nil {
+				f(node)
+			}
+			n++
+		}
+	}
+	return n
+}
+
+// Clear removes all elements in the list.
+//
+// It is same as DeleteRange(nil, nil, nil), so it is not atomic and costs O(n).
+func (sl *ConcurrentList) Clear() {
+	sl.DeleteRange(nil, nil, nil)
+}
+
+// Iter creates an Iterator positioned on the first element that key >= start key.
+// The Iterator is weakly consistent.
+func (sl *ConcurrentList) Iter(start container.Key, boundary container.Key) container.Iterator {
+	return newConcurrentIterator(sl, start, boundary)
+}
+
+// IterReverse creates an reversed Iterator over a snapshot of the elements in range.
+func (sl *ConcurrentList) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	return &reverseIterator{elements: sl.collect(start, boundary)}
+}
+
+// All returns an iterator over all elements in ascending order.
+func (sl *ConcurrentList) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(sl)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (sl *ConcurrentList) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(sl)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (sl *ConcurrentList) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(sl, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (sl *ConcurrentList) Keys() iter.Seq[container.Key] {
+	return container.Keys(sl)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (sl *ConcurrentList) Values() iter.Seq[container.Value] {
+	return container.Values(sl)
+}
+
+// Range calls f sequentially each element present in the list.
+// If f returns false, range stops the iteration.
+func (sl *ConcurrentList) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	it := newConcurrentIterator(sl, start, boundary)
+	for it.Valid() {
+		if !f(it.Next()) {
+			return
+		}
+	}
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+// It iterates over a snapshot of the elements in range.
+func (sl *ConcurrentList) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	elements := sl.collect(start, boundary)
+	for i := len(elements) - 1; i >= 0; i-- {
+		if !f(elements[i]) {
+			return
+		}
+	}
+}
+
+// LastLT searches for the last node that less than the key.
+func (sl *ConcurrentList) LastLT(k container.Key) container.Element {
+	node := sl.searchLast(k, false)
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// LastLE search for the last node that less than or equal to the key.
+func (sl *ConcurrentList) LastLE(k container.Key) container.Element {
+	node := sl.searchLast(k, true)
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// FirstGT search for the first node that greater than to the key.
+func (sl *ConcurrentList) FirstGT(k container.Key) container.Element {
+	node := sl.searchFirst(k, false)
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// FirstGE search for the first node that greater than or equal to the key.
+func (sl *ConcurrentList) FirstGE(k container.Key) container.Element {
+	node := sl.searchFirst(k, true)
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// Creates a new node with the giving key and value.
+func (sl *ConcurrentList) createNode(k container.Key, v container.Value, level int) *concurrentNode {
+	n := &concurrentNode{
+		key:   k,
+		value: unsafe.Pointer(&valueBox{value: v}),
+		next:  make([]unsafe.Pointer, level+1),
+	}
+	for i := 0; i <= level; i++ {
+		n.next[i] = unsafe.Pointer(&markableRef{})
+	}
+	return n
+}
+
+// Chooses the level of new node with a lock-free pseudo random generator (splitmix64).
+func (sl *ConcurrentList) chooseLevel() int {
+	x := atomic.AddUint64(&sl.seed, 0x9e3779b97f4a7c15)
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+
+	level := 0
+	for x&1 == 1 && level < maxLevel {
+		level++
+		x >>= 1
+	}
+	return level
+}
+
+// Searches the predecessors and successors of key in each level, and unlinks the marked nodes during the search.
+// The refs is the references of preds that point to succs, they are never marked.
+// The search starts over if a pred has been marked, because a marked reference must not be changed.
+// Returns true if the node of key found in the bottom level, it is the succs[0].
+func (sl *ConcurrentList) find(k container.Key, preds []*concurrentNode, succs []*concurrentNode, refs []*markableRef) bool {
+	sl.locate(k, false, preds, succs, refs)
+	return succs[0] != nil && succs[0].key.Compare(k) == 0
+}
+
+// The locate is similar to the find, but the preds are the last nodes that less than the key,
+// or less than or equal to the key if inclusive is true.
+func (sl *ConcurrentList) locate(k container.Key, inclusive bool, preds []*concurrentNode, succs []*concurrentNode, refs []*markableRef) {
+retry:
+	for {
+		pred := sl.head
+		for level := maxLevel; level >= 0; level-- {
+			ref := pred.loadNext(level)
+			if ref.marked {
+				// The pred is being removed concurrently.
+				continue retry
+			}
+			curr := ref.node
+			for curr != nil {
+				currRef := curr.loadNext(level)
+				if currRef.marked {
+					// Unlinks the marked node, starts over if pred changed or marked.
+					newRef := &markableRef{node: currRef.node}
+					if !pred.casNext(level, ref, newRef) {
+						continue retry
+					}
+					ref = newRef
+					curr = newRef.node
+					continue
+				}
+				cmp := curr.key.Compare(k)
+				if cmp == 1 || (cmp == 0 && !inclusive) {
+					break
+				}
+				pred = curr
+				ref = currRef
+				curr = currRef.node
+			}
+			preds[level] = pred
+			succs[level] = curr
+			refs[level] = ref
+		}
+		return
+	}
+}
+
+// Creates and links a new node between preds and succs.
+// Returns nil if the preds changed before the node linked in the bottom level.
+func (sl *ConcurrentList) linkNode(k container.Key, v container.Value, preds []*concurrentNode, succs []*concurrentNode, refs []*markableRef) *concurrentNode {
+	level := sl.chooseLevel()
+	node := sl.createNode(k, v, level)
+	for i := 0; i <= level; i++ {
+		node.next[i] = unsafe.Pointer(&markableRef{node: succs[i]})
+	}
+
+	// The node is added to the list logically after linked in the bottom level.
+	if !preds[0].casNext(0, refs[0], &markableRef{node: node}) {
+		return nil
+	}
+	atomic.AddInt64(&sl.len, 1)
+
+	// Links the node in the upper levels.
+	for i := 1; i <= level; i++ {
+		for {
+			ref := node.loadNext(i)
+			if ref.marked {
+				// The node has been deleted concurrently.
+				return node
+			}
+			if ref.node != succs[i] && !node.casNext(i, ref, &markableRef{node: succs[i]}) {
+				continue
+			}
+			if preds[i].casNext(i, refs[i], &markableRef{node: node}) {
+				break
+			}
+			sl.find(k, preds, succs, refs)
+		}
+		if node.loadNext(0).marked {
+			// The node has been deleted concurrently, and the markNode may have finished before it linked
+			// in this level. Unlinks it again to avoid leaving it in the level.
+			sl.find(k, preds, succs, refs)
+			return node
+		}
+	}
+	return node
+}
+
+// Deletes the node logically by CAS on its value, and then removes it physically.
+// Returns false if the node has been deleted by others.
+func (sl *ConcurrentList) deleteNode(node *concurrentNode) bool {
+	for {
+		b := node.loadValue()
+		if b.deleted {
+			return false
+		}
+		if node.casValue(b, &valueBox{value: b.value, deleted: true}) {
+			atomic.AddInt64(&sl.len, -1)
+			sl.markNode(node)
+			return true
+		}
+	}
+}
+
+// Marks the references of a logically deleted node from the top level to the bottom level,
+// and then unlinks it from each level.
+func (sl *ConcurrentList) markNode(node *concurrentNode) {
+	for i := len(node.next) - 1; i >= 0; i-- {
+		for {
+			ref := node.loadNext(i)
+			if ref.marked || node.casNext(i, ref, &markableRef{node: ref.node, marked: true}) {
+				break
+			}
+		}
+	}
+
+	var preds, succs [maxLevel + 1]*concurrentNode
+	var refs [maxLevel + 1]*markableRef
+	sl.find(node.key, preds[:], succs[:], refs[:])
+}
+
+// Sets the value of node in place, and returns the old value.
+// Returns false if the node has been deleted.
+func (sl *ConcurrentList) swapValue(node *concurrentNode, v container.Value) (container.Value, bool) {
+	for {
+		b := node.loadValue()
+		if b.deleted {
+			return nil, false
+		}
+		if node.casValue(b, &valueBox{value: v}) {
+			return b.value, true
+		}
+	}
+}
+
+// Inserts a new node or sets the value of existing node in place, and stores the old value to old.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (sl *ConcurrentList) upsert(k container.Key, v container.Value, old *container.Value) (*concurrentNode, bool) {
+	var preds, succs [maxLevel + 1]*concurrentNode
+	var refs [maxLevel + 1]*markableRef
+
+	for {
+		if sl.find(k, preds[:], succs[:], refs[:]) {
+			node := succs[0]
+			if value, ok := sl.swapValue(node, v); ok {
+				*old = value
+				return node, false
+			}
+			sl.markNode(node)
+			continue
+		}
+		if node := sl.linkNode(k, v, preds[:], succs[:], refs[:]); node != nil {
+			return node, true
+		}
+	}
+}
+
+// Search the node of a given key.
+// Returns nil if key not found or it has been deleted.
+func (sl *ConcurrentList) searchNode(k container.Key) *concurrentNode {
+	_, node := sl.searchPredecessor(k, false)
+	if node == nil || node.key.Compare(k) != 0 || node.loadValue().deleted {
+		return nil
+	}
+	return node
+}
+
+// Search the last node that less than the key, or less than or equal to the key if inclusive is true,
+// and the node next to it in the bottom level. The marked nodes are unlinked during the search as the find does,
+// so that a node linked after its predecessor removed is never missed.
+// Returns the head as pred if not found. The returned nodes may have been deleted.
+func (sl *ConcurrentList) searchPredecessor(k container.Key, inclusive bool) (pred *concurrentNode, succ *concurrentNode) {
+	var preds, succs [maxLevel + 1]*concurrentNode
+	var refs [maxLevel + 1]*markableRef
+
+	sl.locate(k, inclusive, preds[:], succs[:], refs[:])
+	return preds[0], succs[0]
+}
+
+// Search the last node that not deleted and less than the key, or less than or equal to the key if inclusive is true.
+func (sl *ConcurrentList) searchLast(k container.Key, inclusive bool) *concurrentNode {
+	for {
+		node, _ := sl.searchPredecessor(k, inclusive)
+		if node == sl.head {
+			return nil
+		}
+		if !node.loadValue().deleted {
+			return node
+		}
+		// The node has been deleted, searches the one in front of it.
+		k = node.key
+		inclusive = false
+	}
+}
+
+// Search the first node that not deleted and greater than the key, or greater than or equal to the key if inclusive is true.
+func (sl *ConcurrentList) searchFirst(k container.Key, inclusive bool) *concurrentNode {
+	for {
+		_, node := sl.searchPredecessor(k, !inclusive)
+		if node == nil || !node.loadValue().deleted {
+			return node
+		}
+		// The node has been deleted, searches the one behind it.
+		k = node.key
+		inclusive = false
+	}
+}
+
+// Collects the elements in range into a slice.
+func (sl *ConcurrentList) collect(start container.Key, boundary container.Key) []container.Element {
+	var elements []container.Element
+	sl.Range(start, boundary, func(ele container.Element) bool {
+		elements = append(elements, ele)
+		return true
+	})
+	return elements
+}
@@ -0,0 +1,331 @@
+package skip
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+)
+
+func checkConcurrentCorrect(t *testing.T, sl *ConcurrentList) {
+	for i := 0; i <= maxLevel; i++ {
+		p := sl.head.loadNext(i).node
+		for p != nil {
+			ref := p.loadNext(i)
+			require.False(t, ref.marked)
+			require.False(t, p.loadValue().deleted)
+			if ref.node != nil {
+				require.Equal(t, p.key.Compare(ref.node.key), -1)
+			}
+			p = ref.node
+		}
+	}
+}
+
+func TestNewConcurrent(t *testing.T) {
+	sl := NewConcurrent()
+
+	require.NotNil(t, sl)
+	require.NotNil(t, sl.head)
+	require.Equal(t, sl.Len(), 0)
+	require.Equal(t, len(sl.head.next), maxLevel+1)
+
+	for i := 0; i <= maxLevel; i++ {
+		require.Nil(t, sl.head.loadNext(i).node)
+	}
+}
+
+func TestConcurrentList(t *testing.T) {
+	sl := NewConcurrent()
+
+	length := 257
+	maxKey := length * 100
+	keys := make([]container.Int64, length)
+
+	for x := 0; x < 2; x++ {
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		// insert
+		for i := 0; i < length; i++ {
+			for {
+				k := container.Int64(r.Intn(maxKey) + 1)
+				if _, ok := sl.Insert(k, int64(k*2+1)); ok {
+					keys[i] = k
+					break
+				}
+			}
+			checkConcurrentCorrect(t, sl)
+			require.Equal(t, sl.Len(), i+1)
+		}
+
+		// search
+		for i := 0; i < length; i++ {
+			element := sl.Search(keys[i])
+			require.NotNil(t, element)
+			require.Equal(t, element.Value(), int64(keys[i]*2+1))
+		}
+
+		// delete
+		for i := 0; i < length; i++ {
+			element := sl.Delete(keys[i])
+			require.NotNil(t, element)
+			require.Equal(t, element.Value(), int64(keys[i]*2+1))
+			require.Nil(t, sl.Delete(keys[i]))
+			require.Nil(t, sl.Search(keys[i]))
+
+			checkConcurrentCorrect(t, sl)
+			require.Equal(t, sl.Len(), length-i-1)
+		}
+
+		for i := 0; i <= maxLevel; i++ {
+			require.Nil(t, sl.head.loadNext(i).node)
+		}
+	}
+}
+
+func TestConcurrentList_Parallel(t *testing.T) {
+	sl := NewConcurrent()
+
+	workers := 8
+	length := 2048
+
+	// Inserts disjoint keys concurrently.
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < length; i += workers {
+				_, ok := sl.Insert(container.Int(i), i)
+				assert.True(t, ok)
+			}
+		}(w)
+	}
+	wg.Wait()
+	require.Equal(t, sl.Len(), length)
+	checkConcurrentCorrect(t, sl)
+
+	// Reads, updates and deletes concurrently.
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < length; i += workers {
+				if i%2 == 0 {
+					assert.NotNil(t, sl.Delete(container.Int(i)))
+				} else {
+					sl.Upsert(container.Int(i), i*2)
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			var prev container.Key
+			sl.Range(nil, nil, func(ele container.Element) bool {
+				if prev != nil {
+					assert.Equal(t, prev.Compare(ele.Key()), -1)
+				}
+				prev = ele.Key()
+				return true
+			})
+			for i := 1; i < length; i += 2 {
+				assert.NotNil(t, sl.Search(container.Int(i)))
+				assert.NotNil(t, sl.LastLE(container.Int(i)))
+				assert.NotNil(t, sl.FirstGE(container.Int(i)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, sl.Len(), length/2)
+	checkConcurrentCorrect(t, sl)
+	for i := 0; i < length; i++ {
+		ele := sl.Search(container.Int(i))
+		if i%2 == 0 {
+			require.Nil(t, ele)
+		} else {
+			require.Equal(t, ele.Value(), i*2)
+		}
+	}
+}
+
+func TestConcurrentList_Contention(t *testing.T) {
+	sl := NewConcurrent()
+
+	workers := 8
+	rounds := 2000
+	keys := 16
+
+	var wg sync.WaitGroup
+	counts := make([]int, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < rounds; i++ {
+				k := container.Int(r.Intn(keys))
+				switch r.Intn(3) {
+				case 0:
+					if _, ok := sl.Insert(k, w); ok {
+						counts[w]++
+					}
+				case 1:
+					if sl.Delete(k) != nil {
+						counts[w]--
+					}
+				case 2:
+					// Increments the value of key if exists.
+					sl.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+						if !exists {
+							return 0, false
+						}
+						return ele.Value().(int) + 1, true
+					})
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	require.Equal(t, sl.Len(), total)
+
+	n := 0
+	sl.Range(nil, nil, func(ele container.Element) bool {
+		n++
+		return true
+	})
+	require.Equal(t, n, total)
+}
+
+func TestConcurrentList_Compute(t *testing.T) {
+	sl := NewConcurrent()
+
+	workers := 8
+	rounds := 1000
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				sl.Compute(container.Int(i%4), func(ele container.Element, exists bool) (container.Value, bool) {
+					if !exists {
+						return 1, true
+					}
+					return ele.Value().(int) + 1, true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, sl.Len(), 4)
+	for i := 0; i < 4; i++ {
+		require.Equal(t, sl.Search(container.Int(i)).Value(), workers*rounds/4)
+	}
+}
+
+func TestConcurrentList_Model(t *testing.T) {
+	sl := NewConcurrent()
+
+	workers := 8
+	rounds := 20000
+	keys := 64
+
+	// Each worker owns the keys that k % workers == w, so the result of each operation on the key
+	// must be same as its own model, and the neighbors of the key are changed by others concurrently.
+	var wg sync.WaitGroup
+	models := make([]map[int]int, workers)
+	for w := 0; w < workers; w++ {
+		models[w] = make(map[int]int)
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			model := models[w]
+			r := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < rounds; i++ {
+				k := r.Intn(keys)*workers + w
+				v, exists := model[k]
+				switch r.Intn(5) {
+				case 0:
+					ele, ok := sl.Insert(container.Int(k), i)
+					assert.Equal(t, ok, !exists)
+					if ok {
+						model[k] = i
+					} else {
+						assert.Equal(t, ele.Value(), v)
+					}
+				case 1:
+					ele := sl.Delete(container.Int(k))
+					if exists {
+						if assert.NotNil(t, ele) {
+							assert.Equal(t, ele.Value(), v)
+						}
+						delete(model, k)
+					} else {
+						assert.Nil(t, ele)
+					}
+				case 2:
+					_, ok := sl.Upsert(container.Int(k), i)
+					assert.Equal(t, ok, !exists)
+					model[k] = i
+				case 3:
+					ele := sl.Search(container.Int(k))
+					if exists {
+						if assert.NotNil(t, ele) {
+							assert.Equal(t, ele.Value(), v)
+						}
+					} else {
+						assert.Nil(t, ele)
+					}
+				case 4:
+					ele := sl.LastLE(container.Int(k))
+					if exists {
+						if assert.NotNil(t, ele) {
+							assert.Equal(t, ele.Key(), container.Int(k))
+						}
+					} else if ele != nil {
+						assert.Equal(t, ele.Key().Compare(container.Int(k)), -1)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	expected := make(map[int]int)
+	for _, model := range models {
+		for k, v := range model {
+			expected[k] = v
+		}
+	}
+	require.Equal(t, sl.Len(), len(expected))
+	checkConcurrentCorrect(t, sl)
+
+	n := 0
+	sl.Range(nil, nil, func(ele container.Element) bool {
+		v, ok := expected[int(ele.Key().(container.Int))]
+		require.True(t, ok)
+		require.Equal(t, ele.Value(), v)
+		n++
+		return true
+	})
+	require.Equal(t, n, len(expected))
+
+	// All nodes in the upper levels must be linked in the bottom level.
+	for i := 1; i <= maxLevel; i++ {
+		for p := sl.head.loadNext(i).node; p != nil; p = p.loadNext(i).node {
+			require.Equal(t, sl.searchNode(p.key), p)
+		}
+	}
+}
@@ -62,3 +62,145 @@ func (iter *Iterator) Next() container.Element {
 	iter.node = iter.node.next[0]
 	return n
 }
+
+var (
+	_ container.Iterator = (*ConcurrentIterator)(nil)
+	_ container.Iterator = (*reverseIterator)(nil)
+)
+
+// ConcurrentIterator is an Iterator for ConcurrentList, it skips the deleted elements.
+// The ranges is: start <= x < boundary.
+type ConcurrentIterator struct {
+	node     *concurrentNode
+	boundary container.Key
+}
+
+// creates an ConcurrentIterator.
+func newConcurrentIterator(sl *ConcurrentList, start container.Key, boundary container.Key) *ConcurrentIterator {
+	iter := &ConcurrentIterator{
+		node:     nil,
+		boundary: boundary,
+	}
+	// If both the start and boundary are not nil, the start should less than the boundary.
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return iter
+	}
+	if start == nil {
+		iter.seek(sl.head.loadNext(0).node)
+	} else {
+		iter.seek(sl.searchFirst(start, true))
+	}
+	return iter
+}
+
+// Positions the iterator on the first node that not deleted from n.
+func (iter *ConcurrentIterator) seek(n *concurrentNode) {
+	for n != nil && n.loadValue().deleted {
+		n = n.loadNext(0).node
+	}
+	if n != nil && iter.boundary != nil && n.key.Compare(iter.boundary) != -1 {
+		n = nil
+	}
+	iter.node = n
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *ConcurrentIterator) Valid() bool {
+	return iter.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *ConcurrentIterator) Next() container.Element {
+	if !iter.Valid() {
+		return nil
+	}
+	n := iter.node
+	iter.seek(n.loadNext(0).node)
+	return n
+}
+
+// reverseIterator iterates over a snapshot of elements in reverse.
+type reverseIterator struct {
+	elements []container.Element
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *reverseIterator) Valid() bool {
+	return len(iter.elements) != 0
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *reverseIterator) Next() container.Element {
+	if !iter.Valid() {
+		return nil
+	}
+	n := iter.elements[len(iter.elements)-1]
+	iter.elements = iter.elements[:len(iter.elements)-1]
+	return n
+}
+
+var _ container.Iterator = (*ReverseIterator)(nil)
+
+// ReverseIterator is an Iterator for List that yields the elements in the range start <= x < boundary
+// in reverse. The List is singly linked, so it searches the predecessor of each key in O(log n).
+type ReverseIterator struct {
+	sl    *List
+	start container.Key
+	// The remaining nodes of current key in insertion order, they're yielded from the end.
+	nodes []*listNode
+}
+
+// creates an ReverseIterator.
+func newReverseIterator(sl *List, start container.Key, boundary container.Key) *ReverseIterator {
+	iter := &ReverseIterator{
+		sl:    sl,
+		start: start,
+	}
+	// If both the start and boundary are not nil, the start should less than the boundary.
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return iter
+	}
+	if boundary == nil {
+		iter.load(sl.searchLast())
+	} else {
+		iter.load(sl.searchLastLT(boundary))
+	}
+	return iter
+}
+
+// Loads the nodes with the same key as last, the last must be the last node of its key.
+func (iter *ReverseIterator) load(last *listNode) {
+	iter.nodes = iter.nodes[:0]
+	if last == nil || (iter.start != nil && last.key.Compare(iter.start) == -1) {
+		return
+	}
+	if !iter.sl.multi {
+		iter.nodes = append(iter.nodes, last)
+		return
+	}
+	for n := iter.sl.searchFirstGE(last.key); n != last; n = n.next[0] {
+		iter.nodes = append(iter.nodes, n)
+	}
+	iter.nodes = append(iter.nodes, last)
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *ReverseIterator) Valid() bool {
+	return len(iter.nodes) != 0
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *ReverseIterator) Next() container.Element {
+	if !iter.Valid() {
+		return nil
+	}
+	n := iter.nodes[len(iter.nodes)-1]
+	iter.nodes = iter.nodes[:len(iter.nodes)-1]
+	if len(iter.nodes) == 0 {
+		iter.load(iter.sl.searchLastLT(n.key))
+	}
+	return n
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package skip
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	_ json.Marshaler   = (*List)(nil)
+	_ json.Unmarshaler = (*List)(nil)
+)
+
+// MarshalJSON encodes all elements as a JSON array in ascending order by key.
+// See container.JSONEncoder for the format of elements.
+func (sl *List) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := container.WriteJSON(&buf, sl, nil, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON replaces all elements with the elements decoded from a JSON array.
+// The elements with duplicate key are ignored except the first unless the list allows duplicate keys.
+//
+// A zero List is initialized before decoding, so it can be used as the target of json.Unmarshal.
+func (sl *List) UnmarshalJSON(data []byte) error {
+	if sl.head == nil {
+		multi := sl.multi
+		*sl = *New()
+		sl.multi = multi
+	}
+	sl.Clear()
+	if _, err := container.ReadJSON(bytes.NewReader(data), sl); err != nil {
+		sl.Clear()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package skip
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/internal/scan"
+)
+
+// RangeContext is similar to the Range method, but it checks the ctx periodically and stops
+// the iteration if ctx is done. Returns the error of ctx in that case.
+func (sl *List) RangeContext(ctx context.Context, start container.Key, boundary container.Key, f func(ele container.Element) bool) error {
+	return scan.Context(ctx, sl.Range, start, boundary, f)
+}
+
+// ParallelRange calls f with each element in the range start <= x < boundary concurrently in at most
+// workers goroutines, the workers is runtime.GOMAXPROCS(0) if it <= 0. The range is partitioned into
+// the balanced sub-ranges by the keys in the highest level that has enough nodes in the range,
+// and the elements in each sub-range are visited in ascending order.
+//
+// If f returns an error, the iteration stops and the first error is returned.
+// The list must not be changed during the iteration.
+func (sl *List) ParallelRange(start container.Key, boundary container.Key, workers int, f func(ele container.Element) error) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	// About 4 sub-ranges per worker, so the workers finished early can take more.
+	pivots := sl.pivots(start, boundary, workers*4)
+	return scan.Parallel(sl.Range, start, boundary, pivots, workers, f)
+}
+
+// Returns the keys in the range start < x < boundary of the highest level that has at least n of them.
+// The nodes of a level are spread evenly in expectation, so they split the range into balanced sub-ranges.
+func (sl *List) pivots(start container.Key, boundary container.Key, n int) []container.Key {
+	var pivots []container.Key
+	for i := sl.level; i >= 0; i-- {
+		// Searches the last node that less than or equal to the start in level i.
+		p := sl.head
+		for j := sl.level; j >= i; j-- {
+			for start != nil && p.next[j] != nil && p.next[j].key.Compare(start) != 1 {
+				p = p.next[j]
+			}
+		}
+
+		pivots = pivots[:0]
+		for p = p.next[i]; p != nil && (boundary == nil || p.key.Compare(boundary) == -1); p = p.next[i] {
+			pivots = append(pivots, p.key)
+		}
+		if len(pivots) >= n {
+			break
+		}
+	}
+	return pivots
+}
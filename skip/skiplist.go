@@ -6,6 +6,7 @@
 package skip
 
 import (
+	"iter"
 	"math/rand"
 	"time"
 
@@ -13,8 +14,10 @@ import (
 )
 
 var (
-	_ container.Container = (*List)(nil)
-	_ container.Element   = (*listNode)(nil)
+	_ container.Container      = (*List)(nil)
+	_ container.MultiContainer = (*List)(nil)
+	_ container.Element        = (*listNode)(nil)
+	_ container.MutableElement = (*listNode)(nil)
 )
 
 const (
@@ -38,12 +41,18 @@ func (n *listNode) Value() container.Value {
 	return n.value
 }
 
+// SetValue sets the value in place.
+func (n *listNode) SetValue(v container.Value) {
+	n.value = v
+}
+
 // List implements Skip List.
 type List struct {
 	head  *listNode
 	level int
 	lens  []int
 	r     *rand.Rand
+	multi bool
 }
 
 // New creates a Skip List.
@@ -56,6 +65,40 @@ func New() *List {
 	return sl
 }
 
+// NewMulti creates a Skip List that allows duplicate keys, the elements
+// with the same key are kept in insertion order.
+func NewMulti() *List {
+	sl := New()
+	sl.multi = true
+	return sl
+}
+
+// NewFromSorted creates a List with n elements returned by next in ascending order by key.
+// It appends each element at the tail in O(1) without comparison, so the keys must be strictly ascending.
+func NewFromSorted(n int, next func() (container.Key, container.Value)) *List {
+	sl := New()
+
+	// The last node in each level.
+	tails := make([]*listNode, maxLevel+1)
+	for i := range tails {
+		tails[i] = sl.head
+	}
+	for j := 0; j < n; j++ {
+		k, v := next()
+		level := sl.chooseLevel()
+		if level > sl.level {
+			sl.level = level
+		}
+		node := sl.createNode(k, v, level)
+		for i := 0; i <= level; i++ {
+			tails[i].next[i] = node
+			tails[i] = node
+			sl.lens[i]++
+		}
+	}
+	return sl
+}
+
 // Len returns the number of elements.
 func (sl *List) Len() int {
 	return sl.lens[0]
@@ -63,6 +106,8 @@ func (sl *List) Len() int {
 
 // Insert inserts a new element if the key doesn't exist, or returns the existing element for the key if present.
 // The bool result is true if an element was inserted, false if searched.
+//
+// If the list allows duplicate keys, it always inserts a new element behind the elements with the same key.
 func (sl *List) Insert(k container.Key, v container.Value) (container.Element, bool) {
 	level := sl.chooseLevel()
 	if level > sl.level {
@@ -75,7 +120,12 @@ func (sl *List) Insert(k container.Key, v container.Value) (container.Element, b
 		for p.next[i] != nil && p.next[i].key.Compare(k) == -1 {
 			p = p.next[i]
 		}
-		if p.next[i] != nil && p.next[i].key.Compare(k) == 0 {
+		if sl.multi {
+			// Inserts the duplicate key behind the exists.
+			for p.next[i] != nil && p.next[i].key.Compare(k) == 0 {
+				p = p.next[i]
+			}
+		} else if p.next[i] != nil && p.next[i].key.Compare(k) == 0 {
 			// The key already exists. Not allowed duplicates.
 			return p.next[i], false
 		}
@@ -94,6 +144,16 @@ func (sl *List) Insert(k container.Key, v container.Value) (container.Element, b
 // Delete removes and returns the element of a given key.
 // Returns nil if key not found.
 func (sl *List) Delete(k container.Key) container.Element {
+	if sl.multi {
+		// Deletes the first one of duplicate keys.
+		d := sl.searchNode(k)
+		if d == nil {
+			return nil
+		}
+		sl.deleteNode(d)
+		return d
+	}
+
 	var d *listNode
 	p := sl.head
 	for i := sl.level; i >= 0; i-- {
@@ -213,30 +273,213 @@ func (sl *List) Upsert(k container.Key, v container.Value) (container.Element, b
 	return node, false
 }
 
+// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place.
+// Returns the element of key and its old value, returns nil if the key not be found.
+func (sl *List) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	node := sl.searchNode(k)
+	if node == nil {
+		return nil, nil
+	}
+	old := node.value
+	node.value = v
+	return node, old
+}
+
+// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (sl *List) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	var ok bool
+	ele := sl.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		ok = !exists
+		return v, true
+	})
+	return ele, ok
+}
+
 // Search searches the element of a given key.
 // Returns nil if key not found.
 func (sl *List) Search(k container.Key) container.Element {
+	node := sl.searchNode(k)
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f. All of these are done with a single search.
+//
+// Returns the element of key after computed, nil if no element for the key.
+func (sl *List) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) container.Element {
+	var node *listNode
+
+	previous := make([]*listNode, maxLevel+1)
 	p := sl.head
 	for i := sl.level; i >= 0; i-- {
 		for p.next[i] != nil && p.next[i].key.Compare(k) == -1 {
 			p = p.next[i]
 		}
 		if p.next[i] != nil && p.next[i].key.Compare(k) == 0 {
-			return p.next[i]
+			node = p.next[i]
+		}
+		previous[i] = p
+	}
+
+	if node != nil {
+		v, keep := f(node, true)
+		if keep {
+			node.value = v
+			return node
+		}
+
+		// Unlinks the node from each level.
+		for i := sl.level; i >= 0; i-- {
+			if previous[i].next[i] == node {
+				previous[i].next[i] = node.next[i]
+				sl.lens[i]--
+			}
+			if sl.head.next[i] == nil && i != 0 {
+				sl.level--
+			}
+		}
+		// reset the unused field.
+		node.next = nil
+		return nil
+	}
+
+	v, keep := f(nil, false)
+	if !keep {
+		return nil
+	}
+
+	// The key not found, creates and inserts a new node.
+	level := sl.chooseLevel()
+	if level > sl.level {
+		for i := level; i > sl.level; i-- {
+			previous[i] = sl.head
+		}
+		sl.level = level
+	}
+
+	node = sl.createNode(k, v, level)
+	for i := 0; i <= level; i++ {
+		node.next[i] = previous[i].next[i]
+		previous[i].next[i] = node
+		sl.lens[i]++
+	}
+	return node
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+func (sl *List) GetOrInsert(k container.Key, f func() container.Value) (container.Element, bool) {
+	var ok bool
+	ele := sl.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		if exists {
+			return ele.Value(), true
+		}
+		ok = true
+		return f(), true
+	})
+	return ele, ok
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+//
+// The removed elements are unlinked level by level, without search for each of them.
+func (sl *List) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	if start != nil && boundary != nil && start.Compare(boundary) != -1 {
+		return 0
+	}
+
+	return sl.deleteRange(start, func(k container.Key) bool {
+		return boundary == nil || k.Compare(boundary) == -1
+	}, f)
+}
+
+// Clear removes all elements in the list.
+func (sl *List) Clear() {
+	for i := 0; i <= sl.level; i++ {
+		sl.head.next[i] = nil
+		sl.lens[i] = 0
+	}
+	sl.level = 0
+}
+
+// SearchAll searches all elements of a given key, and returns them in insertion order.
+// Returns nil if key not found.
+func (sl *List) SearchAll(k container.Key) []container.Element {
+	var result []container.Element
+	for node := sl.searchNode(k); node != nil && node.key.Compare(k) == 0; node = node.next[0] {
+		result = append(result, node)
+	}
+	return result
+}
+
+// DeleteOne removes and returns the first element of a given key that f returns true.
+// Returns nil if no element matched.
+func (sl *List) DeleteOne(k container.Key, f func(ele container.Element) bool) container.Element {
+	for node := sl.searchNode(k); node != nil && node.key.Compare(k) == 0; node = node.next[0] {
+		if f(node) {
+			sl.deleteNode(node)
+			return node
 		}
 	}
 	return nil
 }
 
+// DeleteAll removes all elements of a given key, and returns the number of removed elements.
+func (sl *List) DeleteAll(k container.Key) int {
+	return sl.deleteRange(k, func(key container.Key) bool {
+		return key.Compare(k) == 0
+	}, nil)
+}
+
+// Count returns the number of elements of a given key.
+func (sl *List) Count(k container.Key) int {
+	n := 0
+	for node := sl.searchNode(k); node != nil && node.key.Compare(k) == 0; node = node.next[0] {
+		n++
+	}
+	return n
+}
+
 // Iter return an Iterator, it's a wrap for skip.Iterator
 func (sl *List) Iter(start container.Key, boundary container.Key) container.Iterator {
 	return newIterator(sl, start, boundary)
 }
 
-// IterReverse return an Iterator, it's a wrap for tree.IterReverse.
+// IterReverse return an reversed Iterator.
+// It searches the predecessor of each key in O(log n), since the list is singly linked.
 func (sl *List) IterReverse(start container.Key, boundary container.Key) container.Iterator {
-	// TODO:
-	panic("skiplist: IterReverse method not implemented")
+	return newReverseIterator(sl, start, boundary)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (sl *List) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(sl)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (sl *List) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(sl)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (sl *List) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(sl, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (sl *List) Keys() iter.Seq[container.Key] {
+	return container.Keys(sl)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (sl *List) Values() iter.Seq[container.Value] {
+	return container.Values(sl)
 }
 
 // Range calls f sequentially each TreeNode present in the Tree.
@@ -268,29 +511,51 @@ func (sl *List) Range(start container.Key, boundary container.Key, f func(elem c
 
 // Reverse is similar to the Range method. But it iteration element in reverse.
 // If f returns false, range stops the iteration.
+//
+// It searches the predecessor of each key in O(log n), since the list is singly linked.
 func (sl *List) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
-	// TODO:
-	panic("skiplist: Reverse method not implemented")
+	iter := newReverseIterator(sl, start, boundary)
+	for iter.Valid() {
+		if !f(iter.Next()) {
+			return
+		}
+	}
 }
 
 // LastLT searches for the last node that less than the key.
 func (sl *List) LastLT(k container.Key) container.Element {
-	return sl.searchLastLT(k)
+	node := sl.searchLastLT(k)
+	if node == nil {
+		return nil
+	}
+	return node
 }
 
 // LastLE search for the last node that less than or equal to the key.
 func (sl *List) LastLE(k container.Key) container.Element {
-	return sl.searchLastLE(k)
+	node := sl.searchLastLE(k)
+	if node == nil {
+		return nil
+	}
+	return node
 }
 
 // FirstGT search for the first node that greater than to the key.
 func (sl *List) FirstGT(k container.Key) container.Element {
-	return sl.searchFirstGT(k)
+	node := sl.searchFirstGT(k)
+	if node == nil {
+		return nil
+	}
+	return node
 }
 
 // FirstGE search for the first node that greater than or equal to the key.
 func (sl *List) FirstGE(k container.Key) container.Element {
-	return sl.searchFirstGE(k)
+	node := sl.searchFirstGE(k)
+	if node == nil {
+		return nil
+	}
+	return node
 }
 
 // Creates a new node with the giving key and value.
@@ -310,6 +575,115 @@ func (sl *List) chooseLevel() int {
 	return level
 }
 
+// Unlinks the nodes from each level, which starts from the first node that greater than or equal to start,
+// and continues until in returns false. Returns the number of unlinked nodes.
+// f will be called with each node if it is not nil.
+func (sl *List) deleteRange(start container.Key, in func(k container.Key) bool, f func(ele container.Element)) int {
+	// Search the last node that less than start in each level.
+	previous := make([]*listNode, sl.level+1)
+	p := sl.head
+	for i := sl.level; i >= 0; i-- {
+		for start != nil && p.next[i] != nil && p.next[i].key.Compare(start) == -1 {
+			p = p.next[i]
+		}
+		previous[i] = p
+	}
+
+	first := previous[0].next[0]
+
+	// Unlinks the nodes in range from each level.
+	for i := sl.level; i >= 0; i-- {
+		q := previous[i].next[i]
+		for q != nil && in(q.key) {
+			q = q.next[i]
+			sl.lens[i]--
+		}
+		previous[i].next[i] = q
+
+		if sl.head.next[i] == nil && i != 0 {
+			sl.level--
+		}
+	}
+
+	n := 0
+	for d := first; d != previous[0].next[0]; n++ {
+		next := d.next[0]
+		// reset the unused field.
+		d.next = nil
+		if f != nil {
+			f(d)
+		}
+		d = next
+	}
+	return n
+}
+
+// Unlinks the node d from each level.
+func (sl *List) deleteNode(d *listNode) {
+	p := sl.head
+	for i := sl.level; i >= 0; i-- {
+		for p.next[i] != nil && p.next[i].key.Compare(d.key) == -1 {
+			p = p.next[i]
+		}
+		if i < len(d.next) {
+			// Skips the duplicate keys in front of d.
+			q := p
+			for q.next[i] != d {
+				q = q.next[i]
+			}
+			q.next[i] = d.next[i]
+			sl.lens[i]--
+		}
+
+		if sl.head.next[i] == nil && i != 0 {
+			sl.level--
+		}
+	}
+	// reset the unused field.
+	d.next = nil
+}
+
+// Search the node of a given key.
+// Returns the first one in insertion order if the list allows duplicate keys.
+func (sl *List) searchNode(k container.Key) *listNode {
+	p := sl.head
+	for i := sl.level; i >= 0; i-- {
+		for p.next[i] != nil && p.next[i].key.Compare(k) == -1 {
+			p = p.next[i]
+		}
+		if p.next[i] != nil && p.next[i].key.Compare(k) == 0 && (i == 0 || !sl.multi) {
+			return p.next[i]
+		}
+	}
+	return nil
+}
+
+// Search the last node that less than or equal to the key in each level.
+// Returns the head if not found.
+func (sl *List) searchLastLENode(k container.Key) *listNode {
+	p := sl.head
+	for i := sl.level; i >= 0; i-- {
+		for p.next[i] != nil && p.next[i].key.Compare(k) != 1 {
+			p = p.next[i]
+		}
+	}
+	return p
+}
+
+// Search the last node in the list.
+func (sl *List) searchLast() *listNode {
+	p := sl.head
+	for i := sl.level; i >= 0; i-- {
+		for p.next[i] != nil {
+			p = p.next[i]
+		}
+	}
+	if p == sl.head {
+		return nil
+	}
+	return p
+}
+
 // Search the last node that less than the key.
 func (sl *List) searchLastLT(k container.Key) *listNode {
 	p := sl.head
@@ -327,6 +701,13 @@ func (sl *List) searchLastLT(k container.Key) *listNode {
 
 // Search the last node that less than or equal to the key.
 func (sl *List) searchLastLE(k container.Key) *listNode {
+	if sl.multi {
+		if p := sl.searchLastLENode(k); p != sl.head {
+			return p
+		}
+		return nil
+	}
+
 	p := sl.head
 	for i := sl.level; i >= 0; i-- {
 		for p.next[i] != nil && p.next[i].key.Compare(k) == -1 {
@@ -345,6 +726,10 @@ func (sl *List) searchLastLE(k container.Key) *listNode {
 
 // Search the first node that greater than to the key.
 func (sl *List) searchFirstGT(k container.Key) *listNode {
+	if sl.multi {
+		return sl.searchLastLENode(k).next[0]
+	}
+
 	p := sl.head
 	for i := sl.level; i >= 0; i-- {
 		for p.next[i] != nil && p.next[i].key.Compare(k) == -1 {
@@ -373,7 +758,7 @@ func (sl *List) searchFirstGE(k container.Key) *listNode {
 		}
 
 		if p.next[i] != nil {
-			if p.next[i].key.Compare(k) == 0 || i == 0 {
+			if (p.next[i].key.Compare(k) == 0 && !sl.multi) || i == 0 {
 				return p.next[i]
 			}
 		}
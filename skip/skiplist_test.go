@@ -142,3 +142,109 @@ func TestList(t *testing.T) {
 		}
 	}
 }
+
+func TestList_DeleteRange(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for x := 0; x < 64; x++ {
+		sl := New()
+		length := r.Intn(512) + 1
+		for i := 0; i < length; i++ {
+			sl.Insert(container.Int(i), i)
+		}
+
+		start := r.Intn(length)
+		boundary := start + r.Intn(length-start+1)
+
+		n := sl.DeleteRange(container.Int(start), container.Int(boundary), nil)
+		require.Equal(t, n, boundary-start)
+		require.Equal(t, sl.Len(), length-n)
+		checkCorrect(t, sl)
+
+		for i := 0; i < length; i++ {
+			if i >= start && i < boundary {
+				require.Nil(t, sl.Search(container.Int(i)))
+			} else {
+				require.NotNil(t, sl.Search(container.Int(i)))
+			}
+		}
+
+		// Delete all the remaining elements.
+		require.Equal(t, sl.DeleteRange(nil, nil, nil), length-n)
+		require.Equal(t, sl.Len(), 0)
+		require.Equal(t, sl.level, 0)
+		for i := 0; i <= maxLevel; i++ {
+			require.Nil(t, sl.head.next[i])
+			require.Equal(t, sl.lens[i], 0)
+		}
+	}
+}
+
+func TestList_Multi(t *testing.T) {
+	sl := NewMulti()
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	check := func() {
+		for i := 0; i <= sl.level; i++ {
+			p := sl.head.next[i]
+			n := 0
+			for p != nil && p.next[i] != nil {
+				require.NotEqual(t, p.key.Compare(p.next[i].key), 1)
+				p = p.next[i]
+				n++
+			}
+			if p != nil {
+				n++
+			}
+			require.Equal(t, sl.lens[i], n)
+		}
+	}
+
+	length := 1024
+	for i := 0; i < length; i++ {
+		_, ok := sl.Insert(container.Int(r.Intn(32)), i)
+		require.True(t, ok)
+		check()
+	}
+
+	for sl.Len() > 0 {
+		k := container.Int(r.Intn(32))
+		switch r.Intn(3) {
+		case 0:
+			sl.Delete(k)
+		case 1:
+			sl.DeleteOne(k, func(ele container.Element) bool {
+				return ele.Value().(int)%2 == 0
+			})
+		case 2:
+			sl.DeleteAll(k)
+		}
+		check()
+	}
+	require.Equal(t, sl.level, 0)
+}
+
+func TestNewFromSorted(t *testing.T) {
+	for n := 0; n < 300; n++ {
+		i := 0
+		sl := NewFromSorted(n, func() (container.Key, container.Value) {
+			i++
+			return container.Int(i), i
+		})
+		require.Equal(t, sl.Len(), n)
+		checkCorrect(t, sl)
+		for l := 0; l <= sl.level; l++ {
+			c := 0
+			for p := sl.head.next[l]; p != nil; p = p.next[l] {
+				c++
+			}
+			require.Equal(t, sl.lens[l], c)
+		}
+
+		// The list is usable after built.
+		_, ok := sl.Insert(container.Int(n+1), n+1)
+		require.True(t, ok)
+		require.NotNil(t, sl.Delete(container.Int(n/2+1)))
+		checkCorrect(t, sl)
+	}
+}
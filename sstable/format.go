@@ -0,0 +1,205 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package sstable implements the sorted string table, an immutable file of elements in ascending order by key.
+//
+// The file is a sequence of blocks followed by a fixed size footer:
+//
+//	[data block 0] ... [data block n-1] [meta block] [index block] [footer]
+//
+// A data block holds the consecutive elements, each key or value is encoded as a type id, a length and
+// the data produced by the codec.Registry. The meta block holds the type names referenced by type ids and
+// the number of elements. The index block holds the location, first key and last key of each data block,
+// it is sparse and loaded into memory when the file opened.
+//
+// Each block is followed by a trailer of the compression type and the CRC-32 checksum, and the footer holds
+// the locations of meta block and index block.
+package sstable
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/yu31/structs-go/codec"
+)
+
+var (
+	// ErrCorrupted is returned when the file is malformed or the checksum mismatched.
+	ErrCorrupted = errors.New("sstable: corrupted file")
+	// ErrUnsorted is returned when adding a key that not greater than the previous key.
+	ErrUnsorted = errors.New("sstable: keys must be added in strictly ascending order")
+	// ErrClosed is returned when adding to a closed Writer.
+	ErrClosed = errors.New("sstable: writer is closed")
+)
+
+// Compression is the compression algorithm of blocks.
+type Compression byte
+
+const (
+	// NoCompression stores the blocks as is.
+	NoCompression Compression = iota
+	// FlateCompression compresses the blocks with DEFLATE.
+	// A block is stored as is if the compressed data is not smaller.
+	FlateCompression
+)
+
+const defaultBlockSize = 4096
+
+// Options is the options of Writer and Reader.
+type Options struct {
+	// BlockSize is the approximate size of data blocks before compression, default 4 KiB.
+	BlockSize int
+	// Compression is the compression algorithm of blocks, only used by Writer.
+	Compression Compression
+	// Registry is used to encode and decode the keys and values, default codec.Default.
+	// The Reader must use a Registry that registered all types used by the Writer.
+	Registry *codec.Registry
+}
+
+// Returns a copy of opts with defaults filled.
+func copyOptions(opts *Options) *Options {
+	o := &Options{}
+	if opts != nil {
+		*o = *opts
+	}
+	if o.BlockSize <= 0 {
+		o.BlockSize = defaultBlockSize
+	}
+	if o.Registry == nil {
+		o.Registry = codec.Default
+	}
+	return o
+}
+
+const (
+	// The size of block trailer: 1 byte compression type and 4 bytes checksum.
+	trailerSize = 5
+	// The size of footer: the handles of meta block and index block, and the magic.
+	footerSize = 40
+	magic      = 0x7373746162676f31 // "sstabgo1"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// blockHandle is the location of a block, the size excludes the trailer.
+type blockHandle struct {
+	offset uint64
+	size   uint64
+}
+
+// Appends the trailer of block data.
+func appendTrailer(buf []byte, data []byte, c Compression) []byte {
+	crc := crc32.Update(crc32.Checksum(data, crcTable), crcTable, []byte{byte(c)})
+	var b [trailerSize]byte
+	b[0] = byte(c)
+	binary.LittleEndian.PutUint32(b[1:], crc)
+	return append(buf, b[:]...)
+}
+
+// Compresses the block data, returns the raw data if not smaller.
+func compress(raw []byte, c Compression) ([]byte, Compression) {
+	if c != FlateCompression {
+		return raw, NoCompression
+	}
+	var buf bytes.Buffer
+	fw, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	fw.Write(raw)
+	fw.Close()
+	if buf.Len() >= len(raw) {
+		return raw, NoCompression
+	}
+	return buf.Bytes(), FlateCompression
+}
+
+// Verifies the trailer and decompresses the block data, the input is the data followed by trailer.
+func decodeBlock(b []byte) ([]byte, error) {
+	if len(b) < trailerSize {
+		return nil, ErrCorrupted
+	}
+	data, trailer := b[:len(b)-trailerSize], b[len(b)-trailerSize:]
+	c := Compression(trailer[0])
+	crc := crc32.Update(crc32.Checksum(data, crcTable), crcTable, trailer[:1])
+	if crc != binary.LittleEndian.Uint32(trailer[1:]) {
+		return nil, ErrCorrupted
+	}
+	switch c {
+	case NoCompression:
+		return data, nil
+	case FlateCompression:
+		raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return nil, ErrCorrupted
+		}
+		return raw, nil
+	}
+	return nil, ErrCorrupted
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	return append(buf, b[:n]...)
+}
+
+// decoder reads the values from a decoded block.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+// Reads an uvarint, the err is set if failed.
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = ErrCorrupted
+		return 0
+	}
+	d.b = d.b[n:]
+	return x
+}
+
+// Reads a length prefixed data, the err is set if failed.
+func (d *decoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.b)) {
+		d.err = ErrCorrupted
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+// Reads a value encoded by the Writer.
+func (d *decoder) value(registry *codec.Registry, types []string) interface{} {
+	tag := d.uvarint()
+	if d.err != nil || tag == 0 {
+		return nil
+	}
+	if tag > uint64(len(types)) {
+		d.err = ErrCorrupted
+		return nil
+	}
+	data := d.bytes()
+	if d.err != nil {
+		return nil
+	}
+	v, err := registry.Decode(types[tag-1], data)
+	if err != nil {
+		d.err = err
+		return nil
+	}
+	return v
+}
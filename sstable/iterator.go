@@ -0,0 +1,152 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package sstable
+
+import (
+	"sort"
+
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	_ container.Iterator = (*Iterator)(nil)
+	_ container.Iterator = (*ReverseIterator)(nil)
+)
+
+// Iterator implements container.Iterator, it reads the data blocks one by one.
+type Iterator struct {
+	rd       *Reader
+	boundary container.Key
+	block    int
+	elements []*element
+	pos      int
+	node     *element
+}
+
+func newIterator(rd *Reader, start container.Key, boundary container.Key) *Iterator {
+	it := &Iterator{
+		rd:       rd,
+		boundary: boundary,
+		block:    rd.seekBlock(start),
+	}
+	if it.block < len(rd.index) {
+		it.elements = rd.loadBlock(it.block)
+		if start != nil {
+			it.pos = sort.Search(len(it.elements), func(i int) bool {
+				return it.elements[i].key.Compare(start) != -1
+			})
+		}
+	}
+	it.advance()
+	return it
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (it *Iterator) Valid() bool {
+	return it.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (it *Iterator) Next() container.Element {
+	if it.node == nil {
+		return nil
+	}
+	n := it.node
+	it.advance()
+	return n
+}
+
+// Moves the node to the element at pos, loads the next block if needed.
+func (it *Iterator) advance() {
+	it.node = nil
+	for it.pos == len(it.elements) {
+		// Stop if failed to load block.
+		if it.elements == nil || it.block+1 >= len(it.rd.index) {
+			return
+		}
+		if it.boundary != nil && it.rd.index[it.block+1].first.Compare(it.boundary) != -1 {
+			return
+		}
+		it.block++
+		it.elements = it.rd.loadBlock(it.block)
+		it.pos = 0
+	}
+	n := it.elements[it.pos]
+	if it.boundary != nil && n.key.Compare(it.boundary) != -1 {
+		return
+	}
+	it.pos++
+	it.node = n
+}
+
+// ReverseIterator implements container.Iterator, it reads the data blocks one by one in reverse.
+type ReverseIterator struct {
+	rd       *Reader
+	start    container.Key
+	block    int
+	elements []*element
+	pos      int // The index of next element plus 1.
+	node     *element
+}
+
+func newReverseIterator(rd *Reader, start container.Key, boundary container.Key) *ReverseIterator {
+	it := &ReverseIterator{
+		rd:    rd,
+		start: start,
+		block: rd.seekBlockReverse(boundary),
+	}
+	if it.block >= 0 {
+		it.elements = rd.loadBlock(it.block)
+		it.pos = len(it.elements)
+		if boundary != nil {
+			it.pos = sort.Search(len(it.elements), func(i int) bool {
+				return it.elements[i].key.Compare(boundary) != -1
+			})
+		}
+	}
+	it.advance()
+	return it
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (it *ReverseIterator) Valid() bool {
+	return it.node != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (it *ReverseIterator) Next() container.Element {
+	if it.node == nil {
+		return nil
+	}
+	n := it.node
+	it.advance()
+	return n
+}
+
+// Moves the node to the element at pos-1, loads the previous block if needed.
+func (it *ReverseIterator) advance() {
+	it.node = nil
+	for it.pos == 0 {
+		// Stop if failed to load block.
+		if it.elements == nil || it.block <= 0 {
+			return
+		}
+		if it.start != nil && it.rd.index[it.block-1].last.Compare(it.start) == -1 {
+			return
+		}
+		it.block--
+		it.elements = it.rd.loadBlock(it.block)
+		it.pos = len(it.elements)
+	}
+	n := it.elements[it.pos-1]
+	if it.start != nil && n.key.Compare(it.start) == -1 {
+		return
+	}
+	it.pos--
+	it.node = n
+}
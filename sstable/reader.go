@@ -0,0 +1,346 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package sstable
+
+import (
+	"encoding/binary"
+	"io"
+	"iter"
+	"sort"
+	"sync"
+
+	"github.com/yu31/structs-go/container"
+)
+
+var (
+	_ container.Retriever = (*Reader)(nil)
+	_ container.Searcher  = (*Reader)(nil)
+	_ container.Element   = (*element)(nil)
+)
+
+// element is an element decoded from the file.
+type element struct {
+	key   container.Key
+	value container.Value
+}
+
+// Key returns the key.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value.
+func (e *element) Value() container.Value {
+	return e.value
+}
+
+// Reader reads the elements from a sstable file.
+// Only the index is kept in memory, the data blocks are read from the file on demand.
+//
+// The methods of Retriever and Searcher can not return errors, so an I/O error or a corrupted
+// data block is treated as no more elements, and it's recorded to be returned by the Err method.
+//
+// A Reader is safe for concurrent use by multiple goroutines.
+type Reader struct {
+	r     io.ReaderAt
+	opts  *Options
+	types []string
+	len   int
+	index []indexEntry
+
+	mu  sync.Mutex
+	err error
+}
+
+// Open opens a sstable file of the given size, the default options is used if opts is nil.
+// The meta block and index block are read and verified.
+func Open(r io.ReaderAt, size int64, opts *Options) (*Reader, error) {
+	rd := &Reader{
+		r:    r,
+		opts: copyOptions(opts),
+	}
+
+	if size < footerSize {
+		return nil, ErrCorrupted
+	}
+	var footer [footerSize]byte
+	if _, err := r.ReadAt(footer[:], size-footerSize); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint64(footer[32:]) != magic {
+		return nil, ErrCorrupted
+	}
+	metaHandle := blockHandle{
+		offset: binary.LittleEndian.Uint64(footer[0:]),
+		size:   binary.LittleEndian.Uint64(footer[8:]),
+	}
+	indexHandle := blockHandle{
+		offset: binary.LittleEndian.Uint64(footer[16:]),
+		size:   binary.LittleEndian.Uint64(footer[24:]),
+	}
+	for _, h := range []blockHandle{metaHandle, indexHandle} {
+		if h.offset > uint64(size) || h.size+trailerSize > uint64(size)-h.offset {
+			return nil, ErrCorrupted
+		}
+	}
+
+	meta, err := rd.readBlock(metaHandle)
+	if err != nil {
+		return nil, err
+	}
+	d := &decoder{b: meta}
+	n := d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		rd.types = append(rd.types, string(d.bytes()))
+	}
+	rd.len = int(d.uvarint())
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	index, err := rd.readBlock(indexHandle)
+	if err != nil {
+		return nil, err
+	}
+	d = &decoder{b: index}
+	n = d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		var e indexEntry
+		e.handle.offset = d.uvarint()
+		e.handle.size = d.uvarint()
+		first, _ := d.value(rd.opts.Registry, rd.types).(container.Key)
+		last, _ := d.value(rd.opts.Registry, rd.types).(container.Key)
+		if d.err == nil && (first == nil || last == nil) {
+			d.err = ErrCorrupted
+		}
+		e.first, e.last = first, last
+		rd.index = append(rd.index, e)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return rd, nil
+}
+
+// Len returns the number of elements.
+func (rd *Reader) Len() int {
+	return rd.len
+}
+
+// Bounds returns the smallest and largest keys in the file, they are nil if the file is empty.
+func (rd *Reader) Bounds() (smallest container.Key, largest container.Key) {
+	if len(rd.index) == 0 {
+		return nil, nil
+	}
+	return rd.index[0].first, rd.index[len(rd.index)-1].last
+}
+
+// Err returns the first error encountered when reading data blocks.
+func (rd *Reader) Err() error {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	return rd.err
+}
+
+// Iter return an Iterator include element of range start <= x < boundary.
+// The elements will return from the beginning if start is nil,
+// And return until the end if the boundary is nil.
+func (rd *Reader) Iter(start container.Key, boundary container.Key) container.Iterator {
+	return newIterator(rd, start, boundary)
+}
+
+// IterReverse return a reversed Iterator include element of range start <= x < boundary.
+// The elements will return from the end if boundary is nil,
+// And return until the beginning if start is nil.
+func (rd *Reader) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	return newReverseIterator(rd, start, boundary)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (rd *Reader) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(rd)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (rd *Reader) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(rd)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (rd *Reader) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(rd, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (rd *Reader) Keys() iter.Seq[container.Key] {
+	return container.Keys(rd)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (rd *Reader) Values() iter.Seq[container.Value] {
+	return container.Values(rd)
+}
+
+// Range calls f sequentially each element present in the file.
+// If f returns false, range stops the iteration.
+func (rd *Reader) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	it := newIterator(rd, start, boundary)
+	for it.Valid() {
+		if !f(it.Next()) {
+			return
+		}
+	}
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+func (rd *Reader) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	it := newReverseIterator(rd, start, boundary)
+	for it.Valid() {
+		if !f(it.Next()) {
+			return
+		}
+	}
+}
+
+// LastLT searches for the last element that less than the key.
+func (rd *Reader) LastLT(k container.Key) container.Element {
+	i := sort.Search(len(rd.index), func(i int) bool {
+		return rd.index[i].first.Compare(k) != -1
+	}) - 1
+	if i < 0 {
+		return nil
+	}
+	elements := rd.loadBlock(i)
+	j := sort.Search(len(elements), func(j int) bool {
+		return elements[j].key.Compare(k) != -1
+	}) - 1
+	if j < 0 {
+		return nil
+	}
+	return elements[j]
+}
+
+// LastLE search for the last element that less than or equal to the key.
+func (rd *Reader) LastLE(k container.Key) container.Element {
+	i := sort.Search(len(rd.index), func(i int) bool {
+		return rd.index[i].first.Compare(k) == 1
+	}) - 1
+	if i < 0 {
+		return nil
+	}
+	elements := rd.loadBlock(i)
+	j := sort.Search(len(elements), func(j int) bool {
+		return elements[j].key.Compare(k) == 1
+	}) - 1
+	if j < 0 {
+		return nil
+	}
+	return elements[j]
+}
+
+// FirstGT search for the first element that greater than to the key.
+func (rd *Reader) FirstGT(k container.Key) container.Element {
+	i := sort.Search(len(rd.index), func(i int) bool {
+		return rd.index[i].last.Compare(k) == 1
+	})
+	if i == len(rd.index) {
+		return nil
+	}
+	elements := rd.loadBlock(i)
+	j := sort.Search(len(elements), func(j int) bool {
+		return elements[j].key.Compare(k) == 1
+	})
+	if j == len(elements) {
+		return nil
+	}
+	return elements[j]
+}
+
+// FirstGE search for the first element that greater than or equal to the key.
+func (rd *Reader) FirstGE(k container.Key) container.Element {
+	i := sort.Search(len(rd.index), func(i int) bool {
+		return rd.index[i].last.Compare(k) != -1
+	})
+	if i == len(rd.index) {
+		return nil
+	}
+	elements := rd.loadBlock(i)
+	j := sort.Search(len(elements), func(j int) bool {
+		return elements[j].key.Compare(k) != -1
+	})
+	if j == len(elements) {
+		return nil
+	}
+	return elements[j]
+}
+
+// Returns the index of first block that may contain the key >= start.
+func (rd *Reader) seekBlock(start container.Key) int {
+	if start == nil {
+		return 0
+	}
+	return sort.Search(len(rd.index), func(i int) bool {
+		return rd.index[i].last.Compare(start) != -1
+	})
+}
+
+// Returns the index of last block that may contain the key < boundary.
+func (rd *Reader) seekBlockReverse(boundary container.Key) int {
+	if boundary == nil {
+		return len(rd.index) - 1
+	}
+	return sort.Search(len(rd.index), func(i int) bool {
+		return rd.index[i].first.Compare(boundary) != -1
+	}) - 1
+}
+
+// Reads and decodes the elements of data block i.
+// Returns nil and records the error if failed.
+func (rd *Reader) loadBlock(i int) []*element {
+	elements, err := rd.decodeElements(rd.index[i].handle)
+	if err != nil {
+		rd.mu.Lock()
+		if rd.err == nil {
+			rd.err = err
+		}
+		rd.mu.Unlock()
+		return nil
+	}
+	return elements
+}
+
+func (rd *Reader) decodeElements(h blockHandle) ([]*element, error) {
+	b, err := rd.readBlock(h)
+	if err != nil {
+		return nil, err
+	}
+	var elements []*element
+	d := &decoder{b: b}
+	for len(d.b) > 0 && d.err == nil {
+		k, _ := d.value(rd.opts.Registry, rd.types).(container.Key)
+		v := d.value(rd.opts.Registry, rd.types)
+		if d.err == nil && k == nil {
+			d.err = ErrCorrupted
+		}
+		elements = append(elements, &element{key: k, value: v})
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return elements, nil
+}
+
+// Reads the block with trailer, and returns the verified and decompressed data.
+func (rd *Reader) readBlock(h blockHandle) ([]byte, error) {
+	b := make([]byte, h.size+trailerSize)
+	if _, err := rd.r.ReadAt(b, int64(h.offset)); err != nil {
+		if err == io.EOF {
+			return nil, ErrCorrupted
+		}
+		return nil, err
+	}
+	return decodeBlock(b)
+}
@@ -0,0 +1,196 @@
+package sstable
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+func collect(r container.Retriever, start container.Key, boundary container.Key, reverse bool) []container.Element {
+	var result []container.Element
+	f := func(ele container.Element) bool {
+		result = append(result, &element{key: ele.Key(), value: ele.Value()})
+		return true
+	}
+	if reverse {
+		r.Reverse(start, boundary, f)
+	} else {
+		r.Range(start, boundary, f)
+	}
+	return result
+}
+
+func collectIter(t *testing.T, it container.Iterator) []container.Element {
+	var result []container.Element
+	for it.Valid() {
+		ele := it.Next()
+		result = append(result, &element{key: ele.Key(), value: ele.Value()})
+	}
+	require.Nil(t, it.Next())
+	return result
+}
+
+func snapshot(ele container.Element) container.Element {
+	if ele == nil {
+		return nil
+	}
+	return &element{key: ele.Key(), value: ele.Value()}
+}
+
+func build(t *testing.T, src container.Retriever, opts *Options) *Reader {
+	var buf bytes.Buffer
+	require.Nil(t, Write(&buf, src, opts))
+	data := buf.Bytes()
+	rd, err := Open(bytes.NewReader(data), int64(len(data)), opts)
+	require.Nil(t, err)
+	return rd
+}
+
+func TestReader(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for _, n := range []int{0, 1, 10, 1000} {
+		for _, opts := range []*Options{
+			nil,
+			{BlockSize: 64},
+			{BlockSize: 64, Compression: FlateCompression},
+			{BlockSize: 1, Compression: FlateCompression},
+		} {
+			src := skip.New()
+			for src.Len() < n {
+				k := container.Int(r.Intn(n*4) * 2)
+				if r.Intn(8) == 0 {
+					src.Insert(k, nil)
+				} else {
+					src.Insert(k, "value-value-value")
+				}
+			}
+
+			rd := build(t, src, opts)
+			require.Equal(t, rd.Len(), n)
+			smallest, largest := rd.Bounds()
+			if n == 0 {
+				require.Nil(t, smallest)
+				require.Nil(t, largest)
+			} else {
+				require.Equal(t, smallest, src.FirstGE(container.Int(-1)).Key())
+				require.Equal(t, largest, src.LastLT(container.Int(n*8)).Key())
+			}
+
+			require.Equal(t, collect(rd, nil, nil, false), collect(src, nil, nil, false))
+			require.Equal(t, collectIter(t, rd.Iter(nil, nil)), collect(src, nil, nil, false))
+			reversed := collect(rd, nil, nil, true)
+			require.Equal(t, len(reversed), n)
+			for i := range reversed {
+				require.Equal(t, reversed[i], collect(src, nil, nil, false)[n-1-i])
+			}
+			require.Equal(t, collectIter(t, rd.IterReverse(nil, nil)), reversed)
+
+			for i := 0; i < 100; i++ {
+				k := container.Int(r.Intn(n*8+2) - 1)
+				require.Equal(t, snapshot(rd.LastLT(k)), snapshot(src.LastLT(k)))
+				require.Equal(t, snapshot(rd.LastLE(k)), snapshot(src.LastLE(k)))
+				require.Equal(t, snapshot(rd.FirstGT(k)), snapshot(src.FirstGT(k)))
+				require.Equal(t, snapshot(rd.FirstGE(k)), snapshot(src.FirstGE(k)))
+
+				var start, boundary container.Key
+				if r.Intn(4) != 0 {
+					start = k
+				}
+				if r.Intn(4) != 0 {
+					boundary = container.Int(int(k) + r.Intn(n+1))
+				}
+				expected := collect(src, start, boundary, false)
+				require.Equal(t, collect(rd, start, boundary, false), expected)
+				require.Equal(t, collectIter(t, rd.Iter(start, boundary)), expected)
+				for l, r := 0, len(expected)-1; l < r; l, r = l+1, r-1 {
+					expected[l], expected[r] = expected[r], expected[l]
+				}
+				require.Equal(t, collect(rd, start, boundary, true), expected)
+				require.Equal(t, collectIter(t, rd.IterReverse(start, boundary)), expected)
+			}
+			require.Nil(t, rd.Err())
+		}
+	}
+}
+
+func TestReader_Compression(t *testing.T) {
+	src := rb.New()
+	for i := 0; i < 1000; i++ {
+		src.Insert(container.Int(i), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	}
+
+	var plain, compressed bytes.Buffer
+	require.Nil(t, Write(&plain, src, nil))
+	require.Nil(t, Write(&compressed, src, &Options{Compression: FlateCompression}))
+	require.Less(t, compressed.Len(), plain.Len()/4)
+
+	data := compressed.Bytes()
+	rd, err := Open(bytes.NewReader(data), int64(len(data)), nil)
+	require.Nil(t, err)
+	require.Equal(t, rd.FirstGE(container.Int(500)).Key(), container.Int(500))
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, nil)
+	require.Nil(t, w.Add(container.Int(1), 1))
+	require.Equal(t, w.Add(container.Int(1), 1), ErrUnsorted)
+	require.Equal(t, w.Add(container.Int(0), 1), ErrUnsorted)
+
+	// Unregistered type is rejected and the writer is still usable.
+	type unknown struct{}
+	require.NotNil(t, w.Add(container.Int(2), unknown{}))
+	require.Nil(t, w.Add(container.Int(2), 2))
+	require.Greater(t, w.Size(), int64(0))
+	require.Nil(t, w.Close())
+	require.Equal(t, w.Size(), int64(buf.Len()))
+	require.Equal(t, w.Close(), ErrClosed)
+	require.Equal(t, w.Add(container.Int(3), 3), ErrClosed)
+
+	data := buf.Bytes()
+	rd, err := Open(bytes.NewReader(data), int64(len(data)), nil)
+	require.Nil(t, err)
+	require.Equal(t, collect(rd, nil, nil, false), []container.Element{
+		&element{key: container.Int(1), value: 1},
+		&element{key: container.Int(2), value: 2},
+	})
+}
+
+func TestReader_Corrupted(t *testing.T) {
+	src := rb.New()
+	for i := 0; i < 100; i++ {
+		src.Insert(container.Int(i), i)
+	}
+	var buf bytes.Buffer
+	require.Nil(t, Write(&buf, src, &Options{BlockSize: 64}))
+	data := buf.Bytes()
+
+	// Bad footer or index.
+	for _, i := range []int{len(data) - 1, len(data) - footerSize, len(data) - footerSize - 1} {
+		bad := append([]byte(nil), data...)
+		bad[i] ^= 0xff
+		_, err := Open(bytes.NewReader(bad), int64(len(bad)), nil)
+		require.NotNil(t, err)
+	}
+	_, err := Open(bytes.NewReader(data[:10]), 10, nil)
+	require.Equal(t, err, ErrCorrupted)
+
+	// Bad data block is found on read.
+	bad := append([]byte(nil), data...)
+	bad[0] ^= 0xff
+	rd, err := Open(bytes.NewReader(bad), int64(len(bad)), nil)
+	require.Nil(t, err)
+	require.Nil(t, rd.Err())
+	require.Nil(t, rd.FirstGE(container.Int(0)))
+	require.Equal(t, rd.Err(), ErrCorrupted)
+	require.Equal(t, len(collect(rd, nil, nil, false)), 0)
+	require.NotEqual(t, len(collect(rd, container.Int(50), nil, false)), 0)
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package sstable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/yu31/structs-go/container"
+)
+
+// The index entry of a data block.
+type indexEntry struct {
+	handle blockHandle
+	first  container.Key
+	last   container.Key
+}
+
+// Writer writes the elements in ascending order by key to a sstable file.
+// The Close method must be called to finish the file.
+type Writer struct {
+	w      io.Writer
+	opts   *Options
+	offset uint64
+	err    error // The first write error, the file is broken after that.
+	closed bool
+
+	types map[string]uint64 // The ids of type names, start from 1.
+	names []string
+
+	block []byte // The pending data block.
+	first container.Key
+	last  container.Key
+	count int
+	index []indexEntry
+}
+
+// NewWriter creates a Writer that writes to w, the default options is used if opts is nil.
+func NewWriter(w io.Writer, opts *Options) *Writer {
+	return &Writer{
+		w:     w,
+		opts:  copyOptions(opts),
+		types: make(map[string]uint64),
+	}
+}
+
+// Write writes all elements of r to w as a sstable file.
+func Write(w io.Writer, r container.Retriever, opts *Options) error {
+	sw := NewWriter(w, opts)
+	var err error
+	r.Range(nil, nil, func(ele container.Element) bool {
+		err = sw.Add(ele.Key(), ele.Value())
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+// Add appends an element, the key must be greater than the previous key.
+func (w *Writer) Add(k container.Key, v container.Value) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if w.last != nil && w.last.Compare(k) != -1 {
+		return ErrUnsorted
+	}
+
+	// The pending block is truncated back if failed.
+	n := len(w.block)
+	var err error
+	if w.block, err = w.appendValue(w.block, k); err == nil {
+		w.block, err = w.appendValue(w.block, v)
+	}
+	if err != nil {
+		w.block = w.block[:n]
+		return err
+	}
+
+	if w.first == nil {
+		w.first = k
+	}
+	w.last = k
+	w.count++
+
+	if len(w.block) >= w.opts.BlockSize {
+		return w.flushBlock()
+	}
+	return nil
+}
+
+// Size returns the number of bytes written plus the size of pending block.
+func (w *Writer) Size() int64 {
+	return int64(w.offset) + int64(len(w.block))
+}
+
+// Close writes the pending block, meta block, index block and footer.
+// It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	w.closed = true
+	if err := w.flushBlock(); err != nil {
+		return err
+	}
+
+	// The index block is encoded first, since it references the type ids.
+	var index []byte
+	index = appendUvarint(index, uint64(len(w.index)))
+	for _, e := range w.index {
+		index = appendUvarint(index, e.handle.offset)
+		index = appendUvarint(index, e.handle.size)
+		// The keys have been encoded in data blocks, so no error.
+		index, _ = w.appendValue(index, e.first)
+		index, _ = w.appendValue(index, e.last)
+	}
+
+	var meta []byte
+	meta = appendUvarint(meta, uint64(len(w.names)))
+	for _, name := range w.names {
+		meta = appendUvarint(meta, uint64(len(name)))
+		meta = append(meta, name...)
+	}
+	meta = appendUvarint(meta, uint64(w.count))
+
+	metaHandle, err := w.writeBlock(meta)
+	if err != nil {
+		return err
+	}
+	indexHandle, err := w.writeBlock(index)
+	if err != nil {
+		return err
+	}
+
+	var footer [footerSize]byte
+	binary.LittleEndian.PutUint64(footer[0:], metaHandle.offset)
+	binary.LittleEndian.PutUint64(footer[8:], metaHandle.size)
+	binary.LittleEndian.PutUint64(footer[16:], indexHandle.offset)
+	binary.LittleEndian.PutUint64(footer[24:], indexHandle.size)
+	binary.LittleEndian.PutUint64(footer[32:], magic)
+	return w.write(footer[:])
+}
+
+// Appends the type id, length and data of v.
+func (w *Writer) appendValue(buf []byte, v interface{}) ([]byte, error) {
+	if v == nil {
+		return appendUvarint(buf, 0), nil
+	}
+	name, ok := w.opts.Registry.TypeName(v)
+	if !ok {
+		return buf, fmt.Errorf("sstable: type %T not registered", v)
+	}
+	id, ok := w.types[name]
+	if !ok {
+		w.names = append(w.names, name)
+		id = uint64(len(w.names))
+		w.types[name] = id
+	}
+	data, err := w.opts.Registry.Encode(nil, v)
+	if err != nil {
+		return buf, err
+	}
+	buf = appendUvarint(buf, id)
+	buf = appendUvarint(buf, uint64(len(data)))
+	return append(buf, data...), nil
+}
+
+// Writes the pending data block and adds its index entry.
+func (w *Writer) flushBlock() error {
+	if w.first == nil {
+		return nil
+	}
+	h, err := w.writeBlock(w.block)
+	if err != nil {
+		return err
+	}
+	w.index = append(w.index, indexEntry{handle: h, first: w.first, last: w.last})
+	w.block = w.block[:0]
+	w.first = nil
+	return nil
+}
+
+// Writes a block with trailer, returns its handle.
+func (w *Writer) writeBlock(raw []byte) (blockHandle, error) {
+	data, c := compress(raw, w.opts.Compression)
+	h := blockHandle{offset: w.offset, size: uint64(len(data))}
+	buf := make([]byte, 0, len(data)+trailerSize)
+	buf = append(buf, data...)
+	buf = appendTrailer(buf, data, c)
+	return h, w.write(buf)
+}
+
+func (w *Writer) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += uint64(n)
+	if err != nil {
+		w.err = err
+	}
+	return err
+}
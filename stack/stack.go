@@ -5,6 +5,10 @@
 
 package stack
 
+import (
+	"iter"
+)
+
 const (
 	defaultCapacity = 64
 )
@@ -73,6 +77,29 @@ func (s *Stack) Peek() interface{} {
 	return item
 }
 
+// All returns an iterator over the elements in pop order (LIFO), the stack is not changed.
+// The index is 0 for the element at the end.
+func (s *Stack) All() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i := 0; i < s.len; i++ {
+			if !yield(i, s.items[s.len-1-i]) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the elements in pop order (LIFO), the stack is not changed.
+func (s *Stack) Values() iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		for _, item := range s.All() {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
 func (s *Stack) autoGrow() {
 	if s.len == s.cap {
 		newCap := s.cap
@@ -71,3 +71,27 @@ func TestStack_Auto_Cap(t *testing.T) {
 	require.Equal(t, st.Pop(), 2)
 	require.Equal(t, st.Pop(), 1)
 }
+
+func TestStack_All(t *testing.T) {
+	st := New(2)
+	for i := 0; i < 5; i++ {
+		st.Push(i)
+	}
+
+	var items []interface{}
+	for i, item := range st.All() {
+		require.Equal(t, i, len(items))
+		items = append(items, item)
+	}
+	require.Equal(t, items, []interface{}{4, 3, 2, 1, 0})
+	require.Equal(t, st.Len(), 5)
+
+	items = nil
+	for item := range st.Values() {
+		if item == 2 {
+			break
+		}
+		items = append(items, item)
+	}
+	require.Equal(t, items, []interface{}{4, 3})
+}
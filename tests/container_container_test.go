@@ -465,3 +465,312 @@ func TestContainer_Len(t *testing.T) {
 		})
 	}
 }
+
+func TestContainer_DeleteRange(t *testing.T) {
+	seeds := retrieverSeeds
+
+	process := func(t *testing.T, ctr container.Container, start container.Key, boundary container.Key) {
+		// Insert seeds in random order
+		for _, k := range shuffleSeeds(seeds) {
+			ctr.Insert(k, int64(k*2+1))
+		}
+
+		expected := searchRange(ctr, start, boundary)
+
+		var removed []container.Element
+		n := ctr.DeleteRange(start, boundary, func(ele container.Element) {
+			removed = append(removed, ele)
+		})
+		require.Equal(t, n, len(expected))
+		require.Equal(t, removed, expected)
+		require.Equal(t, ctr.Len(), len(seeds)-n)
+
+		for _, ele := range removed {
+			require.Nil(t, ctr.Search(ele.Key()))
+			if n, ok := ele.(container.TreeNode); ok {
+				require.Nil(t, n.Left())
+				require.Nil(t, n.Right())
+			}
+		}
+		require.Equal(t, len(searchRange(ctr, start, boundary)), 0)
+		require.Equal(t, len(searchRange(ctr, nil, nil)), len(seeds)-n)
+
+		// The remaining elements still in order.
+		var last container.Key
+		ctr.Range(nil, nil, func(ele container.Element) bool {
+			if last != nil {
+				require.Equal(t, last.Compare(ele.Key()), -1)
+			}
+			require.Equal(t, ele.Value(), int64(ele.Key().(container.Int64)*2+1))
+			last = ele.Key()
+			return true
+		})
+
+		// Delete again with nothing.
+		require.Equal(t, ctr.DeleteRange(start, boundary, nil), 0)
+	}
+
+	cases := [][2]container.Key{
+		{nil, nil},
+		{container.Int64(22), nil},
+		{nil, container.Int64(150)},
+		{container.Int64(21), container.Int64(151)},
+		{container.Int64(62), container.Int64(98)},
+		{container.Int64(64), container.Int64(97)},
+		{container.Int64(90), container.Int64(91)},
+		{container.Int64(91), container.Int64(92)},
+		{container.Int64(97), container.Int64(64)},
+		{container.Int64(151), nil},
+		{nil, container.Int64(22)},
+	}
+
+	for name, f := range containers {
+		for i, c := range cases {
+			t.Run(name+"_case"+strconv.Itoa(i), func(t *testing.T) {
+				process(t, f(), c[0], c[1])
+			})
+		}
+	}
+}
+
+func TestContainer_Clear(t *testing.T) {
+	process := func(ctr container.Container) {
+		ctr.Clear()
+		require.Equal(t, ctr.Len(), 0)
+
+		for k, v := range seeds {
+			ctr.Insert(k, v)
+		}
+		require.Equal(t, ctr.Len(), len(seeds))
+
+		ctr.Clear()
+		require.Equal(t, ctr.Len(), 0)
+		require.Equal(t, len(searchRange(ctr, nil, nil)), 0)
+		require.False(t, ctr.Iter(nil, nil).Valid())
+		for k := range seeds {
+			require.Nil(t, ctr.Search(k))
+		}
+
+		// The container is still available after clear.
+		for k, v := range seeds {
+			_, ok := ctr.Insert(k, v)
+			require.True(t, ok)
+		}
+		require.Equal(t, ctr.Len(), len(seeds))
+	}
+
+	// Base test for all container implementation.
+	for name, f := range containers {
+		t.Run(name+"_base", func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestContainer_Compute(t *testing.T) {
+	process := func(ctr container.Container) {
+		// The key not exists and f returns keep false, nothing happens.
+		for k := range seeds {
+			ele := ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+				require.Nil(t, ele)
+				require.False(t, exists)
+				return nil, false
+			})
+			require.Nil(t, ele)
+			require.Nil(t, ctr.Search(k))
+		}
+		require.Equal(t, ctr.Len(), 0)
+
+		// The key not exists and f returns keep true, same as the Insert.
+		for k, v := range seeds {
+			ele := ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+				require.Nil(t, ele)
+				require.False(t, exists)
+				return v, true
+			})
+			require.NotNil(t, ele)
+			require.Equal(t, ele.Key(), k)
+			require.Equal(t, ele.Value(), v)
+		}
+		require.Equal(t, ctr.Len(), len(seeds))
+
+		// The key exists and f returns keep true, the value updated in place.
+		for k, v := range seeds {
+			old := ctr.Search(k)
+			ele := ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+				require.True(t, exists)
+				require.Equal(t, ele.Key(), k)
+				require.Equal(t, ele.Value(), v)
+				return ele.Value().(string) + v, true
+			})
+			require.NotNil(t, ele)
+			require.True(t, ele == old)
+			require.Equal(t, ele.Key(), k)
+			require.Equal(t, ele.Value(), v+v)
+			require.Equal(t, ctr.Search(k).Value(), v+v)
+		}
+		require.Equal(t, ctr.Len(), len(seeds))
+
+		// The key exists and f returns keep false, same as the Delete.
+		i := len(seeds)
+		for k, v := range seeds {
+			ele := ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+				require.True(t, exists)
+				require.Equal(t, ele.Value(), v+v)
+				return nil, false
+			})
+			require.Nil(t, ele)
+			require.Nil(t, ctr.Search(k))
+			i--
+			require.Equal(t, ctr.Len(), i)
+		}
+		require.Equal(t, ctr.Len(), 0)
+		require.Equal(t, len(searchRange(ctr, nil, nil)), 0)
+	}
+
+	// Base test for all container implementation.
+	for name, f := range containers {
+		t.Run(name+"_base", func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestContainer_GetOrInsert(t *testing.T) {
+	process := func(ctr container.Container) {
+		for k, v := range seeds {
+			ele, ok := ctr.GetOrInsert(k, func() container.Value {
+				return v
+			})
+			require.True(t, ok)
+			require.NotNil(t, ele)
+			require.Equal(t, ele.Key(), k)
+			require.Equal(t, ele.Value(), v)
+		}
+		require.Equal(t, ctr.Len(), len(seeds))
+
+		for k, v := range seeds {
+			ele, ok := ctr.GetOrInsert(k, func() container.Value {
+				require.FailNow(t, "the f should not be called if the key exists")
+				return nil
+			})
+			require.False(t, ok)
+			require.NotNil(t, ele)
+			require.Equal(t, ele.Key(), k)
+			require.Equal(t, ele.Value(), v)
+		}
+		require.Equal(t, ctr.Len(), len(seeds))
+	}
+
+	// Base test for all container implementation.
+	for name, f := range containers {
+		t.Run(name+"_base", func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestContainer_UpdateInPlace(t *testing.T) {
+	process := func(ctr container.Container) {
+		// The updated key not exists.
+		for k, v := range seeds {
+			ele, old := ctr.UpdateInPlace(k, v)
+			require.Nil(t, ele)
+			require.Nil(t, old)
+			require.Nil(t, ctr.Search(k))
+		}
+
+		for k, v := range seeds {
+			ctr.Insert(k, v)
+		}
+
+		for k, v := range seeds {
+			e1 := ctr.Search(k)
+			ele, old := ctr.UpdateInPlace(k, v+v)
+			require.NotNil(t, ele)
+			require.True(t, ele == e1)
+			require.Equal(t, old, v)
+			require.Equal(t, ele.Key(), k)
+			require.Equal(t, ele.Value(), v+v)
+			require.True(t, ctr.Search(k) == e1)
+		}
+		require.Equal(t, ctr.Len(), len(seeds))
+	}
+
+	// Base test for all container implementation.
+	for name, f := range containers {
+		t.Run(name+"_base", func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestContainer_UpsertInPlace(t *testing.T) {
+	process := func(ctr container.Container) {
+		// The key not exists, UpsertInPlace same as the Insert
+		for k, v := range seeds {
+			ele, ok := ctr.UpsertInPlace(k, v)
+			require.True(t, ok)
+			require.NotNil(t, ele)
+			require.Equal(t, ele.Key(), k)
+			require.Equal(t, ele.Value(), v)
+		}
+		require.Equal(t, ctr.Len(), len(seeds))
+
+		// The key already exists, the value set in place.
+		for k, v := range seeds {
+			e1 := ctr.Search(k)
+			ele, ok := ctr.UpsertInPlace(k, v+v)
+			require.False(t, ok)
+			require.True(t, ele == e1)
+			require.Equal(t, ele.Value(), v+v)
+			require.Equal(t, ctr.Search(k).Value(), v+v)
+		}
+		require.Equal(t, ctr.Len(), len(seeds))
+	}
+
+	// Base test for all container implementation.
+	for name, f := range containers {
+		t.Run(name+"_base", func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestContainer_MutableElement(t *testing.T) {
+	process := func(ctr container.Container) {
+		handles := make(map[container.Int]container.MutableElement, len(seeds))
+		for k, v := range seeds {
+			ele, _ := ctr.Insert(k, v)
+			handles[k] = ele.(container.MutableElement)
+		}
+
+		// Sets the value by the handle.
+		for k, v := range seeds {
+			handles[k].SetValue(v + v)
+			require.Equal(t, ctr.Search(k).Value(), v+v)
+		}
+
+		// The handles stay valid after other keys deleted.
+		i := 0
+		for k := range seeds {
+			if i%2 == 0 {
+				ctr.Delete(k)
+				delete(handles, k)
+			}
+			i++
+		}
+		for k, h := range handles {
+			require.True(t, ctr.Search(k) == h)
+			h.SetValue(k)
+			require.Equal(t, ctr.Search(k).Value(), k)
+		}
+	}
+
+	// Base test for all container implementation.
+	for name, f := range containers {
+		t.Run(name+"_base", func(t *testing.T) {
+			process(f())
+		})
+	}
+}
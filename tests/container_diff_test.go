@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+)
+
+func TestContainerDiff(t *testing.T) {
+	for nameA, fa := range containers {
+		for nameB, fb := range containers {
+			t.Run(nameA+"_"+nameB, func(t *testing.T) {
+				a, b := fa(), fb()
+				require.True(t, container.Equal(a, b, nil))
+
+				for _, k := range shuffleSeeds(searchSeeds) {
+					a.Insert(k, int(k))
+					b.Insert(k, int(k))
+				}
+				require.True(t, container.Equal(a, b, nil))
+				require.True(t, container.Equal(b, a, nil))
+
+				a.Insert(container.Int64(-1), -1)
+				b.Delete(searchSeeds[3])
+				b.Update(searchSeeds[5], 0)
+				b.Insert(container.Int64(1000), 1000)
+				require.False(t, container.Equal(a, b, nil))
+				require.False(t, container.Equal(b, a, nil))
+
+				var diffs []container.Difference
+				container.Diff(a, b, nil, func(d container.Difference) bool {
+					diffs = append(diffs, d)
+					return true
+				})
+				require.Equal(t, len(diffs), 4)
+
+				require.Equal(t, diffs[0].Key(), container.Int64(-1))
+				require.Equal(t, diffs[0].A.Value(), -1)
+				require.Nil(t, diffs[0].B)
+
+				require.Equal(t, diffs[1].Key(), searchSeeds[3])
+				require.Equal(t, diffs[1].A.Value(), int(searchSeeds[3]))
+				require.Nil(t, diffs[1].B)
+
+				require.Equal(t, diffs[2].Key(), searchSeeds[5])
+				require.Equal(t, diffs[2].A.Value(), int(searchSeeds[5]))
+				require.Equal(t, diffs[2].B.Value(), 0)
+
+				require.Equal(t, diffs[3].Key(), container.Int64(1000))
+				require.Nil(t, diffs[3].A)
+				require.Equal(t, diffs[3].B.Value(), 1000)
+
+				// Stops the diff.
+				n := 0
+				container.Diff(a, b, nil, func(d container.Difference) bool {
+					n++
+					return false
+				})
+				require.Equal(t, n, 1)
+
+				// The custom valueEq.
+				a.Delete(container.Int64(-1))
+				b.Insert(searchSeeds[3], int(searchSeeds[3]))
+				b.Delete(container.Int64(1000))
+				require.False(t, container.Equal(a, b, nil))
+				require.True(t, container.Equal(a, b, func(x container.Value, y container.Value) bool {
+					return true
+				}))
+
+				// Compares the sub-ranges.
+				require.True(t, container.EqualIter(a.Iter(searchSeeds[6], nil), b.Iter(searchSeeds[6], nil), nil))
+				require.False(t, container.EqualIter(a.Iter(nil, nil), b.Iter(searchSeeds[1], nil), nil))
+			})
+		}
+	}
+}
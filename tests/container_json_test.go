@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+func TestContainerJSON_WriteRead(t *testing.T) {
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			ctr := f()
+			for _, k := range shuffleSeeds(searchSeeds) {
+				ctr.Insert(k, float64(k*2+1))
+			}
+
+			for _, ndjson := range []bool{false, true} {
+				write, read := container.WriteJSON, container.ReadJSON
+				if ndjson {
+					write, read = container.WriteNDJSON, container.ReadNDJSON
+				}
+
+				var buf bytes.Buffer
+				require.Nil(t, write(&buf, ctr, container.Int64(35), container.Int64(97)))
+				if ndjson {
+					require.Equal(t, strings.Count(buf.String(), "\n"), 8)
+				}
+
+				dst := f()
+				n, err := read(&buf, dst)
+				require.Nil(t, err)
+				require.Equal(t, n, 8)
+				expected := searchRange(ctr, container.Int64(35), container.Int64(97))
+				actual := searchRange(dst, nil, nil)
+				require.Equal(t, len(actual), len(expected))
+				for i := range expected {
+					require.Equal(t, actual[i].Key(), expected[i].Key())
+					require.Equal(t, actual[i].Value(), expected[i].Value())
+				}
+
+				// Empty range.
+				buf.Reset()
+				require.Nil(t, write(&buf, ctr, container.Int64(200), nil))
+				n, err = read(&buf, f())
+				require.Nil(t, err)
+				require.Equal(t, n, 0)
+			}
+		})
+	}
+}
+
+func TestContainerJSON_KeyTypes(t *testing.T) {
+	now := time.Date(2020, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	keys := []container.Key{
+		container.String("s"),
+		container.Byte(1),
+		container.Rune('r'),
+		container.Int(-1),
+		container.Int8(-8),
+		container.Int16(-16),
+		container.Int32(-32),
+		container.Int64(-1 << 62),
+		container.Uint(1),
+		container.Uint8(8),
+		container.Uint16(16),
+		container.Uint32(32),
+		container.Uint64(1 << 63),
+		container.Bytes("\x00\xff"),
+		container.Duration(90 * time.Second),
+		container.Time(now),
+	}
+
+	for _, k := range keys {
+		tr := rb.New()
+		tr.Insert(k, "v")
+
+		data, err := json.Marshal(tr)
+		require.Nil(t, err)
+
+		dst := rb.New()
+		require.Nil(t, json.Unmarshal(data, dst))
+		require.Equal(t, dst.Len(), 1)
+		require.Equal(t, dst.Search(k).Value(), "v")
+	}
+
+	// The format of special types.
+	tr := rb.New()
+	tr.Insert(container.Time(now), nil)
+	data, err := json.Marshal(tr)
+	require.Nil(t, err)
+	require.Equal(t, string(data), `[{"type":"Time","key":"2020-05-06T07:08:09.123456789Z","value":null}]`)
+
+	tr = rb.New()
+	tr.Insert(container.Bytes("ab"), nil)
+	data, err = json.Marshal(tr)
+	require.Nil(t, err)
+	require.Equal(t, string(data), `[{"type":"Bytes","key":"YWI=","value":null}]`)
+
+	tr = rb.New()
+	tr.Insert(container.Duration(90*time.Second), nil)
+	data, err = json.Marshal(tr)
+	require.Nil(t, err)
+	require.Equal(t, string(data), `[{"type":"Duration","key":"1m30s","value":null}]`)
+}
+
+func TestContainerJSON_Unmarshal(t *testing.T) {
+	// The zero List can be decoded into.
+	var sl skip.List
+	require.Nil(t, json.Unmarshal([]byte(`[{"type":"Int","key":2,"value":"b"},{"type":"Int","key":1,"value":"a"}]`), &sl))
+	require.Equal(t, sl.Len(), 2)
+	require.Equal(t, sl.Search(container.Int(1)).Value(), "a")
+
+	// Replaces the existing elements.
+	require.Nil(t, json.Unmarshal([]byte(`[]`), &sl))
+	require.Equal(t, sl.Len(), 0)
+
+	tr := rb.New()
+	tr.Insert(container.Int(1), 1)
+	for _, data := range []string{
+		`{}`,
+		`[{"type":"Unknown","key":1}]`,
+		`[{"type":"Int","key":"x"}]`,
+		`[{"type":"Int"}]`,
+		`[{"type":"Int","key":1}`,
+	} {
+		require.NotNil(t, json.Unmarshal([]byte(data), tr), data)
+		require.Equal(t, tr.Len(), 0)
+	}
+
+	// Unsupported key type.
+	type key struct{ container.Int }
+	tr.Insert(key{1}, nil)
+	_, err := json.Marshal(tr)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,287 @@
+package tests
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+)
+
+// multiModel is a reference model of MultiContainer that implements by a map of slice.
+type multiModel struct {
+	values map[container.Int64][]int
+}
+
+func (m *multiModel) keys() []container.Int64 {
+	keys := make([]container.Int64, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func (m *multiModel) elements() [][2]interface{} {
+	var result [][2]interface{}
+	for _, k := range m.keys() {
+		for _, v := range m.values[k] {
+			result = append(result, [2]interface{}{k, v})
+		}
+	}
+	return result
+}
+
+func (m *multiModel) len() int {
+	n := 0
+	for _, vs := range m.values {
+		n += len(vs)
+	}
+	return n
+}
+
+func toPairs(elements []container.Element) [][2]interface{} {
+	var result [][2]interface{}
+	for _, ele := range elements {
+		result = append(result, [2]interface{}{ele.Key(), ele.Value()})
+	}
+	return result
+}
+
+func insertDuplicates(ctr container.MultiContainer, m *multiModel, n int, maxKey int) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i < n; i++ {
+		k := container.Int64(r.Intn(maxKey))
+		ele, ok := ctr.Insert(k, i)
+		if !ok || ele.Key() != k || ele.Value() != i {
+			panic("insert duplicates failed")
+		}
+		m.values[k] = append(m.values[k], i)
+	}
+}
+
+func TestMultiContainer_Insert(t *testing.T) {
+	process := func(ctr container.MultiContainer) {
+		m := &multiModel{values: make(map[container.Int64][]int)}
+		insertDuplicates(ctr, m, 2048, 64)
+
+		require.Equal(t, ctr.Len(), m.len())
+		require.Equal(t, toPairs(searchRange(ctr, nil, nil)), m.elements())
+		require.Equal(t, toPairs(searchRangeByIter(ctr, nil, nil)), m.elements())
+
+		for _, k := range m.keys() {
+			require.Equal(t, ctr.Count(k), len(m.values[k]))
+
+			all := ctr.SearchAll(k)
+			require.Equal(t, len(all), len(m.values[k]))
+			for i, ele := range all {
+				require.Equal(t, ele.Key(), k)
+				require.Equal(t, ele.Value(), m.values[k][i])
+			}
+
+			// Search returns the first element in insertion order.
+			require.Equal(t, ctr.Search(k).Value(), m.values[k][0])
+
+			// Range yields every duplicate.
+			require.Equal(t, len(searchRange(ctr, k, k+1)), len(m.values[k]))
+			require.Equal(t, len(searchRangeByIter(ctr, k, k+1)), len(m.values[k]))
+		}
+
+		require.Nil(t, ctr.SearchAll(container.Int64(-1)))
+		require.Equal(t, ctr.Count(container.Int64(-1)), 0)
+	}
+
+	for name, f := range multiContainers {
+		t.Run(name, func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestMultiContainer_Searcher(t *testing.T) {
+	process := func(ctr container.MultiContainer) {
+		for _, k := range searchSeeds {
+			for i := 0; i < 3; i++ {
+				ctr.Insert(k, i)
+			}
+		}
+
+		var ele container.Element
+
+		ele = ctr.LastLT(container.Int64(24))
+		require.Equal(t, ele.Key(), container.Int64(22))
+		require.Equal(t, ele.Value(), 2)
+
+		ele = ctr.LastLE(container.Int64(24))
+		require.Equal(t, ele.Key(), container.Int64(24))
+		require.Equal(t, ele.Value(), 2)
+
+		ele = ctr.LastLE(container.Int64(25))
+		require.Equal(t, ele.Key(), container.Int64(24))
+		require.Equal(t, ele.Value(), 2)
+
+		ele = ctr.FirstGT(container.Int64(24))
+		require.Equal(t, ele.Key(), container.Int64(35))
+		require.Equal(t, ele.Value(), 0)
+
+		ele = ctr.FirstGE(container.Int64(24))
+		require.Equal(t, ele.Key(), container.Int64(24))
+		require.Equal(t, ele.Value(), 0)
+
+		ele = ctr.FirstGE(container.Int64(23))
+		require.Equal(t, ele.Key(), container.Int64(24))
+		require.Equal(t, ele.Value(), 0)
+
+		require.Nil(t, ctr.LastLT(container.Int64(22)))
+		require.Nil(t, ctr.FirstGT(container.Int64(150)))
+		require.Equal(t, ctr.LastLE(container.Int64(150)).Value(), 2)
+		require.Equal(t, ctr.FirstGE(container.Int64(150)).Value(), 0)
+	}
+
+	for name, f := range multiContainers {
+		t.Run(name, func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestMultiContainer_Delete(t *testing.T) {
+	process := func(ctr container.MultiContainer) {
+		m := &multiModel{values: make(map[container.Int64][]int)}
+		insertDuplicates(ctr, m, 2048, 64)
+
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for _, k := range m.keys() {
+			switch r.Intn(4) {
+			case 0:
+				// Delete removes the first one.
+				ele := ctr.Delete(k)
+				require.Equal(t, ele.Value(), m.values[k][0])
+				m.values[k] = m.values[k][1:]
+			case 1:
+				// DeleteOne removes the first matched one.
+				target := m.values[k][r.Intn(len(m.values[k]))]
+				ele := ctr.DeleteOne(k, func(ele container.Element) bool {
+					return ele.Value() == target
+				})
+				require.NotNil(t, ele)
+				require.Equal(t, ele.Value(), target)
+				for i, v := range m.values[k] {
+					if v == target {
+						m.values[k] = append(m.values[k][:i:i], m.values[k][i+1:]...)
+						break
+					}
+				}
+				require.Nil(t, ctr.DeleteOne(k, func(ele container.Element) bool {
+					return ele.Value() == target
+				}))
+			case 2:
+				require.Equal(t, ctr.DeleteAll(k), len(m.values[k]))
+				require.Equal(t, ctr.DeleteAll(k), 0)
+				m.values[k] = nil
+			case 3:
+				// Update and Compute operate on the first one.
+				ctr.Update(k, -1)
+				ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+					require.True(t, exists)
+					require.Equal(t, ele.Value(), -1)
+					return -2, true
+				})
+				m.values[k][0] = -2
+			}
+			if len(m.values[k]) == 0 {
+				delete(m.values, k)
+				require.Nil(t, ctr.Search(k))
+			}
+
+			require.Equal(t, ctr.Len(), m.len())
+			require.Equal(t, ctr.Count(k), len(m.values[k]))
+		}
+
+		require.Equal(t, toPairs(searchRange(ctr, nil, nil)), m.elements())
+
+		// Delete all elements.
+		for _, k := range m.keys() {
+			for range m.values[k] {
+				require.NotNil(t, ctr.Delete(k))
+			}
+			require.Nil(t, ctr.Delete(k))
+		}
+		require.Equal(t, ctr.Len(), 0)
+	}
+
+	for name, f := range multiContainers {
+		t.Run(name, func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestMultiContainer_DeleteRange(t *testing.T) {
+	process := func(ctr container.MultiContainer) {
+		m := &multiModel{values: make(map[container.Int64][]int)}
+		insertDuplicates(ctr, m, 2048, 64)
+
+		n := ctr.DeleteRange(container.Int64(16), container.Int64(48), nil)
+		expected := 0
+		for k, vs := range m.values {
+			if k >= 16 && k < 48 {
+				expected += len(vs)
+				delete(m.values, k)
+			}
+		}
+		require.Equal(t, n, expected)
+		require.Equal(t, ctr.Len(), m.len())
+		require.Equal(t, toPairs(searchRange(ctr, nil, nil)), m.elements())
+	}
+
+	for name, f := range multiContainers {
+		t.Run(name, func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestMultiContainer_Reverse(t *testing.T) {
+	process := func(ctr container.MultiContainer) {
+		for _, k := range shuffleSeeds(searchSeeds) {
+			for i := 0; i < 3; i++ {
+				ctr.Insert(k, i)
+			}
+		}
+
+		for _, r := range [][2]container.Key{
+			{nil, nil},
+			{container.Int64(24), container.Int64(97)},
+			{container.Int64(25), nil},
+			{nil, container.Int64(22)},
+		} {
+			expected := reverseElementSlice(searchRange(ctr, r[0], r[1]))
+			require.Equal(t, searchReceive(ctr, r[0], r[1]), expected)
+
+			var actual []container.Element
+			iter := ctr.IterReverse(r[0], r[1])
+			for iter.Valid() {
+				actual = append(actual, iter.Next())
+			}
+			require.Equal(t, actual, expected)
+		}
+
+		// The elements with the same key are in reverse insertion order.
+		var values []container.Value
+		ctr.Reverse(container.Int64(24), container.Int64(25), func(ele container.Element) bool {
+			values = append(values, ele.Value())
+			return true
+		})
+		require.Equal(t, values, []container.Value{2, 1, 0})
+	}
+
+	for name, f := range multiContainers {
+		t.Run(name, func(t *testing.T) {
+			process(f())
+		})
+	}
+}
@@ -141,7 +141,7 @@ func TestContainerRetriever_Range(t *testing.T) {
 	}
 
 	// Test for all container implementation.
-	for name, f := range containers {
+	for name, f := range retrievers {
 		t.Run(name, func(t *testing.T) {
 			process(t, f())
 		})
@@ -277,11 +277,7 @@ func TestContainerRetriever_Reverse(t *testing.T) {
 	}
 
 	// Test for all container implementation.
-	for name, f := range containers {
-		// TODO: skiplist not implemented on now.
-		if name == "skiplist" {
-			continue
-		}
+	for name, f := range retrievers {
 		t.Run(name, func(t *testing.T) {
 			process(t, f())
 		})
@@ -536,7 +532,7 @@ func TestContainerRetriever_Iter(t *testing.T) {
 	}
 
 	// Test for all container implementation.
-	for name, f := range containers {
+	for name, f := range retrievers {
 		t.Run(name, func(t *testing.T) {
 			process(t, f())
 		})
@@ -791,11 +787,7 @@ func TestContainerRetriever_IterReverse(t *testing.T) {
 	}
 
 	// Test for all container implementation.
-	for name, f := range containers {
-		// TODO: skiplist not implemented on now.
-		if name == "skiplist" {
-			continue
-		}
+	for name, f := range retrievers {
 		t.Run(name, func(t *testing.T) {
 			process(t, f())
 		})
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+type scanner interface {
+	container.Container
+	RangeContext(ctx context.Context, start container.Key, boundary container.Key, f func(ele container.Element) bool) error
+	ParallelRange(start container.Key, boundary container.Key, workers int, f func(ele container.Element) error) error
+}
+
+var scanners = map[string]func() scanner{
+	"rbtree": func() scanner {
+		return rb.New()
+	},
+	"skiplist": func() scanner {
+		return skip.New()
+	},
+	"rbtree_multi": func() scanner {
+		return rb.NewMulti()
+	},
+	"skiplist_multi": func() scanner {
+		return skip.NewMulti()
+	},
+}
+
+func TestContainerScan_RangeContext(t *testing.T) {
+	for name, f := range scanners {
+		t.Run(name, func(t *testing.T) {
+			ctr := f()
+			for i := 0; i < 1000; i++ {
+				ctr.Insert(container.Int(i), i)
+			}
+
+			n := 0
+			err := ctr.RangeContext(context.Background(), container.Int(100), container.Int(900), func(ele container.Element) bool {
+				require.Equal(t, ele.Key(), container.Int(100+n))
+				n++
+				return true
+			})
+			require.Nil(t, err)
+			require.Equal(t, n, 800)
+
+			// Stops by f.
+			n = 0
+			err = ctr.RangeContext(context.Background(), nil, nil, func(ele container.Element) bool {
+				n++
+				return n < 10
+			})
+			require.Nil(t, err)
+			require.Equal(t, n, 10)
+
+			// Cancelled before the iteration.
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			n = 0
+			err = ctr.RangeContext(ctx, nil, nil, func(ele container.Element) bool {
+				n++
+				return true
+			})
+			require.Equal(t, err, context.Canceled)
+			require.Equal(t, n, 0)
+
+			// Cancelled during the iteration.
+			ctx, cancel = context.WithCancel(context.Background())
+			n = 0
+			err = ctr.RangeContext(ctx, nil, nil, func(ele container.Element) bool {
+				n++
+				if n == 300 {
+					cancel()
+				}
+				return true
+			})
+			require.Equal(t, err, context.Canceled)
+			require.Less(t, n, 1000)
+		})
+	}
+}
+
+func TestContainerScan_ParallelRange(t *testing.T) {
+	for name, f := range scanners {
+		t.Run(name, func(t *testing.T) {
+			ctr := f()
+			for i := 0; i < 5000; i++ {
+				ctr.Insert(container.Int(i%2500), i)
+			}
+
+			for _, workers := range []int{0, 1, 3, 8} {
+				for _, r := range [][2]container.Key{
+					{nil, nil},
+					{container.Int(100), container.Int(2000)},
+					{container.Int(1000), nil},
+					{nil, container.Int(3)},
+					{container.Int(3000), nil},
+				} {
+					var mu sync.Mutex
+					var keys []int
+					err := ctr.ParallelRange(r[0], r[1], workers, func(ele container.Element) error {
+						mu.Lock()
+						keys = append(keys, int(ele.Key().(container.Int)))
+						mu.Unlock()
+						return nil
+					})
+					require.Nil(t, err)
+
+					var expected []int
+					for _, ele := range searchRange(ctr, r[0], r[1]) {
+						expected = append(expected, int(ele.Key().(container.Int)))
+					}
+					sort.Ints(keys)
+					require.Equal(t, keys, expected)
+				}
+			}
+
+			// Returns the first error.
+			errStop := errors.New("stop")
+			var n int64
+			err := ctr.ParallelRange(nil, nil, 4, func(ele container.Element) error {
+				if atomic.AddInt64(&n, 1) == 100 {
+					return errStop
+				}
+				return nil
+			})
+			require.Equal(t, err, errStop)
+			require.Less(t, atomic.LoadInt64(&n), int64(ctr.Len()))
+		})
+	}
+}
@@ -79,7 +79,7 @@ func TestContainerSearcher_LastLT(t *testing.T) {
 	}
 
 	// Test for all container implementation.
-	for name, f := range containers {
+	for name, f := range retrievers {
 		t.Run(name, func(t *testing.T) {
 			process(f())
 		})
@@ -156,7 +156,7 @@ func TestContainerSearcher_LastLE(t *testing.T) {
 	}
 
 	// Test for all container implementation.
-	for name, f := range containers {
+	for name, f := range retrievers {
 		t.Run(name, func(t *testing.T) {
 			process(f())
 		})
@@ -225,7 +225,7 @@ func TestContainerSearcher_FirstGT(t *testing.T) {
 	}
 
 	// Test for all container implementation.
-	for name, f := range containers {
+	for name, f := range retrievers {
 		t.Run(name, func(t *testing.T) {
 			process(f())
 		})
@@ -297,7 +297,31 @@ func TestContainerSearcher_FirstGE(t *testing.T) {
 	}
 
 	// Test for all container implementation.
-	for name, f := range containers {
+	for name, f := range retrievers {
+		t.Run(name, func(t *testing.T) {
+			process(f())
+		})
+	}
+}
+
+func TestContainerSearcher_NotFound(t *testing.T) {
+	process := func(ctr container.Container) {
+		require.True(t, ctr.LastLT(container.Int64(22)) == nil)
+		require.True(t, ctr.LastLE(container.Int64(22)) == nil)
+		require.True(t, ctr.FirstGT(container.Int64(22)) == nil)
+		require.True(t, ctr.FirstGE(container.Int64(22)) == nil)
+
+		for _, k := range shuffleSeeds(searchSeeds) {
+			ctr.Insert(k, int64(k*2+1))
+		}
+
+		require.True(t, ctr.LastLT(container.Int64(22)) == nil)
+		require.True(t, ctr.LastLE(container.Int64(21)) == nil)
+		require.True(t, ctr.FirstGT(container.Int64(150)) == nil)
+		require.True(t, ctr.FirstGE(container.Int64(151)) == nil)
+	}
+
+	for name, f := range retrievers {
 		t.Run(name, func(t *testing.T) {
 			process(f())
 		})
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"iter"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/container"
+)
+
+func TestContainerSeq(t *testing.T) {
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			ctr := f()
+			for _, k := range shuffleSeeds(searchSeeds) {
+				ctr.Insert(k, int(k)*2)
+			}
+
+			var keys []container.Key
+			for k, v := range container.All(ctr) {
+				require.Equal(t, v, int(k.(container.Int64))*2)
+				keys = append(keys, k)
+			}
+			require.Equal(t, len(keys), len(searchSeeds))
+			for i, k := range keys {
+				require.Equal(t, k, searchSeeds[i])
+			}
+
+			keys = nil
+			for k := range container.Keys(ctr) {
+				keys = append(keys, k)
+			}
+			require.Equal(t, len(keys), len(searchSeeds))
+
+			var values []container.Value
+			for v := range container.Values(ctr) {
+				values = append(values, v)
+			}
+			require.Equal(t, values[0], int(searchSeeds[0])*2)
+			require.Equal(t, len(values), len(searchSeeds))
+
+			// Breaks early.
+			n := 0
+			for range container.All(ctr) {
+				n++
+				if n == 3 {
+					break
+				}
+			}
+			require.Equal(t, n, 3)
+
+			expected := searchRange(ctr, container.Int64(35), container.Int64(97))
+			i := 0
+			for k, v := range container.Between(ctr, container.Int64(35), container.Int64(97)) {
+				require.Equal(t, k, expected[i].Key())
+				require.Equal(t, v, expected[i].Value())
+				i++
+			}
+			require.Equal(t, i, len(expected))
+
+			keys = nil
+			for k := range container.Backward(ctr) {
+				keys = append(keys, k)
+			}
+			require.Equal(t, len(keys), len(searchSeeds))
+			for i, k := range keys {
+				require.Equal(t, k, searchSeeds[len(searchSeeds)-1-i])
+			}
+		})
+	}
+}
+
+// The sequence methods of the containers.
+type sequencer interface {
+	All() iter.Seq2[container.Key, container.Value]
+	Backward() iter.Seq2[container.Key, container.Value]
+	Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value]
+	Keys() iter.Seq[container.Key]
+	Values() iter.Seq[container.Value]
+}
+
+func TestContainerSeq_Methods(t *testing.T) {
+	pairs := func(seq iter.Seq2[container.Key, container.Value]) []interface{} {
+		var result []interface{}
+		for k, v := range seq {
+			result = append(result, k, v)
+		}
+		return result
+	}
+
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			ctr := f()
+			for _, k := range shuffleSeeds(searchSeeds) {
+				ctr.Insert(k, int(k)*2)
+			}
+
+			seq, ok := ctr.(sequencer)
+			require.True(t, ok)
+			require.Equal(t, pairs(seq.All()), pairs(container.All(ctr)))
+			require.Equal(t, pairs(seq.Backward()), pairs(container.Backward(ctr)))
+			require.Equal(t, pairs(seq.Between(container.Int64(35), container.Int64(97))),
+				pairs(container.Between(ctr, container.Int64(35), container.Int64(97))))
+
+			n := 0
+			for k := range seq.Keys() {
+				require.Equal(t, k, searchSeeds[n])
+				n++
+			}
+			require.Equal(t, n, len(searchSeeds))
+
+			n = 0
+			for v := range seq.Values() {
+				require.Equal(t, v, int(searchSeeds[n])*2)
+				n++
+			}
+			require.Equal(t, n, len(searchSeeds))
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/yu31/structs-go/avl"
 	"github.com/yu31/structs-go/bs"
 	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/partition"
 	"github.com/yu31/structs-go/rb"
 	"github.com/yu31/structs-go/skip"
 )
@@ -24,6 +25,37 @@ var containers = map[string]func() container.Container{
 	"skiplist": func() container.Container {
 		return skip.New()
 	},
+	"concurrent_skiplist": func() container.Container {
+		return skip.NewConcurrent()
+	},
+}
+
+// retrievers are the containers checked by the Retriever and Searcher tests. Besides the containers,
+// it includes the partition.Map, whose elements are snapshots, with the shards of each implementation.
+var retrievers = map[string]func() container.Container{}
+
+func init() {
+	for name, f := range containers {
+		retrievers[name] = f
+	}
+	retrievers["partition_rbtree"] = func() container.Container {
+		return partition.New(func() container.Container { return rb.New() }, 4)
+	}
+	retrievers["partition_skiplist"] = func() container.Container {
+		return partition.New(func() container.Container { return skip.New() }, 4)
+	}
+}
+
+var multiContainers = map[string]func() container.MultiContainer{
+	"avltree": func() container.MultiContainer {
+		return avl.NewMulti()
+	},
+	"rbtree": func() container.MultiContainer {
+		return rb.NewMulti()
+	},
+	"skiplist": func() container.MultiContainer {
+		return skip.NewMulti()
+	},
 }
 
 var trees = map[string]func() container.Tree{
@@ -109,3 +141,37 @@ func reverseSeedsSlice(seeds []container.Int64) []container.Int64 {
 	}
 	return result
 }
+
+// Pairs returns the keys and values of the elements in range start <= x < boundary of r,
+// in descending order if reverse is true. It is shared by the tests of other packages.
+func Pairs(r container.Retriever, start container.Key, boundary container.Key, reverse bool) []interface{} {
+	var result []interface{}
+	f := func(ele container.Element) bool {
+		result = append(result, ele.Key(), ele.Value())
+		return true
+	}
+	if reverse {
+		r.Reverse(start, boundary, f)
+	} else {
+		r.Range(start, boundary, f)
+	}
+	return result
+}
+
+// IterPairs returns the keys and values of the remaining elements of it.
+func IterPairs(it container.Iterator) []interface{} {
+	var result []interface{}
+	for it.Valid() {
+		ele := it.Next()
+		result = append(result, ele.Key(), ele.Value())
+	}
+	return result
+}
+
+// ElementPair returns the key and value of ele, nil if ele is nil.
+func ElementPair(ele container.Element) []interface{} {
+	if ele == nil {
+		return nil
+	}
+	return []interface{}{ele.Key(), ele.Value()}
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ttl
+
+import (
+	"iter"
+	"time"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/minheap"
+)
+
+var (
+	_ container.Retriever = (*Container)(nil)
+	_ container.Searcher  = (*Container)(nil)
+	_ container.Element   = (*element)(nil)
+)
+
+// entry is the value stored in the wrapped container.
+type entry struct {
+	value    container.Value
+	deadline time.Time
+	item     *minheap.Item // The item of deadline in heap, nil if the entry never expires.
+}
+
+// element is an element of Container.
+type element struct {
+	key   container.Key
+	entry *entry
+}
+
+// Key returns the key.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value.
+func (e *element) Value() container.Value {
+	return e.entry.value
+}
+
+// Deadline returns the time that the element expires at, zero if it never expires.
+func (e *element) Deadline() time.Time {
+	return e.entry.deadline
+}
+
+// Container pairs a container.Container with a MinHeap of deadlines to support per-key expiry.
+//
+// The expired keys are invisible to Search, Range and the Searcher methods. They are reclaimed lazily
+// when found by Search and the Searcher methods, or by an explicit Expire sweep.
+//
+// The Container is not safe for concurrent use.
+type Container struct {
+	ctr  container.Container
+	heap *minheap.MinHeap // The key of item is the deadline, and the value is the key of element.
+	now  func() time.Time
+}
+
+// New creates a Container with an empty container, such as rb.New() or skip.New().
+// The now is used to get the current time, time.Now is used if it's nil.
+func New(ctr container.Container, now func() time.Time) *Container {
+	if now == nil {
+		now = time.Now
+	}
+	return &Container{
+		ctr:  ctr,
+		heap: minheap.Default(),
+		now:  now,
+	}
+}
+
+// Len returns the number of elements, including the expired elements that have not been reclaimed.
+func (c *Container) Len() int {
+	return c.ctr.Len()
+}
+
+// Put inserts or updates an element with the given key and value, the element expires after ttl.
+// The element never expires if ttl <= 0.
+// The bool result is true if an element was inserted, false if an element was updated.
+// Replacing an expired element is an insertion.
+func (c *Container) Put(k container.Key, v container.Value, ttl time.Duration) bool {
+	var deadline time.Time
+	if ttl > 0 {
+		deadline = c.now().Add(ttl)
+	}
+	return c.PutWithDeadline(k, v, deadline)
+}
+
+// PutWithDeadline is similar to the Put method, but the element expires at the deadline.
+// The element never expires if deadline is zero.
+func (c *Container) PutWithDeadline(k container.Key, v container.Value, deadline time.Time) bool {
+	e := &entry{value: v, deadline: deadline}
+	if !deadline.IsZero() {
+		e.item = c.heap.Push(container.Time(deadline), k)
+	}
+	ele, inserted := c.ctr.Upsert(k, e)
+	if !inserted {
+		old := ele.Value().(*entry)
+		c.removeItem(old)
+		// The expired element is absent as the Search method, though it has not been reclaimed.
+		inserted = c.expired(old, c.now())
+	}
+	return inserted
+}
+
+// Delete removes and returns the element of a given key.
+// Returns nil if key not found or it has expired.
+func (c *Container) Delete(k container.Key) container.Element {
+	ele := c.ctr.Delete(k)
+	if ele == nil {
+		return nil
+	}
+	e := ele.Value().(*entry)
+	c.removeItem(e)
+	if c.expired(e, c.now()) {
+		return nil
+	}
+	return &element{key: ele.Key(), entry: e}
+}
+
+// Search searches the element of a given key.
+// Returns nil if key not found or it has expired.
+func (c *Container) Search(k container.Key) container.Element {
+	return c.visible(c.ctr.Search(k), c.now())
+}
+
+// TTL returns the remaining time to live of key.
+// The bool result is false if key not found or it has expired. The duration is zero if the key never expires.
+func (c *Container) TTL(k container.Key) (time.Duration, bool) {
+	now := c.now()
+	ele := c.visible(c.ctr.Search(k), now)
+	if ele == nil {
+		return 0, false
+	}
+	deadline := ele.(*element).entry.deadline
+	if deadline.IsZero() {
+		return 0, true
+	}
+	return deadline.Sub(now), true
+}
+
+// Expire removes all elements that expired at the time now, and returns the number of removed elements.
+func (c *Container) Expire(now time.Time) int {
+	n := 0
+	for !c.heap.Empty() {
+		item := c.heap.Peek()
+		if time.Time(item.Key().(container.Time)).After(now) {
+			break
+		}
+		c.heap.Pop()
+		c.ctr.Delete(item.Value().(container.Key))
+		n++
+	}
+	return n
+}
+
+// Iter creates an Iterator positioned on the first element that key >= start key.
+// The Iterator skips the elements that expired at the time of created.
+func (c *Container) Iter(start container.Key, boundary container.Key) container.Iterator {
+	return newIterator(c, c.ctr.Iter(start, boundary), c.now())
+}
+
+// IterReverse is similar to the Iter method, but the elements are in reverse order.
+func (c *Container) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	return newIterator(c, c.ctr.IterReverse(start, boundary), c.now())
+}
+
+// All returns an iterator over all elements in ascending order.
+func (c *Container) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(c)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (c *Container) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(c)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (c *Container) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(c, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (c *Container) Keys() iter.Seq[container.Key] {
+	return container.Keys(c)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (c *Container) Values() iter.Seq[container.Value] {
+	return container.Values(c)
+}
+
+// Range calls f sequentially each element that not expired in the Container.
+// If f returns false, range stops the iteration.
+func (c *Container) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	now := c.now()
+	c.ctr.Range(start, boundary, func(ele container.Element) bool {
+		e := ele.Value().(*entry)
+		if c.expired(e, now) {
+			return true
+		}
+		return f(&element{key: ele.Key(), entry: e})
+	})
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+func (c *Container) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	now := c.now()
+	c.ctr.Reverse(start, boundary, func(ele container.Element) bool {
+		e := ele.Value().(*entry)
+		if c.expired(e, now) {
+			return true
+		}
+		return f(&element{key: ele.Key(), entry: e})
+	})
+}
+
+// LastLT searches for the last element that less than the key.
+func (c *Container) LastLT(k container.Key) container.Element {
+	return c.search(k, container.Searcher.LastLT, container.Searcher.LastLT)
+}
+
+// LastLE search for the last element that less than or equal to the key.
+func (c *Container) LastLE(k container.Key) container.Element {
+	return c.search(k, container.Searcher.LastLE, container.Searcher.LastLT)
+}
+
+// FirstGT search for the first element that greater than to the key.
+func (c *Container) FirstGT(k container.Key) container.Element {
+	return c.search(k, container.Searcher.FirstGT, container.Searcher.FirstGT)
+}
+
+// FirstGE search for the first element that greater than or equal to the key.
+func (c *Container) FirstGE(k container.Key) container.Element {
+	return c.search(k, container.Searcher.FirstGE, container.Searcher.FirstGT)
+}
+
+// Searches the element with first, and calls next to skip the expired elements.
+func (c *Container) search(k container.Key, first, next func(s container.Searcher, k container.Key) container.Element) container.Element {
+	now := c.now()
+	ele := first(c.ctr, k)
+	for ele != nil {
+		if v := c.visible(ele, now); v != nil {
+			return v
+		}
+		ele = next(c.ctr, ele.Key())
+	}
+	return nil
+}
+
+// Returns the element of Container if it has not expired at the time now.
+// Otherwise, reclaims it and returns nil.
+func (c *Container) visible(ele container.Element, now time.Time) container.Element {
+	if ele == nil {
+		return nil
+	}
+	e := ele.Value().(*entry)
+	if c.expired(e, now) {
+		c.ctr.Delete(ele.Key())
+		c.removeItem(e)
+		return nil
+	}
+	return &element{key: ele.Key(), entry: e}
+}
+
+// Represents whether the entry has expired at the time now.
+func (c *Container) expired(e *entry, now time.Time) bool {
+	return e.item != nil && !e.deadline.After(now)
+}
+
+// Removes the deadline of entry from heap.
+func (c *Container) removeItem(e *entry) {
+	if e.item != nil && e.item.Index() >= 0 {
+		c.heap.Remove(e.item.Index())
+	}
+}
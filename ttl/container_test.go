@@ -0,0 +1,140 @@
+package ttl
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/avl"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+var containers = map[string]func() container.Container{
+	"avltree": func() container.Container {
+		return avl.New()
+	},
+	"rbtree": func() container.Container {
+		return rb.New()
+	},
+	"skiplist": func() container.Container {
+		return skip.New()
+	},
+}
+
+// clock is a fake clock for deterministic tests.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func keys(r container.Retriever) []container.Key {
+	var ks []container.Key
+	r.Range(nil, nil, func(ele container.Element) bool {
+		ks = append(ks, ele.Key())
+		return true
+	})
+	return ks
+}
+
+func TestContainer(t *testing.T) {
+	process := func(c *Container, clk *clock) {
+		require.True(t, c.Put(container.Int(1), 1, time.Second))
+		require.True(t, c.Put(container.Int(2), 2, 3*time.Second))
+		require.True(t, c.Put(container.Int(3), 3, 0))
+		require.True(t, c.Put(container.Int(4), 4, 2*time.Second))
+		require.Equal(t, keys(c), []container.Key{container.Int(1), container.Int(2), container.Int(3), container.Int(4)})
+
+		d, ok := c.TTL(container.Int(2))
+		require.True(t, ok)
+		require.Equal(t, d, 3*time.Second)
+		d, ok = c.TTL(container.Int(3))
+		require.True(t, ok)
+		require.Equal(t, d, time.Duration(0))
+
+		// The key 1 expires.
+		clk.Advance(time.Second)
+		require.Nil(t, c.Search(container.Int(1)))
+		require.Equal(t, c.Len(), 3)
+		require.Equal(t, keys(c), []container.Key{container.Int(2), container.Int(3), container.Int(4)})
+		require.Equal(t, c.FirstGE(container.Int(0)).Key(), container.Int(2))
+
+		// Updates the key 4 to expire later.
+		require.False(t, c.Put(container.Int(4), 44, 10*time.Second))
+
+		// The key 2 expires.
+		clk.Advance(2 * time.Second)
+		require.Nil(t, c.Search(container.Int(2)))
+		require.Nil(t, c.LastLT(container.Int(3)))
+		require.Equal(t, c.LastLE(container.Int(3)).Key(), container.Int(3))
+		require.Equal(t, c.FirstGT(container.Int(3)).Value(), 44)
+
+		var ks []container.Key
+		it := c.Iter(nil, nil)
+		for it.Valid() {
+			ks = append(ks, it.Next().Key())
+		}
+		require.Equal(t, ks, []container.Key{container.Int(3), container.Int(4)})
+
+		require.Equal(t, c.Expire(clk.Now().Add(time.Hour)), 1)
+		require.Equal(t, c.Len(), 1)
+		require.Equal(t, c.Search(container.Int(3)).Value(), 3)
+		require.Equal(t, c.Delete(container.Int(3)).Value(), 3)
+		require.Equal(t, c.Len(), 0)
+		require.True(t, c.heap.Empty())
+
+		// Replacing an expired element that has not been reclaimed is an insertion.
+		require.True(t, c.Put(container.Int(5), 5, time.Second))
+		clk.Advance(time.Second)
+		require.Equal(t, c.Len(), 1)
+		require.True(t, c.Put(container.Int(5), 55, time.Second))
+		require.False(t, c.Put(container.Int(5), 555, time.Second))
+		require.Equal(t, c.Search(container.Int(5)).Value(), 555)
+	}
+
+	for name, f := range containers {
+		t.Run(name, func(t *testing.T) {
+			clk := &clock{now: time.Unix(0, 0)}
+			process(New(f(), clk.Now), clk)
+		})
+	}
+}
+
+func TestContainer_Expire(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	clk := &clock{now: time.Unix(0, 0)}
+	c := New(rb.New(), clk.Now)
+
+	// The reference model of deadlines.
+	deadlines := make(map[int]time.Time)
+	for i := 0; i < 4096; i++ {
+		k := r.Intn(256)
+		ttl := time.Duration(r.Intn(64)+1) * time.Millisecond
+		c.Put(container.Int(k), k, ttl)
+		deadlines[k] = clk.Now().Add(ttl)
+
+		if r.Intn(16) == 0 {
+			clk.Advance(time.Duration(r.Intn(32)) * time.Millisecond)
+			n := 0
+			for k, deadline := range deadlines {
+				if !deadline.After(clk.Now()) {
+					delete(deadlines, k)
+					n++
+				}
+			}
+			require.Equal(t, c.Expire(clk.Now()), n)
+			require.Equal(t, c.Len(), len(deadlines))
+			require.Equal(t, c.heap.Len(), len(deadlines))
+		}
+	}
+}
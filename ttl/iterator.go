@@ -0,0 +1,62 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ttl
+
+import (
+	"time"
+
+	"github.com/yu31/structs-go/container"
+)
+
+var _ container.Iterator = (*Iterator)(nil)
+
+// Iterator skips the elements that expired at the time now.
+type Iterator struct {
+	c    *Container
+	iter container.Iterator
+	now  time.Time
+	next container.Element
+}
+
+// creates an Iterator.
+func newIterator(c *Container, iter container.Iterator, now time.Time) *Iterator {
+	it := &Iterator{
+		c:    c,
+		iter: iter,
+		now:  now,
+	}
+	it.advance()
+	return it
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *Iterator) Valid() bool {
+	return iter.next != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *Iterator) Next() container.Element {
+	if !iter.Valid() {
+		return nil
+	}
+	n := iter.next
+	iter.advance()
+	return n
+}
+
+// Finds the next element that not expired.
+func (iter *Iterator) advance() {
+	iter.next = nil
+	for iter.iter.Valid() {
+		ele := iter.iter.Next()
+		e := ele.Value().(*entry)
+		if !iter.c.expired(e, iter.now) {
+			iter.next = &element{key: ele.Key(), entry: e}
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package txn
+
+import (
+	"github.com/yu31/structs-go/container"
+)
+
+var _ container.Iterator = (*Iterator)(nil)
+
+// Iterator merges the elements of base container with the buffered writes.
+// The buffered writes take precedence over the base elements with the same key, and the deletions are skipped.
+type Iterator struct {
+	base    container.Iterator
+	writes  container.Iterator
+	reverse bool
+
+	b container.Element // The next element of base.
+	w container.Element // The next buffered write.
+	n container.Element // The next element to return.
+}
+
+// creates an Iterator.
+func newIterator(base container.Iterator, writes container.Iterator, reverse bool) *Iterator {
+	iter := &Iterator{
+		base:    base,
+		writes:  writes,
+		reverse: reverse,
+	}
+	iter.b = base.Next()
+	iter.w = writes.Next()
+	iter.advance()
+	return iter
+}
+
+// Valid represents whether to have more elements in the Iterator.
+func (iter *Iterator) Valid() bool {
+	return iter.n != nil
+}
+
+// Next returns a element and moved the iterator to the next element.
+// Returns nil if no more elements.
+func (iter *Iterator) Next() container.Element {
+	if !iter.Valid() {
+		return nil
+	}
+	n := iter.n
+	iter.advance()
+	return n
+}
+
+// Finds the next element to return.
+func (iter *Iterator) advance() {
+	for {
+		if iter.w == nil {
+			iter.n = iter.b
+			iter.b = iter.base.Next()
+			return
+		}
+
+		cmp := -1
+		if iter.b != nil {
+			cmp = iter.b.Key().Compare(iter.w.Key())
+			if iter.reverse {
+				cmp = -cmp
+			}
+		}
+		if iter.b != nil && cmp == -1 {
+			iter.n = iter.b
+			iter.b = iter.base.Next()
+			return
+		}
+		if cmp == 0 && iter.b != nil {
+			// The base element is overwritten by the buffered write.
+			iter.b = iter.base.Next()
+		}
+
+		w := iter.w.Value().(*element)
+		iter.w = iter.writes.Next()
+		if !w.deleted {
+			iter.n = w
+			return
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package txn
+
+import (
+	"iter"
+
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+)
+
+var (
+	_ container.Retriever = (*Txn)(nil)
+	_ container.Searcher  = (*Txn)(nil)
+	_ container.Element   = (*element)(nil)
+)
+
+// element is a buffered write of key.
+// The element is a deletion if deleted is true.
+type element struct {
+	key     container.Key
+	value   container.Value
+	deleted bool
+}
+
+// Key returns the key.
+func (e *element) Key() container.Key {
+	return e.key
+}
+
+// Value returns the value.
+func (e *element) Value() container.Value {
+	return e.value
+}
+
+// Txn buffers a group of writes to a base container, and applies all of them on Commit or discards them on Rollback.
+// The base container is never changed before Commit, so a failure halfway before Commit can be undone by Rollback.
+//
+// The reads of Txn see its own uncommitted writes merged with the base container.
+// The Txn is reusable after Commit or Rollback.
+//
+// The Txn is not safe for concurrent use, and the base container should not be changed by others
+// during the transaction, otherwise the merged reads may be inconsistent.
+type Txn struct {
+	base   container.Container
+	writes *rb.Tree // The buffered writes, the value of each node is an *element.
+	delta  int      // The number of elements changed by buffered writes.
+}
+
+// New begins a Txn over the base container.
+func New(base container.Container) *Txn {
+	return &Txn{
+		base:   base,
+		writes: rb.New(),
+		delta:  0,
+	}
+}
+
+// Len returns the number of elements in the base container with buffered writes applied.
+func (t *Txn) Len() int {
+	return t.base.Len() + t.delta
+}
+
+// Buffered returns the number of buffered writes.
+func (t *Txn) Buffered() int {
+	return t.writes.Len()
+}
+
+// Insert buffers an insertion if the key doesn't exist, or returns the existing element for the key if present.
+// The bool result is true if an element was inserted, false if searched.
+func (t *Txn) Insert(k container.Key, v container.Value) (container.Element, bool) {
+	if ele := t.Search(k); ele != nil {
+		return ele, false
+	}
+	return t.put(k, v, false), true
+}
+
+// Delete buffers a deletion and returns the element of a given key.
+// Returns nil if key not found.
+func (t *Txn) Delete(k container.Key) container.Element {
+	ele := t.Search(k)
+	if ele == nil {
+		return nil
+	}
+	if t.base.Search(k) == nil {
+		// The key only exists in the buffered writes, just discards it.
+		t.writes.Delete(k)
+	} else {
+		t.writes.Upsert(k, &element{key: k, deleted: true})
+	}
+	t.delta--
+	return ele
+}
+
+// Update buffers an update of key, And returns the old element of key.
+// Returns nil if the key not be found.
+func (t *Txn) Update(k container.Key, v container.Value) container.Element {
+	ele := t.Search(k)
+	if ele == nil {
+		return nil
+	}
+	t.put(k, v, true)
+	return ele
+}
+
+// Upsert buffers an insertion or update of key.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (t *Txn) Upsert(k container.Key, v container.Value) (container.Element, bool) {
+	if ele := t.Search(k); ele != nil {
+		t.put(k, v, true)
+		return ele, false
+	}
+	return t.put(k, v, false), true
+}
+
+// Search searches the element of a given key.
+// Returns nil if key not found.
+func (t *Txn) Search(k container.Key) container.Element {
+	if w := t.writes.Search(k); w != nil {
+		return visible(w)
+	}
+	return t.base.Search(k)
+}
+
+// Commit applies all the buffered writes to the base container in ascending order by key,
+// and then starts a new transaction.
+func (t *Txn) Commit() {
+	t.writes.Range(nil, nil, func(ele container.Element) bool {
+		w := ele.Value().(*element)
+		if w.deleted {
+			t.base.Delete(w.key)
+		} else {
+			t.base.Upsert(w.key, w.value)
+		}
+		return true
+	})
+	t.reset()
+}
+
+// Rollback discards all the buffered writes, and then starts a new transaction.
+func (t *Txn) Rollback() {
+	t.reset()
+}
+
+// Iter creates an Iterator positioned on the first element that key >= start key.
+// The Iterator yields the elements of base container merged with the buffered writes.
+func (t *Txn) Iter(start container.Key, boundary container.Key) container.Iterator {
+	return newIterator(t.base.Iter(start, boundary), t.writes.Iter(start, boundary), false)
+}
+
+// IterReverse is similar to the Iter method, but the elements are in reverse order.
+func (t *Txn) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	return newIterator(t.base.IterReverse(start, boundary), t.writes.IterReverse(start, boundary), true)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (t *Txn) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(t)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (t *Txn) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(t)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (t *Txn) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(t, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (t *Txn) Keys() iter.Seq[container.Key] {
+	return container.Keys(t)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (t *Txn) Values() iter.Seq[container.Value] {
+	return container.Values(t)
+}
+
+// Range calls f sequentially each element present in the base container merged with the buffered writes.
+// If f returns false, range stops the iteration.
+func (t *Txn) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	it := t.Iter(start, boundary)
+	for it.Valid() {
+		if !f(it.Next()) {
+			return
+		}
+	}
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+func (t *Txn) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	it := t.IterReverse(start, boundary)
+	for it.Valid() {
+		if !f(it.Next()) {
+			return
+		}
+	}
+}
+
+// LastLT searches for the last element that less than the key.
+func (t *Txn) LastLT(k container.Key) container.Element {
+	return t.search(k, container.Searcher.LastLT, container.Searcher.LastLT, -1)
+}
+
+// LastLE search for the last element that less than or equal to the key.
+func (t *Txn) LastLE(k container.Key) container.Element {
+	return t.search(k, container.Searcher.LastLE, container.Searcher.LastLT, -1)
+}
+
+// FirstGT search for the first element that greater than to the key.
+func (t *Txn) FirstGT(k container.Key) container.Element {
+	return t.search(k, container.Searcher.FirstGT, container.Searcher.FirstGT, 1)
+}
+
+// FirstGE search for the first element that greater than or equal to the key.
+func (t *Txn) FirstGE(k container.Key) container.Element {
+	return t.search(k, container.Searcher.FirstGE, container.Searcher.FirstGT, 1)
+}
+
+// Buffers a write of key and returns it. The exists represents whether the key exists before.
+func (t *Txn) put(k container.Key, v container.Value, exists bool) container.Element {
+	w := &element{key: k, value: v}
+	t.writes.Upsert(k, w)
+	if !exists {
+		t.delta++
+	}
+	return w
+}
+
+// Discards all the buffered writes.
+func (t *Txn) reset() {
+	t.writes.Clear()
+	t.delta = 0
+}
+
+// Searches the element with first in both the base container and buffered writes, and calls next
+// to skip the deleted elements. The dir is -1 for searching backward, 1 for forward.
+func (t *Txn) search(k container.Key, first, next func(s container.Searcher, k container.Key) container.Element, dir int) container.Element {
+	// Searches the base container, skips the elements deleted by buffered writes.
+	b := first(t.base, k)
+	for b != nil {
+		if w := t.writes.Search(b.Key()); w == nil || !w.Value().(*element).deleted {
+			break
+		}
+		b = next(t.base, b.Key())
+	}
+
+	// Searches the buffered writes, skips the deletions.
+	w := first(t.writes, k)
+	for w != nil && w.Value().(*element).deleted {
+		w = next(t.writes, w.Key())
+	}
+
+	if w == nil {
+		return b
+	}
+	// The buffered write takes precedence over the base element with the same key.
+	if b == nil || b.Key().Compare(w.Key()) != -dir {
+		return w.Value().(*element)
+	}
+	return b
+}
+
+// Returns the element of a buffered write, nil if it's a deletion.
+func visible(w container.Element) container.Element {
+	e := w.Value().(*element)
+	if e.deleted {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,133 @@
+package txn
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/avl"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+)
+
+func clone(ctr container.Container) container.Container {
+	c := avl.New()
+	ctr.Range(nil, nil, func(ele container.Element) bool {
+		c.Insert(ele.Key(), ele.Value())
+		return true
+	})
+	return c
+}
+
+func collect(r container.Retriever, start, boundary container.Key, reverse bool) []container.Element {
+	var elements []container.Element
+	f := func(ele container.Element) bool {
+		elements = append(elements, &element{key: ele.Key(), value: ele.Value()})
+		return true
+	}
+	if reverse {
+		r.Reverse(start, boundary, f)
+	} else {
+		r.Range(start, boundary, f)
+	}
+	return elements
+}
+
+func requireSameElement(t *testing.T, e1, e2 container.Element) {
+	require.Equal(t, e1 == nil, e2 == nil)
+	if e1 != nil {
+		require.Equal(t, e1.Key(), e2.Key())
+		require.Equal(t, e1.Value(), e2.Value())
+	}
+}
+
+func TestTxn(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	maxKey := 128
+
+	base := rb.New()
+	for i := 0; i < maxKey; i += 2 {
+		base.Insert(container.Int(i), i)
+	}
+
+	for x := 0; x < 32; x++ {
+		tx := New(base)
+		// The model applies the writes directly to a copy of base.
+		model := clone(base)
+		original := clone(base)
+
+		for i := 0; i < 256; i++ {
+			k := container.Int(r.Intn(maxKey))
+			switch r.Intn(4) {
+			case 0:
+				e1, ok1 := tx.Insert(k, i)
+				e2, ok2 := model.Insert(k, i)
+				require.Equal(t, ok1, ok2)
+				requireSameElement(t, e1, e2)
+			case 1:
+				requireSameElement(t, tx.Delete(k), model.Delete(k))
+			case 2:
+				requireSameElement(t, tx.Update(k, i), model.Update(k, i))
+			case 3:
+				e1, ok1 := tx.Upsert(k, i)
+				e2, ok2 := model.Upsert(k, i)
+				require.Equal(t, ok1, ok2)
+				requireSameElement(t, e1, e2)
+			}
+			require.Equal(t, tx.Len(), model.Len())
+
+			// Reads see the uncommitted writes.
+			k = container.Int(r.Intn(maxKey+2) - 1)
+			requireSameElement(t, tx.Search(k), model.Search(k))
+			requireSameElement(t, tx.LastLT(k), model.LastLT(k))
+			requireSameElement(t, tx.LastLE(k), model.LastLE(k))
+			requireSameElement(t, tx.FirstGT(k), model.FirstGT(k))
+			requireSameElement(t, tx.FirstGE(k), model.FirstGE(k))
+
+			var start, boundary container.Key
+			if r.Intn(4) != 0 {
+				start = container.Int(r.Intn(maxKey))
+			}
+			if r.Intn(4) != 0 {
+				boundary = container.Int(r.Intn(maxKey))
+			}
+			require.Equal(t, collect(tx, start, boundary, false), collect(model, start, boundary, false))
+			require.Equal(t, collect(tx, start, boundary, true), collect(model, start, boundary, true))
+		}
+
+		// The base is unchanged before Commit.
+		require.Equal(t, collect(base, nil, nil, false), collect(original, nil, nil, false))
+
+		if r.Intn(2) == 0 {
+			tx.Rollback()
+			require.Equal(t, collect(base, nil, nil, false), collect(original, nil, nil, false))
+		} else {
+			tx.Commit()
+			require.Equal(t, collect(base, nil, nil, false), collect(model, nil, nil, false))
+		}
+		require.Equal(t, tx.Buffered(), 0)
+		require.Equal(t, tx.Len(), base.Len())
+	}
+}
+
+func TestTxn_Reuse(t *testing.T) {
+	base := rb.New()
+	tx := New(base)
+
+	tx.Insert(container.Int(1), 1)
+	tx.Insert(container.Int(2), 2)
+	require.Equal(t, base.Len(), 0)
+	require.Equal(t, tx.Len(), 2)
+	tx.Commit()
+	require.Equal(t, base.Len(), 2)
+
+	require.NotNil(t, tx.Delete(container.Int(1)))
+	require.Nil(t, tx.Search(container.Int(1)))
+	require.NotNil(t, base.Search(container.Int(1)))
+	require.Equal(t, tx.FirstGE(container.Int(0)).Key(), container.Int(2))
+	tx.Rollback()
+	require.NotNil(t, tx.Search(container.Int(1)))
+	require.Equal(t, tx.Len(), 2)
+}
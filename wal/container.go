@@ -0,0 +1,653 @@
+// Copyright (c) 2020, Yu Wu <yu.771991@gmail.com> All rights reserved.
+//
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package wal implements the write-ahead log and crash recovery for in-memory containers.
+//
+// The Container wraps a container.Container to append a record for each change to a log file.
+// A checkpoint writes a full snapshot of the container and truncates the log. On startup, the snapshot
+// is loaded and the log is replayed.
+//
+// The records hold the results of changes, such as setting a key to a value or deleting a key,
+// instead of the operations. So replaying the log over a newer snapshot still gives the same result,
+// and a crash during checkpoint is safe.
+package wal
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yu31/structs-go/codec"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/internal/record"
+)
+
+var _ container.Container = (*Container)(nil)
+
+var (
+	// ErrClosed is returned when using a closed Container.
+	ErrClosed = errors.New("wal: container is closed")
+	// ErrCorrupted is returned when the log is corrupted.
+	ErrCorrupted = errors.New("wal: corrupted log")
+)
+
+const (
+	logName      = "wal.log"
+	snapshotName = "snapshot"
+)
+
+// The records in the log.
+const (
+	opSet byte = iota + 1
+	opDelete
+	opDeleteRange
+	opClear
+)
+
+// SyncPolicy decides when the log is synced to the disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs the log after each change, no change is lost on machine crash.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval syncs the log periodically in background,
+	// the changes in the last interval may be lost on machine crash.
+	SyncInterval
+	// SyncNever never syncs the log explicitly, it depends on the operating system.
+	// No change is lost on process crash.
+	SyncNever
+)
+
+// Options is the options of Container.
+type Options struct {
+	// Registry is used to encode and decode the keys and values, default codec.Default.
+	Registry *codec.Registry
+	// Sync is the policy to sync the log, default SyncAlways.
+	Sync SyncPolicy
+	// SyncInterval is the interval for SyncInterval policy, default 1 second.
+	SyncInterval time.Duration
+	// CheckpointSize triggers a checkpoint after a change if the log exceeds the size, 0 to disable.
+	CheckpointSize int64
+	// CheckpointInterval triggers a checkpoint periodically in background, 0 to disable.
+	CheckpointInterval time.Duration
+}
+
+// Returns a copy of opts with defaults filled.
+func copyOptions(opts *Options) *Options {
+	o := &Options{}
+	if opts != nil {
+		*o = *opts
+	}
+	if o.Registry == nil {
+		o.Registry = codec.Default
+	}
+	if o.SyncInterval <= 0 {
+		o.SyncInterval = time.Second
+	}
+	return o
+}
+
+// Container wraps a container.Container to log the changes to a local directory.
+//
+// The methods of container.Container can not return errors, so the first error of writing log is
+// recorded and returned by the Err, Sync and Checkpoint methods. The changes are still applied to
+// the wrapped container after that, but they are no longer logged. So are the changes after Close,
+// and ErrClosed is recorded for them.
+//
+// The wrapped container must not allow duplicate keys, since a change is replayed as an upsert.
+// Like the wrapped container, the Container is not safe for concurrent use,
+// but the background sync and checkpoint are synchronized with the changes.
+type Container struct {
+	ctr  container.Container
+	dir  string
+	opts *Options
+
+	mu      sync.Mutex // Protects the fields below and the changes to ctr.
+	log     *os.File
+	logw    *record.Writer
+	logSize int64
+	dirty   bool // Whether the log has unsynced records.
+	err     error
+	closed  bool
+	buf     []byte
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open recovers the ctr from the snapshot and log in dir, and returns a Container that wraps it.
+// The ctr should be empty, the dir is created if not exists. The default options is used if opts is nil.
+//
+// A torn record at the end of log is discarded, it's caused by a crash during writing.
+func Open(dir string, ctr container.Container, opts *Options) (*Container, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := &Container{
+		ctr:  ctr,
+		dir:  dir,
+		opts: copyOptions(opts),
+		done: make(chan struct{}),
+	}
+
+	if err := c.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := c.replay(); err != nil {
+		return nil, err
+	}
+
+	if c.opts.Sync == SyncInterval || c.opts.CheckpointInterval > 0 {
+		c.wg.Add(1)
+		go c.background()
+	}
+	return c, nil
+}
+
+// Loads the snapshot into ctr if exists.
+func (c *Container) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(c.dir, snapshotName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = c.opts.Registry.UnmarshalTo(f, c.ctr)
+	return err
+}
+
+// Replays the log into ctr, and opens it for appending.
+func (c *Container) replay() error {
+	f, err := os.OpenFile(filepath.Join(c.dir, logName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r := record.NewReader(f, fi.Size())
+	for {
+		payload, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == record.ErrTorn {
+			// Discards the torn record, so that the new records are appended after the valid records.
+			if err := f.Truncate(r.Offset()); err != nil {
+				f.Close()
+				return err
+			}
+			break
+		}
+		if err == record.ErrCorrupted {
+			err = ErrCorrupted
+		}
+		if err == nil {
+			err = c.apply(payload)
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if _, err := f.Seek(r.Offset(), io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	c.log = f
+	c.logw = record.NewWriter(f)
+	c.logSize = r.Offset()
+	return nil
+}
+
+// Applies a record to ctr.
+func (c *Container) apply(payload []byte) error {
+	if len(payload) == 0 {
+		return ErrCorrupted
+	}
+	op, data := payload[0], payload[1:]
+
+	// Reads the values of record.
+	var values [2]interface{}
+	for i := 0; i < 2 && len(data) != 0; i++ {
+		v, n, err := c.opts.Registry.ReadValue(data)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+		data = data[n:]
+	}
+	key := func(i int) (container.Key, bool) {
+		if values[i] == nil {
+			return nil, true
+		}
+		k, ok := values[i].(container.Key)
+		return k, ok
+	}
+
+	switch op {
+	case opSet:
+		k, ok := key(0)
+		if !ok || k == nil {
+			return ErrCorrupted
+		}
+		c.ctr.Upsert(k, values[1])
+	case opDelete:
+		k, ok := key(0)
+		if !ok || k == nil {
+			return ErrCorrupted
+		}
+		c.ctr.Delete(k)
+	case opDeleteRange:
+		start, ok1 := key(0)
+		boundary, ok2 := key(1)
+		if !ok1 || !ok2 {
+			return ErrCorrupted
+		}
+		c.ctr.DeleteRange(start, boundary, nil)
+	case opClear:
+		c.ctr.Clear()
+	default:
+		return ErrCorrupted
+	}
+	return nil
+}
+
+// Runs the periodic sync and checkpoint.
+func (c *Container) background() {
+	defer c.wg.Done()
+
+	var syncC, checkpointC <-chan time.Time
+	if c.opts.Sync == SyncInterval {
+		t := time.NewTicker(c.opts.SyncInterval)
+		defer t.Stop()
+		syncC = t.C
+	}
+	if c.opts.CheckpointInterval > 0 {
+		t := time.NewTicker(c.opts.CheckpointInterval)
+		defer t.Stop()
+		checkpointC = t.C
+	}
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-syncC:
+			c.Sync()
+		case <-checkpointC:
+			c.Checkpoint()
+		}
+	}
+}
+
+// Err returns the first error of writing log.
+func (c *Container) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Sync syncs the log to the disk.
+func (c *Container) Sync() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	return c.sync()
+}
+
+func (c *Container) sync() error {
+	if c.err != nil {
+		return c.err
+	}
+	if !c.dirty {
+		return nil
+	}
+	if err := c.log.Sync(); err != nil {
+		c.err = err
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// Checkpoint writes a snapshot of the container and truncates the log.
+func (c *Container) Checkpoint() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	return c.checkpoint()
+}
+
+func (c *Container) checkpoint() error {
+	if c.err != nil {
+		return c.err
+	}
+
+	tmp := filepath.Join(c.dir, snapshotName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	err = c.opts.Registry.Marshal(bw, c.ctr)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(c.dir, snapshotName))
+	}
+	if err != nil {
+		// The snapshot is not replaced, the log is still valid.
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(c.dir)
+
+	// A crash before truncated is safe, since the log can be replayed over the new snapshot.
+	if err = c.log.Truncate(0); err == nil {
+		_, err = c.log.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		c.err = err
+		return err
+	}
+	c.logSize = 0
+	c.dirty = false
+	return nil
+}
+
+// Close stops the background tasks, syncs and closes the log.
+// It does not checkpoint, the log will be replayed on next Open.
+func (c *Container) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.closed = true
+	close(c.done)
+	err := c.sync()
+	if e := c.log.Close(); err == nil {
+		err = e
+	}
+	c.mu.Unlock()
+
+	c.wg.Wait()
+	return err
+}
+
+// Appends a record with the op and values.
+func (c *Container) write(op byte, values ...interface{}) {
+	if c.err != nil {
+		return
+	}
+	if c.closed {
+		c.err = ErrClosed
+		return
+	}
+	var err error
+	c.buf = append(c.buf[:0], op)
+	for _, v := range values {
+		if c.buf, err = c.opts.Registry.AppendValue(c.buf, v); err != nil {
+			c.err = err
+			return
+		}
+	}
+	n, err := c.logw.Write(c.buf)
+	c.logSize += int64(n)
+	if err != nil {
+		c.err = err
+		return
+	}
+	c.dirty = true
+
+	if c.opts.Sync == SyncAlways {
+		if c.sync() != nil {
+			return
+		}
+	}
+	if c.opts.CheckpointSize > 0 && c.logSize > c.opts.CheckpointSize {
+		c.checkpoint()
+	}
+}
+
+// Syncs the directory to persist the renamed file.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	// Some platforms do not support to sync a directory.
+	d.Sync()
+	d.Close()
+}
+
+// Len returns the number of elements.
+func (c *Container) Len() int {
+	return c.ctr.Len()
+}
+
+// Insert inserts a new element if the key doesn't exist, or returns the existing element for the key if present.
+// The bool result is true if an element was inserted, false if searched.
+func (c *Container) Insert(k container.Key, v container.Value) (container.Element, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ele, ok := c.ctr.Insert(k, v)
+	if ok {
+		c.write(opSet, k, v)
+	}
+	return ele, ok
+}
+
+// Delete removes and returns the element of a given key.
+// Returns nil if key not found.
+func (c *Container) Delete(k container.Key) container.Element {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ele := c.ctr.Delete(k)
+	if ele != nil {
+		c.write(opDelete, k)
+	}
+	return ele
+}
+
+// Update updates an element with the given key and value, And returns the old element of key.
+// Returns nil if the key not be found.
+func (c *Container) Update(k container.Key, v container.Value) container.Element {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ele := c.ctr.Update(k, v)
+	if ele != nil {
+		c.write(opSet, k, v)
+	}
+	return ele
+}
+
+// Upsert inserts or updates an element by giving key and value.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (c *Container) Upsert(k container.Key, v container.Value) (container.Element, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ele, ok := c.ctr.Upsert(k, v)
+	c.write(opSet, k, v)
+	return ele, ok
+}
+
+// UpdateInPlace is similar to the Update method, but it sets the value of the existing element in place.
+// Returns the element of key and its old value, returns nil if the key not be found.
+func (c *Container) UpdateInPlace(k container.Key, v container.Value) (container.Element, container.Value) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ele, old := c.ctr.UpdateInPlace(k, v)
+	if ele != nil {
+		c.write(opSet, k, v)
+	}
+	return ele, old
+}
+
+// UpsertInPlace is similar to the Upsert method, but it sets the value of the existing element in place.
+// The bool result is true if an element was inserted, false if an element was updated.
+func (c *Container) UpsertInPlace(k container.Key, v container.Value) (container.Element, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ele, ok := c.ctr.UpsertInPlace(k, v)
+	c.write(opSet, k, v)
+	return ele, ok
+}
+
+// Search searches the element of a given key.
+// Returns nil if key not found.
+//
+// The changes made by MutableElement.SetValue on the returned elements are not logged,
+// use the UpdateInPlace method instead.
+func (c *Container) Search(k container.Key) container.Element {
+	return c.ctr.Search(k)
+}
+
+// Compute searches the element of a given key and calls f with it, then inserts, updates or deletes
+// the element depending on the result of f.
+func (c *Container) Compute(k container.Key, f func(ele container.Element, exists bool) (container.Value, bool)) container.Element {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var changed, deleted bool
+	var value container.Value
+	ele := c.ctr.Compute(k, func(ele container.Element, exists bool) (container.Value, bool) {
+		v, keep := f(ele, exists)
+		changed = keep || exists
+		deleted = !keep
+		value = v
+		return v, keep
+	})
+	if changed {
+		if deleted {
+			c.write(opDelete, k)
+		} else {
+			c.write(opSet, k, value)
+		}
+	}
+	return ele
+}
+
+// GetOrInsert returns the existing element for the key if present.
+// Otherwise, inserts and returns a new element with the value returned by f.
+// The bool result is true if an element was inserted, false if searched.
+func (c *Container) GetOrInsert(k container.Key, f func() container.Value) (container.Element, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ele, ok := c.ctr.GetOrInsert(k, f)
+	if ok {
+		c.write(opSet, k, ele.Value())
+	}
+	return ele, ok
+}
+
+// DeleteRange removes all elements in the range start <= x < boundary, and returns the number of removed elements.
+// If f is not nil, it will be called sequentially with each removed element in ascending order.
+func (c *Container) DeleteRange(start container.Key, boundary container.Key, f func(ele container.Element)) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.ctr.DeleteRange(start, boundary, f)
+	if n != 0 {
+		c.write(opDeleteRange, start, boundary)
+	}
+	return n
+}
+
+// Clear removes all elements in the Container.
+func (c *Container) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctr.Clear()
+	c.write(opClear)
+}
+
+// Iter return an Iterator of the wrapped container.
+func (c *Container) Iter(start container.Key, boundary container.Key) container.Iterator {
+	return c.ctr.Iter(start, boundary)
+}
+
+// IterReverse return an reversed Iterator of the wrapped container.
+func (c *Container) IterReverse(start container.Key, boundary container.Key) container.Iterator {
+	return c.ctr.IterReverse(start, boundary)
+}
+
+// All returns an iterator over all elements in ascending order.
+func (c *Container) All() iter.Seq2[container.Key, container.Value] {
+	return container.All(c)
+}
+
+// Backward returns an iterator over all elements in descending order.
+func (c *Container) Backward() iter.Seq2[container.Key, container.Value] {
+	return container.Backward(c)
+}
+
+// Between returns an iterator over the elements in the range start <= x < boundary in ascending order.
+func (c *Container) Between(start container.Key, boundary container.Key) iter.Seq2[container.Key, container.Value] {
+	return container.Between(c, start, boundary)
+}
+
+// Keys returns an iterator over all keys in ascending order.
+func (c *Container) Keys() iter.Seq[container.Key] {
+	return container.Keys(c)
+}
+
+// Values returns an iterator over all values in ascending order by key.
+func (c *Container) Values() iter.Seq[container.Value] {
+	return container.Values(c)
+}
+
+// Range calls f sequentially each element present in the Container.
+// If f returns false, range stops the iteration.
+func (c *Container) Range(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	c.ctr.Range(start, boundary, f)
+}
+
+// Reverse is similar to the Range method. But it iteration element in reverse.
+func (c *Container) Reverse(start container.Key, boundary container.Key, f func(ele container.Element) bool) {
+	c.ctr.Reverse(start, boundary, f)
+}
+
+// LastLT searches for the last element that less than the key.
+func (c *Container) LastLT(k container.Key) container.Element {
+	return c.ctr.LastLT(k)
+}
+
+// LastLE search for the last element that less than or equal to the key.
+func (c *Container) LastLE(k container.Key) container.Element {
+	return c.ctr.LastLE(k)
+}
+
+// FirstGT search for the first element that greater than to the key.
+func (c *Container) FirstGT(k container.Key) container.Element {
+	return c.ctr.FirstGT(k)
+}
+
+// FirstGE search for the first element that greater than or equal to the key.
+func (c *Container) FirstGE(k container.Key) container.Element {
+	return c.ctr.FirstGE(k)
+}
@@ -0,0 +1,243 @@
+package wal
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yu31/structs-go/avl"
+	"github.com/yu31/structs-go/bs"
+	"github.com/yu31/structs-go/container"
+	"github.com/yu31/structs-go/rb"
+	"github.com/yu31/structs-go/skip"
+)
+
+var creates = map[string]func() container.Container{
+	"avltree":  func() container.Container { return avl.New() },
+	"rbtree":   func() container.Container { return rb.New() },
+	"bstree":   func() container.Container { return bs.New() },
+	"skiplist": func() container.Container { return skip.New() },
+}
+
+// Returns the keys and values of elements in ascending order.
+func collect(ctr container.Container) []interface{} {
+	var result []interface{}
+	ctr.Range(nil, nil, func(ele container.Element) bool {
+		result = append(result, ele.Key(), ele.Value())
+		return true
+	})
+	return result
+}
+
+// Applies a random change to both c and model.
+func randomChange(r *rand.Rand, c *Container, model *rb.Tree) {
+	k := container.Int(r.Intn(200))
+	v := r.Intn(1000)
+	switch r.Intn(10) {
+	case 0:
+		c.Insert(k, v)
+		model.Insert(k, v)
+	case 1:
+		c.Delete(k)
+		model.Delete(k)
+	case 2:
+		c.Update(k, v)
+		model.Update(k, v)
+	case 3:
+		c.UpdateInPlace(k, v)
+		model.UpdateInPlace(k, v)
+	case 4:
+		c.UpsertInPlace(k, v)
+		model.UpsertInPlace(k, v)
+	case 5:
+		f := func(ele container.Element, exists bool) (container.Value, bool) {
+			return v, v%3 != 0
+		}
+		c.Compute(k, f)
+		model.Compute(k, f)
+	case 6:
+		f := func() container.Value { return v }
+		c.GetOrInsert(k, f)
+		model.GetOrInsert(k, f)
+	case 7:
+		if r.Intn(20) == 0 {
+			boundary := k + container.Int(r.Intn(20))
+			c.DeleteRange(k, boundary, nil)
+			model.DeleteRange(k, boundary, nil)
+		}
+	case 8:
+		if r.Intn(200) == 0 {
+			c.Clear()
+			model.Clear()
+		}
+	default:
+		c.Upsert(k, v)
+		model.Upsert(k, v)
+	}
+}
+
+func TestContainer(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for name, create := range creates {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := &Options{Sync: SyncNever}
+			c, err := Open(dir, create(), opts)
+			require.Nil(t, err)
+			model := rb.New()
+
+			for round := 0; round < 5; round++ {
+				for i := 0; i < 1000; i++ {
+					randomChange(r, c, model)
+				}
+				require.Equal(t, collect(c), collect(model))
+				if round%2 == 1 {
+					require.Nil(t, c.Checkpoint())
+				}
+
+				// Recovers from the snapshot and log.
+				require.Nil(t, c.Close())
+				require.Equal(t, c.Close(), ErrClosed)
+				require.Equal(t, c.Sync(), ErrClosed)
+				c, err = Open(dir, create(), opts)
+				require.Nil(t, err)
+				require.Equal(t, collect(c), collect(model))
+				require.Equal(t, c.Len(), model.Len())
+			}
+			require.Nil(t, c.Err())
+			require.Nil(t, c.Close())
+
+			// The changes after closed are not logged, and ErrClosed is recorded.
+			require.Nil(t, c.Err())
+			c.Insert(container.Int(-1), "closed")
+			require.Equal(t, c.Err(), ErrClosed)
+			c, err = Open(dir, create(), opts)
+			require.Nil(t, err)
+			require.Nil(t, c.Search(container.Int(-1)))
+			require.Nil(t, c.Close())
+		})
+	}
+}
+
+func TestContainer_TornLog(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, rb.New(), nil)
+	require.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		c.Insert(container.Int(i), "value")
+	}
+	require.Nil(t, c.Close())
+
+	// Cuts the last record in half.
+	name := filepath.Join(dir, logName)
+	fi, err := os.Stat(name)
+	require.Nil(t, err)
+	require.Nil(t, os.Truncate(name, fi.Size()-3))
+
+	c, err = Open(dir, rb.New(), nil)
+	require.Nil(t, err)
+	require.Equal(t, c.Len(), 9)
+	require.Nil(t, c.Search(container.Int(9)))
+
+	// The new records are appended after the valid records.
+	c.Insert(container.Int(10), "value")
+	require.Nil(t, c.Close())
+	c, err = Open(dir, rb.New(), nil)
+	require.Nil(t, err)
+	require.NotNil(t, c.Search(container.Int(10)))
+	require.Equal(t, c.Len(), 10)
+	require.Nil(t, c.Close())
+
+	// A corrupted record in the middle fails the Open.
+	data, err := os.ReadFile(name)
+	require.Nil(t, err)
+	data[10] ^= 0xff
+	require.Nil(t, os.WriteFile(name, data, 0644))
+	_, err = Open(dir, rb.New(), nil)
+	require.Equal(t, err, ErrCorrupted)
+
+	// A broken length in the middle fails the Open, and the log is not truncated.
+	data[10] ^= 0xff
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)))
+	require.Nil(t, os.WriteFile(name, data, 0644))
+	_, err = Open(dir, rb.New(), nil)
+	require.Equal(t, err, ErrCorrupted)
+	fi, err = os.Stat(name)
+	require.Nil(t, err)
+	require.Equal(t, fi.Size(), int64(len(data)))
+}
+
+func TestContainer_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, logName)
+	c, err := Open(dir, rb.New(), &Options{CheckpointSize: 1 << 10})
+	require.Nil(t, err)
+
+	for i := 0; i < 1000; i++ {
+		c.Upsert(container.Int(i%100), i)
+		fi, err := os.Stat(name)
+		require.Nil(t, err)
+		require.LessOrEqual(t, fi.Size(), int64(1<<10))
+	}
+	require.Nil(t, c.Err())
+
+	// A log left by a crash during checkpoint is replayed over the new snapshot.
+	data, err := os.ReadFile(name)
+	require.Nil(t, err)
+	require.Nil(t, c.Checkpoint())
+	fi, err := os.Stat(name)
+	require.Nil(t, err)
+	require.Equal(t, fi.Size(), int64(0))
+	require.Nil(t, c.Close())
+	require.Nil(t, os.WriteFile(name, data, 0644))
+
+	ctr := rb.New()
+	c, err = Open(dir, ctr, nil)
+	require.Nil(t, err)
+	require.Equal(t, ctr.Len(), 100)
+	for i := 0; i < 100; i++ {
+		require.Equal(t, ctr.Search(container.Int(i)).Value(), 900+i)
+	}
+	require.Nil(t, c.Close())
+
+	// Unregistered types are recorded as error.
+	c, err = Open(dir, rb.New(), nil)
+	require.Nil(t, err)
+	c.Insert(container.Int(1000), struct{}{})
+	require.NotNil(t, c.Err())
+	require.NotNil(t, c.Sync())
+	require.NotNil(t, c.Checkpoint())
+	require.NotNil(t, c.Close())
+}
+
+func TestContainer_Background(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, rb.New(), &Options{
+		Sync:               SyncInterval,
+		SyncInterval:       time.Millisecond,
+		CheckpointInterval: 5 * time.Millisecond,
+	})
+	require.Nil(t, err)
+
+	for i := 0; i < 100; i++ {
+		c.Insert(container.Int(i), i)
+		time.Sleep(time.Millisecond / 10)
+	}
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, snapshotName))
+		return err == nil
+	}, time.Second, time.Millisecond)
+	require.Nil(t, c.Err())
+	require.Nil(t, c.Close())
+
+	c, err = Open(dir, rb.New(), nil)
+	require.Nil(t, err)
+	require.Equal(t, c.Len(), 100)
+	require.Nil(t, c.Close())
+}